@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AgentDeployment is the name of probe-agent deployment in probe namespace
+	AgentDeployment = "probe-agent"
+	// AgentContainer is the default container name of agent in probe-agent deployment
+	AgentContainer = "probe-agent"
+)
+
+// FindAgentContainer returns the container of name in probe-agent deployment,
+// the only container is used if none has the name
+func FindAgentContainer(deploy *appsv1.Deployment, name string) (*apiv1.Container, error) {
+	containers := deploy.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i], nil
+		}
+	}
+	if len(containers) == 1 {
+		return &containers[0], nil
+	}
+	return nil, fmt.Errorf("container %s not found in deployment %s/%s", name, deploy.Namespace, deploy.Name)
+}
+
+// ImageVersion returns the tag of image, or its digest like sha256:... if the image has no tag,
+// the image itself is returned if it has neither
+func ImageVersion(image string) string {
+	name, digest := image, ""
+	if i := strings.Index(image, "@"); i >= 0 {
+		name, digest = image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[i+1:]
+	}
+	if digest != "" {
+		return digest
+	}
+	return image
+}
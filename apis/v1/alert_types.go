@@ -20,16 +20,133 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// ReceiverType is the channel type of an alert receiver
+type ReceiverType string
+
+const (
+	ReceiverTypeDingDing ReceiverType = "dingding"
+	ReceiverTypeSlack    ReceiverType = "slack"
+	ReceiverTypeWeCom    ReceiverType = "wecom"
+	ReceiverTypeFeishu   ReceiverType = "feishu"
+	ReceiverTypeEmail    ReceiverType = "email"
+	ReceiverTypeWebhook  ReceiverType = "webhook"
+)
+
 // AlertSpec defines the desired state of Alert
 type AlertSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of alert
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Foo is an example field of Alert. Edit cluster_types.go to remove/update
-	Address   string   `json:"address,omitempty"`
-	Token     string   `json:"token,omitempty"`
-	Sign      string   `json:"sign,omitempty"`
+	// type of the receiver, dingding if empty
+	// +kubebuilder:validation:Enum=dingding;slack;wecom;feishu;email;webhook
+	Type ReceiverType `json:"type,omitempty"`
+
+	// address, token and sign of dingding robot
+	Address string `json:"address,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Sign    string `json:"sign,omitempty"`
+	// Deprecated: node alerts containing any of the substrings are not sent, use suppress instead
 	BlackList []string `json:"blackList,omitempty"`
+
+	// node alerts matching any of the matchers are not sent
+	Suppress []AlertSuppressMatcher `json:"suppress,omitempty"`
+
+	Slack   *SlackConfig   `json:"slack,omitempty"`
+	WeCom   *WeComConfig   `json:"wecom,omitempty"`
+	Feishu  *FeishuConfig  `json:"feishu,omitempty"`
+	Email   *EmailConfig   `json:"email,omitempty"`
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+
+	// templates of messages sent to the receiver
+	Template *AlertTemplate `json:"template,omitempty"`
+}
+
+// AlertTemplate customizes the messages of a receiver with go templates,
+// the data of templates is {Receiver, Firing, Resolved} where Firing and
+// Resolved are lists of {Cluster, Probe, Checker, Fingerprint, Status,
+// Message, StartsAt, EndsAt}. The data of ticket templates is {Cluster, Probe,
+// Checker, Node, Type, Component, Status, Level, Message}
+type AlertTemplate struct {
+	// language of built-in templates, the language of probe-master if empty
+	// +kubebuilder:validation:Enum=zh;en
+	Language string `json:"language,omitempty"`
+	// template of message title, built-in template if empty
+	Title string `json:"title,omitempty"`
+	// template of message text, built-in template if empty
+	Text string `json:"text,omitempty"`
+	// template of ticket title, built-in template if empty. Tickets of node alerts use
+	// templates of the dingding receiver, tickets of checkers use templates of the first
+	// receiver of matched routes
+	TicketTitle string `json:"ticketTitle,omitempty"`
+	// template of ticket content, built-in template if empty
+	TicketContent string `json:"ticketContent,omitempty"`
+}
+
+// AlertSuppressMatcher suppresses node alerts whose fields match all of its conditions
+type AlertSuppressMatcher struct {
+	// name of the matcher, suppressed counts in status are keyed by it
+	Name string `json:"name"`
+	// +kubebuilder:validation:MinItems=1
+	Conditions []AlertSuppressCondition `json:"conditions"`
+	// the matcher is ignored after expiry, never expires if empty
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	Comment   string       `json:"comment,omitempty"`
+}
+
+// operators of suppress conditions
+const (
+	SuppressOperatorEqual    = "Equal"
+	SuppressOperatorNotEqual = "NotEqual"
+	SuppressOperatorRegex    = "Regex"
+	SuppressOperatorNotRegex = "NotRegex"
+)
+
+// AlertSuppressCondition compares a field of node alerts, regexes match the whole value
+type AlertSuppressCondition struct {
+	// +kubebuilder:validation:Enum=cluster;node;component;level;type
+	Field string `json:"field"`
+	// Equal if empty
+	// +kubebuilder:validation:Enum=Equal;NotEqual;Regex;NotRegex
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value"`
+}
+
+// SlackConfig is the incoming webhook of slack
+type SlackConfig struct {
+	URL string `json:"url"`
+	// override the default channel of webhook
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// WeComConfig is the group robot of wecom
+type WeComConfig struct {
+	URL string `json:"url"`
+	// user ids to mention, "@all" mentions everyone
+	MentionedList []string `json:"mentionedList,omitempty"`
+}
+
+// FeishuConfig is the custom bot of feishu/lark
+type FeishuConfig struct {
+	URL string `json:"url"`
+	// secret of signature verification, optional
+	Secret string `json:"secret,omitempty"`
+}
+
+// EmailConfig sends alerts by smtp
+type EmailConfig struct {
+	// smtp server, host:port, port 465 uses implicit tls and others use starttls if supported
+	SmartHost string   `json:"smarthost"`
+	From      string   `json:"from"`
+	To        []string `json:"to"`
+	Username  string   `json:"username,omitempty"`
+	Password  string   `json:"password,omitempty"`
+}
+
+// WebhookConfig posts alerts as json to the url
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // AlertStatus defines the observed state of Alert
@@ -37,10 +154,13 @@ type AlertStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of alert
 	// Important: Run "make" to regenerate code after modifying this file
 	AlertCount map[string]int `json:"alertCount,omitempty"`
+	// count of node alerts suppressed by every matcher
+	Suppressed map[string]int64 `json:"suppressed,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Alert is the Schema for the alerts API
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AlertRouteSpec defines which alerts are sent to which receivers
+type AlertRouteSpec struct {
+	// routes are evaluated by priority ascending, then by name
+	Priority int `json:"priority,omitempty"`
+	// alerts matching all conditions are sent to receivers, an empty match matches all alerts
+	Match AlertRouteMatch `json:"match,omitempty"`
+	// names of Alert objects in default namespace
+	Receivers []string `json:"receivers,omitempty"`
+	// ticket backends where tickets of matched ERROR results are created,
+	// tickets are closed when checkers pass again
+	Tickets []TicketBackendType `json:"tickets,omitempty"`
+	// keep evaluating the following routes after this one matched
+	Continue bool `json:"continue,omitempty"`
+	// tiers of receivers notified when ERROR alerts keep firing without acknowledgement
+	Escalations []AlertEscalation `json:"escalations,omitempty"`
+}
+
+// AlertEscalation is a tier of receivers notified when the alert fired for a duration
+type AlertEscalation struct {
+	// duration since the alert fired, e.g. 30m
+	After metav1.Duration `json:"after"`
+	// names of Alert objects in default namespace
+	// +kubebuilder:validation:MinItems=1
+	Receivers []string `json:"receivers"`
+}
+
+// TicketBackendType is a ticket backend configured in probe-master
+// +kubebuilder:validation:Enum=erda;jira;github
+type TicketBackendType string
+
+// AlertRouteMatch is the conditions of an alert route, empty conditions match all
+type AlertRouteMatch struct {
+	// names of clusters
+	Clusters []string `json:"clusters,omitempty"`
+	// selector on the labels of Cluster objects
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// names of probes, node alerts are matched only if node-alert is listed,
+	// their checkers are "<node>/<type>/<component>"
+	Probes []string `json:"probes,omitempty"`
+	// regular expression on checker name, fully matched
+	Checker string `json:"checker,omitempty"`
+	// checker status to match, ERROR if empty
+	Severities []CheckerStatus `json:"severities,omitempty"`
+}
+
+// AlertRouteStatus defines the observed state of AlertRoute
+type AlertRouteStatus struct {
+	// error of the route spec, e.g. invalid checker regex
+	Error string `json:"error,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+// +kubebuilder:printcolumn:name="Receivers",type=string,JSONPath=`.spec.receivers`
+// +kubebuilder:printcolumn:name="Tickets",type=string,JSONPath=`.spec.tickets`
+// +kubebuilder:printcolumn:name="Continue",type=boolean,JSONPath=`.spec.continue`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// AlertRoute is the Schema for the alertroutes API
+type AlertRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AlertRouteSpec   `json:"spec,omitempty"`
+	Status AlertRouteStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AlertRouteList contains a list of AlertRoute
+type AlertRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertRoute `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertRoute{}, &AlertRouteList{})
+}
@@ -53,19 +53,25 @@ type ClusterConfig struct {
 type ClusterStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	HeartBeatTimeStamp string            `json:"heartBeatTimeStamp,omitempty"`
-	NodeCount          int               `json:"nodeCount,omitempty"`
-	AttachedProbes     []string          `json:"attachedProbes,omitempty"`
-	Checkers           string            `json:"checkers,omitempty"`
-	OnceProbeList      []OnceProbeItem   `json:"onceProbeList,omitempty"`
-	ExtraStatus        map[string]string `json:"extraStatus,omitempty"`
+
+	// last time the heartbeat of probe-agent is received,
+	// replaces the formatted string heartBeatTimeStamp
+	HeartBeatTime  *metav1.Time      `json:"heartBeatTime,omitempty"`
+	NodeCount      int               `json:"nodeCount,omitempty"`
+	AttachedProbes []string          `json:"attachedProbes,omitempty"`
+	Checkers       string            `json:"checkers,omitempty"`
+	OnceProbeList  []OnceProbeItem   `json:"onceProbeList,omitempty"`
+	ExtraStatus    map[string]string `json:"extraStatus,omitempty"`
+	// image tag of probe-agent reported by heartbeat, the digest if the image has no tag
+	AgentVersion string `json:"agentVersion,omitempty"`
 }
 
 type OnceProbeItem struct {
-	ID         string   `json:"id,omitempty"`
-	CreateTime string   `json:"createTime,omitempty"`
-	FinishTime string   `json:"finishTime,omitempty"`
-	Probes     []string `json:"probes,omitempty"`
+	ID        string       `json:"id,omitempty"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// empty until the one-time probe finishes
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	Probes         []string     `json:"probes,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -75,7 +81,7 @@ type OnceProbeItem struct {
 // +kubebuilder:printcolumn:name="PROBENAMESPACE",type=string,JSONPath=`.spec.clusterConfig.probeNamespaces`
 // +kubebuilder:printcolumn:name="PROBE",type=string,JSONPath=`.status.attachedProbes`
 // +kubebuilder:printcolumn:name="TOTAL/ERROR",type=string,JSONPath=`.status.checkers`
-// +kubebuilder:printcolumn:name="HEARTBEATTIME",type=string,JSONPath=`.status.heartBeatTimeStamp`
+// +kubebuilder:printcolumn:name="HEARTBEAT",type="date",JSONPath=`.status.heartBeatTime`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Cluster is the Schema for the clusters API
@@ -0,0 +1,55 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ProbeEnvs returns envs injected into containers of the probe, probers report checker status to reportURL by them
+func ProbeEnvs(probe Probe, reportURL string) (envs []apiv1.EnvVar, envFromSources []apiv1.EnvFromSource) {
+	// env from
+	envFromSources = []apiv1.EnvFromSource{
+		{
+			ConfigMapRef: &apiv1.ConfigMapEnvSource{
+				LocalObjectReference: apiv1.LocalObjectReference{
+					Name: ExtraCMName,
+				}}},
+	}
+
+	// envs
+	envs = []apiv1.EnvVar{
+		{
+			Name:  ProbeNamespace,
+			Value: probe.Namespace,
+		},
+		{
+			Name:  ProbeName,
+			Value: probe.Name,
+		},
+		{
+			Name:  ProbeStatusReportUrl,
+			Value: reportURL,
+		},
+	}
+
+	// env from probe configs
+	for i := range probe.Spec.Configs {
+		for j := range probe.Spec.Configs[i].Env {
+			envs = append(envs, probe.Spec.Configs[i].Env[j])
+		}
+	}
+
+	return
+}
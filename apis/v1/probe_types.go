@@ -27,6 +27,8 @@ const (
 	LabelValueApp          = "kubeprober.erda.cloud"
 	LabelKeyProbeNameSpace = "kubeprober.erda.cloud/probe-namespace"
 	LabelKeyProbeName      = "kubeprober.erda.cloud/probe-name"
+	// pods run by kubectl-probe run-local, which are not watched by probe-agent
+	LabelKeyRunLocal = "kubeprober.erda.cloud/run-local"
 
 	DefaultSourceKey   = "source"
 	DefaultSourceValue = "kubeprober"
@@ -116,7 +116,7 @@ func (p *Probe) ValidateDelete() error {
 	}
 
 	if len(attachedCluster) > 0 {
-		errstr := fmt.Sprintf("There are cluster %s attached this probe, you need detached cluster first", attachedCluster)
+		errstr := fmt.Sprintf("There are cluster %s attached this probe, you need detached cluster first by: kubectl probe detach --all %s", attachedCluster, p.Name)
 		return errors.New(errstr)
 	}
 	return nil
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SilenceSpec mutes alerts and tickets matching matchers between startsAt and endsAt
+type SilenceSpec struct {
+	Matchers SilenceMatchers `json:"matchers"`
+	StartsAt metav1.Time     `json:"startsAt"`
+	EndsAt   metav1.Time     `json:"endsAt"`
+	// who created the silence
+	CreatedBy string `json:"createdBy,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// SilenceMatchers is the conditions of a silence, empty conditions match all
+type SilenceMatchers struct {
+	// name of cluster
+	Cluster string `json:"cluster,omitempty"`
+	// name of probe
+	Probe string `json:"probe,omitempty"`
+	// name of checker
+	Checker string `json:"checker,omitempty"`
+	// checker status to match, all status if empty
+	Severities []CheckerStatus `json:"severities,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=pending;active;expired
+type SilenceState string
+
+const (
+	SilenceStatePending SilenceState = "pending"
+	SilenceStateActive  SilenceState = "active"
+	SilenceStateExpired SilenceState = "expired"
+)
+
+// SilenceStatus defines the observed state of Silence
+type SilenceStatus struct {
+	State SilenceState `json:"state,omitempty"`
+}
+
+// State returns the state of silence at time now
+func (s *Silence) State(now time.Time) SilenceState {
+	if now.Before(s.Spec.StartsAt.Time) {
+		return SilenceStatePending
+	}
+	if now.Before(s.Spec.EndsAt.Time) {
+		return SilenceStateActive
+	}
+	return SilenceStateExpired
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.matchers.cluster`
+// +kubebuilder:printcolumn:name="Probe",type=string,JSONPath=`.spec.matchers.probe`
+// +kubebuilder:printcolumn:name="Checker",type=string,JSONPath=`.spec.matchers.checker`
+// +kubebuilder:printcolumn:name="Starts",type=date,JSONPath=`.spec.startsAt`
+// +kubebuilder:printcolumn:name="Ends",type=date,JSONPath=`.spec.endsAt`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="CreatedBy",type=string,JSONPath=`.spec.createdBy`
+
+// Silence is the Schema for the silences API
+type Silence struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SilenceSpec   `json:"spec,omitempty"`
+	Status SilenceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SilenceList contains a list of Silence
+type SilenceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Silence `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Silence{}, &SilenceList{})
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+type AlertConfig struct {
+	// how long to wait before the first notification of a new alert group
+	GroupWait time.Duration
+	// how long to wait before notifying changes of an alert group
+	GroupInterval time.Duration
+	// how long to wait before sending a firing alert again
+	RepeatInterval time.Duration
+	// firing alerts without new results in this duration are resolved
+	ResolveTimeout time.Duration
+	// url of Alertmanager which checker failures are forwarded to, disabled if empty
+	AlertmanagerURL string
+}
+
+type AlertStateType string
+
+const (
+	AlertFiring   AlertStateType = "firing"
+	AlertResolved AlertStateType = "resolved"
+)
+
+// AlertState is the lifecycle of alert of one checker, keyed by cluster/probe/checker
+type AlertState struct {
+	Cluster string `json:"cluster"`
+	Probe   string `json:"probe"`
+	Checker string `json:"checker"`
+	// stable identity of the alert, independent of message text
+	Fingerprint string                     `json:"fingerprint"`
+	State       AlertStateType             `json:"state"`
+	Status      kubeproberv1.CheckerStatus `json:"status"`
+	Message     string                     `json:"message"`
+	StartsAt    time.Time                  `json:"startsAt"`
+	EndsAt      time.Time                  `json:"endsAt,omitempty"`
+	// time of the latest result of the checker
+	LastSeen time.Time `json:"lastSeen"`
+	// receivers of the firing alert decided by alert routes
+	Receivers []string `json:"receivers,omitempty"`
+	// last firing notification time of every receiver
+	Notified map[string]time.Time `json:"notified,omitempty"`
+	// escalation tiers of matched routes, ordered by duration
+	Tiers []AlertEscalationTier `json:"tiers,omitempty"`
+	// tiers the alert escalated to
+	Escalations []AlertEscalation `json:"escalations,omitempty"`
+	// acknowledged alerts are not escalated or repeated
+	Ack *AlertAck `json:"ack,omitempty"`
+}
+
+// AlertEscalationTier notifies receivers when an alert keeps firing without acknowledgement for a duration
+type AlertEscalationTier struct {
+	After     time.Duration `json:"after"`
+	Receivers []string      `json:"receivers"`
+}
+
+// AlertEscalation records the escalation of a firing alert to a tier
+type AlertEscalation struct {
+	// index of the tier
+	Tier      int       `json:"tier"`
+	Receivers []string  `json:"receivers"`
+	Time      time.Time `json:"time"`
+}
+
+// AlertAck is the acknowledgement of a firing alert
+type AlertAck struct {
+	By      string    `json:"by,omitempty"`
+	Comment string    `json:"comment,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+func (s *AlertState) Key() string {
+	return s.Cluster + "/" + s.Probe + "/" + s.Checker
+}
+
+// AlertWebhookMessage is the body posted by the webhook receiver of probe-master
+type AlertWebhookMessage struct {
+	Receiver string       `json:"receiver"`
+	Title    string       `json:"title"`
+	Text     string       `json:"text"`
+	Alerts   []AlertState `json:"alerts"`
+}
+
+// AlertRouteDecision is the receivers of an alert decided by alert routes
+type AlertRouteDecision struct {
+	// matched routes in evaluation order
+	Routes    []string `json:"routes"`
+	Receivers []string `json:"receivers"`
+	// ticket backends of the result
+	Tickets []string `json:"tickets"`
+	// escalation tiers of the result, ordered by duration
+	Escalations []AlertEscalationTier `json:"escalations,omitempty"`
+	// no route exists, error alerts are sent to all receivers
+	All bool `json:"all,omitempty"`
+}
+
+// Fingerprint returns a short stable hash of identity fields of an alert or
+// ticket, used for deduplication instead of titles
+func Fingerprint(fields ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(fields, "/")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -31,6 +31,8 @@ type HeartBeatReq struct {
 	ProbeNamespace string            `json:"probeNamespace"`
 	Checkers       string            `json:"checkers"`
 	ExtraStatus    map[string]string `json:"extraStatus"`
+	// image tag of probe-agent
+	AgentVersion string `json:"agentVersion,omitempty"`
 }
 
 type CollectProbeStatusReq struct {
@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+// inventory items besides extra status of cluster
+const (
+	InventoryK8sVersion   = "k8sVersion"
+	InventoryNodeCount    = "nodeCount"
+	InventoryAgentVersion = "agentVersion"
+)
+
+// CheckerResult is one checker report collected by probe-master
+type CheckerResult struct {
+	Cluster string                     `json:"cluster"`
+	Probe   string                     `json:"probe"`
+	Checker string                     `json:"checker"`
+	Status  kubeproberv1.CheckerStatus `json:"status"`
+	Message string                     `json:"message,omitempty"`
+	Time    time.Time                  `json:"time"`
+}
+
+// NodeAlertProbe is the probe of node alerts routed as checker results,
+// only alert routes listing it in probes match node alerts
+const NodeAlertProbe = "node-alert"
+
+// NodeAlertResult returns the checker result of a node alert, its checker is "<node>/<type>/<component>"
+func NodeAlertResult(cluster, node, typ, component string, status kubeproberv1.CheckerStatus, message string,
+	t time.Time) *CheckerResult {
+	return &CheckerResult{
+		Cluster: cluster,
+		Probe:   NodeAlertProbe,
+		Checker: fmt.Sprintf("%s/%s/%s", node, typ, component),
+		Status:  status,
+		Message: message,
+		Time:    t,
+	}
+}
+
+// Key identifies the checker which reports the result
+func (r *CheckerResult) Key() string {
+	return fmt.Sprintf("%s/%s/%s", r.Cluster, r.Probe, r.Checker)
+}
+
+// Fingerprint is the stable identity of alerts and tickets of the checker
+func (r *CheckerResult) Fingerprint() string {
+	return Fingerprint("checker", r.Cluster, r.Probe, r.Checker)
+}
+
+// StatusChange is a period in which a checker kept the same status
+type StatusChange struct {
+	Cluster string                     `json:"cluster"`
+	Probe   string                     `json:"probe"`
+	Checker string                     `json:"checker"`
+	Status  kubeproberv1.CheckerStatus `json:"status"`
+	Message string                     `json:"message,omitempty"`
+	Since   time.Time                  `json:"since"`
+	Until   time.Time                  `json:"until"`
+	Count   int                        `json:"count"`
+}
+
+// PassRate is the ratio of PASS results among all results in a period
+type PassRate struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Total int       `json:"total"`
+	Pass  int       `json:"pass"`
+	Rate  float64   `json:"rate"`
+}
+
+// HistoryResponse is returned by the history api of probe-master,
+// data is []CheckerResult, []StatusChange or []PassRate
+type HistoryResponse struct {
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+	Data  interface{} `json:"data"`
+}
+
+// ClusterInventory is the inventory of a cluster reported by heartbeat, items are
+// k8sVersion, nodeCount, agentVersion and extra status like kernelVersions and osImages
+type ClusterInventory struct {
+	Cluster string            `json:"cluster"`
+	Items   map[string]string `json:"items"`
+	Time    time.Time         `json:"time"`
+}
+
+// NewClusterInventory collects the inventory items from spec and status of cluster
+func NewClusterInventory(c *kubeproberv1.Cluster, t time.Time) *ClusterInventory {
+	items := make(map[string]string, len(c.Status.ExtraStatus)+3)
+	for k, v := range c.Status.ExtraStatus {
+		items[k] = v
+	}
+	items[InventoryK8sVersion] = c.Spec.K8sVersion
+	items[InventoryNodeCount] = strconv.Itoa(c.Status.NodeCount)
+	items[InventoryAgentVersion] = c.Status.AgentVersion
+	return &ClusterInventory{Cluster: c.Name, Items: items, Time: t}
+}
+
+// AlertRecord is one alert received by the alert proxy of probe-master
+type AlertRecord struct {
+	Cluster   string    `json:"cluster"`
+	Node      string    `json:"node,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Component string    `json:"component,omitempty"`
+	Level     string    `json:"level,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// AlertNotification is one alert sent to a receiver, alert statistics are counted from them
+type AlertNotification struct {
+	Cluster string `json:"cluster"`
+	// probe of checker alerts, alert type of node alerts
+	Type string `json:"type,omitempty"`
+	// checker of checker alerts, component of node alerts
+	Checker  string    `json:"checker,omitempty"`
+	Level    string    `json:"level,omitempty"`
+	Receiver string    `json:"receiver,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// AlertStat is the count of alert notifications in a group, fields not grouped by are empty
+type AlertStat struct {
+	Cluster  string `json:"cluster,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Checker  string `json:"checker,omitempty"`
+	Level    string `json:"level,omitempty"`
+	Receiver string `json:"receiver,omitempty"`
+	Count    int    `json:"count"`
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "time"
+
+// TicketConfig is the ticket backends besides erda, backends with empty url or repo are disabled
+type TicketConfig struct {
+	Jira   JiraConfig
+	GitHub GitHubConfig
+	// minimum interval between comments on an issue
+	CommentInterval time.Duration
+}
+
+type JiraConfig struct {
+	URL      string
+	Username string
+	// api token of jira cloud, or password of jira server
+	Token     string
+	Project   string
+	IssueType string
+	// names of workflow transitions to close and reopen issues
+	CloseTransition  string
+	ReopenTransition string
+}
+
+type GitHubConfig struct {
+	// api url, https://api.github.com default, or https://HOST/api/v3 of github enterprise
+	URL   string
+	Token string
+	// owner/repo of issues
+	Repo string
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/erda-project/kubeprober/apistructs"
+	tunnelclient "github.com/erda-project/kubeprober/cli/probe/tunnel-client"
+)
+
+var (
+	alertsBy       string
+	alertsLimit    int
+	alertsType     string
+	alertsLevel    string
+	alertsReceiver string
+	alertsAll      bool
+	alertsAckBy    string
+	alertsComment  string
+)
+
+var AlertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Manage alerts of probe-master",
+	Long:  "List and acknowledge alerts of probe-master, and print statistics of sent alerts",
+}
+
+var AlertsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List firing alerts with their acknowledgements and escalations",
+	Long:  "List firing alerts with their acknowledgements and escalations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ListAlerts()
+	},
+}
+
+var AlertsAckCmd = &cobra.Command{
+	Use:   "ack FINGERPRINT...",
+	Short: "Acknowledge firing alerts, which stops their escalations and repeats",
+	Long:  "Acknowledge firing alerts, which stops their escalations and repeats, e.g. kubectl probe alerts ack 3f2a9c1d --comment investigating",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return AckAlerts(args)
+	},
+}
+
+var AlertsTopCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Print the noisiest alert groups",
+	Long:  "Print the noisiest alert groups, e.g. kubectl probe alerts top --by cluster,type --since 168h",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return GetAlertsTop()
+	},
+}
+
+func init() {
+	AlertsCmd.AddCommand(AlertsListCmd, AlertsAckCmd, AlertsTopCmd)
+}
+
+func ListAlerts() error {
+	params := map[string]string{"cluster": clusterName}
+	if !alertsAll {
+		params["state"] = string(apistructs.AlertFiring)
+	}
+	var states []apistructs.AlertState
+	if err := requestMaster(http.MethodGet, "/api/alerts", params, nil, &states); err != nil {
+		return err
+	}
+
+	p := newPrinter("FINGERPRINT", "CLUSTER", "PROBER", "CHECKER", "STATUS", "STATE", "SINCE", "ESCALATED", "ACK").
+		Wide("RECEIVERS", "MESSAGE")
+	p.MaxColWidth = 45
+	for _, s := range states {
+		escalated := "-"
+		if n := len(s.Escalations); n > 0 {
+			escalated = fmt.Sprintf("%d/%d %s", n, len(s.Tiers), strings.Join(s.Escalations[n-1].Receivers, ","))
+		} else if len(s.Tiers) > 0 {
+			escalated = fmt.Sprintf("0/%d", len(s.Tiers))
+		}
+		ack := "-"
+		if s.Ack != nil {
+			ack = fmt.Sprintf("%s %s", s.Ack.By, formatTime(s.Ack.Time))
+		}
+		receivers := "-"
+		if len(s.Receivers) > 0 {
+			receivers = strings.Join(s.Receivers, ",")
+		}
+		p.AddRow(s.Fingerprint, s, s.Fingerprint, s.Cluster, s.Probe, s.Checker, s.Status, s.State, formatTime(s.StartsAt),
+			escalated, ack, receivers, strings.TrimSpace(s.Message))
+	}
+	return p.Print()
+}
+
+func AckAlerts(ids []string) error {
+	by := alertsAckBy
+	if by == "" {
+		by = os.Getenv("USER")
+	}
+	for _, id := range ids {
+		s := &apistructs.AlertState{}
+		if err := requestMaster(http.MethodPost, "/api/alerts/"+id+"/ack", nil,
+			&apistructs.AlertAck{By: by, Comment: alertsComment}, s); err != nil {
+			return err
+		}
+		fmt.Printf("alert %s of %s is acknowledged\n", s.Fingerprint, s.Key())
+	}
+	return nil
+}
+
+// requestMaster sends the json body to api of probe-master and decodes the response into out
+func requestMaster(method, path string, params map[string]string, body, out interface{}) error {
+	u, err := tunnelclient.GetMasterURL(path)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err = json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, u.String(), &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("request %s of probe-master failed, status: %d, body: %s", path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func GetAlertsTop() error {
+	var stats []apistructs.AlertStat
+	if err := queryMasterHistory("/api/alert/stats", map[string]string{
+		"by":       alertsBy,
+		"limit":    strconv.Itoa(alertsLimit),
+		"type":     alertsType,
+		"level":    alertsLevel,
+		"receiver": alertsReceiver,
+	}, &stats); err != nil {
+		return err
+	}
+
+	keys := strings.Split(alertsBy, ",")
+	var header []string
+	for _, k := range keys {
+		header = append(header, strings.ToUpper(strings.TrimSpace(k)))
+	}
+	p := newPrinter(append(header, "COUNT")...)
+	for _, s := range stats {
+		var row []interface{}
+		var names []string
+		for _, k := range keys {
+			v := alertStatValue(&s, strings.TrimSpace(k))
+			row = append(row, v)
+			names = append(names, v)
+		}
+		p.AddRow(strings.Join(names, "/"), s, append(row, s.Count)...)
+	}
+	return p.Print()
+}
+
+func alertStatValue(s *apistructs.AlertStat, key string) string {
+	switch key {
+	case "cluster":
+		return s.Cluster
+	case "type":
+		return s.Type
+	case "checker":
+		return s.Checker
+	case "level":
+		return s.Level
+	case "receiver":
+		return s.Receiver
+	}
+	return ""
+}
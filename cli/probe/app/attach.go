@@ -0,0 +1,186 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+const (
+	// clusters run probes whose label probe/<name> is true
+	probeLabelPrefix = "probe/"
+
+	attachActionAttach = "attach"
+	attachActionDetach = "detach"
+)
+
+var (
+	attachSelector string
+	attachAll      bool
+	attachDryRun   bool
+)
+
+// AttachResult is the result of attaching a probe to or detaching a probe from a cluster
+type AttachResult struct {
+	Cluster string `json:"cluster"`
+	Probe   string `json:"probe"`
+	Result  string `json:"result"`
+	Error   string `json:"error,omitempty"`
+}
+
+var AttachCmd = &cobra.Command{
+	Use:   "attach PROBE...",
+	Short: "Attach probes to clusters",
+	Long:  "Attach probes to clusters by label probe/<name>=true of clusters, e.g. kubectl probe attach k8s -l env=prod --dry-run",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return AttachProbes(attachActionAttach, args)
+	},
+}
+
+var DetachCmd = &cobra.Command{
+	Use:   "detach PROBE...",
+	Short: "Detach probes from clusters",
+	Long:  "Detach probes from clusters, e.g. kubectl probe detach --all k8s detaches probe k8s from all clusters before deleting it",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return AttachProbes(attachActionDetach, args)
+	},
+}
+
+// AttachProbes attaches or detaches probes of clusters selected by --cluster, --selector or --all
+func AttachProbes(action string, probeNames []string) error {
+	clusters, err := selectAttachClusters()
+	if err != nil {
+		return err
+	}
+	if action == attachActionAttach {
+		for _, name := range probeNames {
+			probe := &kubeproberv1.Probe{}
+			if err = k8sRestClient.Get(context.Background(), client.ObjectKey{
+				Namespace: metav1.NamespaceDefault,
+				Name:      name,
+			}, probe); err != nil {
+				return errors.Wrapf(err, "get probe %s", name)
+			}
+		}
+	}
+
+	p := newPrinter("CLUSTER", "PROBE", "RESULT")
+	p.MaxColWidth = 70
+	for i := range clusters {
+		for _, r := range attachClusterProbes(&clusters[i], action, probeNames, attachDryRun) {
+			result := r.Result
+			if r.Error != "" {
+				result = r.Error
+			}
+			p.AddRow(r.Cluster+"/"+r.Probe, r, r.Cluster, r.Probe, result)
+		}
+	}
+	return p.Print()
+}
+
+func selectAttachClusters() ([]kubeproberv1.Cluster, error) {
+	n := 0
+	for _, set := range []bool{clusterName != "", attachSelector != "", attachAll} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("exactly one of --cluster, --selector and --all is required")
+	}
+	if clusterName == "" {
+		return listClusters(attachSelector)
+	}
+
+	var clusters []kubeproberv1.Cluster
+	for _, name := range strings.Split(clusterName, ",") {
+		cluster := kubeproberv1.Cluster{}
+		if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+			Namespace: metav1.NamespaceDefault,
+			Name:      strings.TrimSpace(name),
+		}, &cluster); err != nil {
+			return nil, errors.Wrapf(err, "get cluster %s", name)
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// attachClusterProbes patches probe labels of the cluster, nothing is changed with dry run
+func attachClusterProbes(cluster *kubeproberv1.Cluster, action string, probeNames []string, dryRun bool) []AttachResult {
+	var results []AttachResult
+	patchLabels := make(map[string]interface{})
+	for _, name := range probeNames {
+		r := AttachResult{Cluster: cluster.Name, Probe: name}
+		attached := cluster.Labels[probeLabelPrefix+name] == "true"
+		switch {
+		case action == attachActionAttach && attached:
+			r.Result = "already attached"
+		case action == attachActionDetach && !attached:
+			r.Result = "not attached"
+		case action == attachActionAttach:
+			r.Result = "attached"
+			patchLabels[probeLabelPrefix+name] = "true"
+		default:
+			r.Result = "detached"
+			patchLabels[probeLabelPrefix+name] = nil
+		}
+		results = append(results, r)
+	}
+	if len(patchLabels) == 0 {
+		return results
+	}
+
+	var err error
+	if dryRun {
+		for i := range results {
+			if results[i].Result == "attached" || results[i].Result == "detached" {
+				results[i].Result = fmt.Sprintf("%s (dry run)", results[i].Result)
+			}
+		}
+		return results
+	}
+	var patch []byte
+	if patch, err = json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": patchLabels},
+	}); err == nil {
+		err = k8sRestClient.Patch(context.Background(), &kubeproberv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cluster.Name,
+				Namespace: metav1.NamespaceDefault,
+			},
+		}, client.RawPatch(types.MergePatchType, patch))
+	}
+	if err != nil {
+		for i := range results {
+			if _, ok := patchLabels[probeLabelPrefix+results[i].Probe]; ok {
+				results[i].Error = err.Error()
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestAttachClusterProbesDryRun(t *testing.T) {
+	cluster := &kubeproberv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+		Name:   "prod",
+		Labels: map[string]string{"probe/k8s": "true", "probe/node": "false"},
+	}}
+
+	results := attachClusterProbes(cluster, attachActionAttach, []string{"k8s", "node"}, true)
+	assert.Equal(t, []AttachResult{
+		{Cluster: "prod", Probe: "k8s", Result: "already attached"},
+		{Cluster: "prod", Probe: "node", Result: "attached (dry run)"},
+	}, results)
+
+	results = attachClusterProbes(cluster, attachActionDetach, []string{"k8s", "node"}, true)
+	assert.Equal(t, []AttachResult{
+		{Cluster: "prod", Probe: "k8s", Result: "detached (dry run)"},
+		{Cluster: "prod", Probe: "node", Result: "not attached"},
+	}, results)
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const (
+	clusterHealthy = "Healthy"
+	clusterError   = "Error"
+	clusterLost    = "Lost"
+	clusterUnknown = "Unknown"
+
+	tunnelConnected   = "Connected"
+	tunnelUnreachable = "Unreachable"
+)
+
+var (
+	clusterSelector         string
+	clusterSortBy           string
+	clusterHeartbeatTimeout time.Duration
+	clusterTunnelTimeout    time.Duration
+)
+
+// ClusterDescription is a cluster together with its health, tunnel state and firing alerts
+type ClusterDescription struct {
+	Cluster *kubeproberv1.Cluster   `json:"cluster"`
+	Health  string                  `json:"health"`
+	Tunnel  string                  `json:"tunnel,omitempty"`
+	Alerts  []apistructs.AlertState `json:"alerts,omitempty"`
+}
+
+var ClusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "List or describe clusters managed by probe-master",
+	Long:  "List or describe clusters managed by probe-master",
+}
+
+var ClusterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List clusters with their health",
+	Long: "List clusters with their health, a cluster is Lost when its heartbeat is older than --heartbeat-timeout, " +
+		"and Error when any checker is ERROR, e.g. kubectl probe cluster list --sort-by errors",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ListClusters()
+	},
+}
+
+var ClusterDescribeCmd = &cobra.Command{
+	Use:   "describe NAME",
+	Short: "Describe a cluster with attached probes, one-time probes, extra status, tunnel state and firing alerts",
+	Long:  "Describe a cluster with attached probes, one-time probes, extra status, tunnel state and firing alerts",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return DescribeCluster(args[0])
+	},
+}
+
+func init() {
+	ClusterCmd.AddCommand(ClusterListCmd, ClusterDescribeCmd)
+}
+
+func ListClusters() error {
+	clusters, err := listClusters(clusterSelector)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = sortClusters(clusters, clusterSortBy, now); err != nil {
+		return err
+	}
+
+	p := newPrinter("NAME", "VERSION", "NODES", "PROBES", "TOTAL/ERROR", "AGENT", "HEARTBEAT", "HEALTH").
+		Wide("PROBENAMESPACE", "ERDAVERSION", "AGE")
+	p.MaxColWidth = 45
+	for i := range clusters {
+		c := &clusters[i]
+		p.AddRow(c.Name, c, c.Name, orDash(c.Spec.K8sVersion), c.Status.NodeCount, len(c.Status.AttachedProbes),
+			orDash(c.Status.Checkers), orDash(c.Status.AgentVersion), heartbeatAge(c, now), clusterHealth(c, now),
+			orDash(c.Spec.ClusterConfig.ProbeNamespaces), orDash(c.Status.ExtraStatus["diceVersion"]),
+			duration.HumanDuration(now.Sub(c.CreationTimestamp.Time)))
+	}
+	return p.Print()
+}
+
+// sortClusters sorts clusters by name, heartbeat, version, nodes, errors or health, name is the tie breaker
+func sortClusters(clusters []kubeproberv1.Cluster, by string, now time.Time) error {
+	var compare func(a, b *kubeproberv1.Cluster) int
+	switch by {
+	case "", "name":
+		compare = func(a, b *kubeproberv1.Cluster) int { return 0 }
+	case "heartbeat":
+		// stale heartbeats first
+		compare = func(a, b *kubeproberv1.Cluster) int {
+			return compareTime(heartbeatTime(a), heartbeatTime(b))
+		}
+	case "version":
+		compare = func(a, b *kubeproberv1.Cluster) int { return strings.Compare(a.Spec.K8sVersion, b.Spec.K8sVersion) }
+	case "nodes":
+		compare = func(a, b *kubeproberv1.Cluster) int { return b.Status.NodeCount - a.Status.NodeCount }
+	case "errors":
+		compare = func(a, b *kubeproberv1.Cluster) int { return checkerErrors(b) - checkerErrors(a) }
+	case "health":
+		compare = func(a, b *kubeproberv1.Cluster) int {
+			return healthOrder(clusterHealth(a, now)) - healthOrder(clusterHealth(b, now))
+		}
+	default:
+		return errors.Errorf("invalid --sort-by %q, allowed values are: name, heartbeat, version, nodes, errors, health", by)
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if c := compare(&clusters[i], &clusters[j]); c != 0 {
+			return c < 0
+		}
+		return clusters[i].Name < clusters[j].Name
+	})
+	return nil
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func heartbeatTime(c *kubeproberv1.Cluster) time.Time {
+	if c.Status.HeartBeatTime == nil {
+		return time.Time{}
+	}
+	return c.Status.HeartBeatTime.Time
+}
+
+func heartbeatAge(c *kubeproberv1.Cluster, now time.Time) string {
+	if c.Status.HeartBeatTime == nil {
+		return "-"
+	}
+	return duration.HumanDuration(now.Sub(c.Status.HeartBeatTime.Time))
+}
+
+// checkerErrors parses the error count of TOTAL/ERROR checkers string
+func checkerErrors(c *kubeproberv1.Cluster) int {
+	parts := strings.Split(c.Status.Checkers, "/")
+	if len(parts) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[1])
+	return n
+}
+
+func clusterHealth(c *kubeproberv1.Cluster, now time.Time) string {
+	switch {
+	case c.Status.HeartBeatTime == nil:
+		return clusterUnknown
+	case now.Sub(c.Status.HeartBeatTime.Time) > clusterHeartbeatTimeout:
+		return clusterLost
+	case checkerErrors(c) > 0:
+		return clusterError
+	}
+	return clusterHealthy
+}
+
+// healthOrder puts unhealthy clusters first
+func healthOrder(health string) int {
+	switch health {
+	case clusterLost:
+		return 0
+	case clusterUnknown:
+		return 1
+	case clusterError:
+		return 2
+	}
+	return 3
+}
+
+func DescribeCluster(name string) error {
+	cluster := &kubeproberv1.Cluster{}
+	if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+		Namespace: metav1.NamespaceDefault,
+		Name:      name,
+	}, cluster); err != nil {
+		return err
+	}
+
+	d := &ClusterDescription{
+		Cluster: cluster,
+		Health:  clusterHealth(cluster, time.Now()),
+		Tunnel:  tunnelState(cluster, clusterTunnelTimeout),
+	}
+	alertsErr := requestMaster(http.MethodGet, "/api/alerts", map[string]string{
+		"cluster": name,
+		"state":   string(apistructs.AlertFiring),
+	}, nil, &d.Alerts)
+
+	if outputFormat != "" && outputFormat != outputWide {
+		p := newPrinter("NAME", "HEALTH", "TUNNEL")
+		p.AddRow(name, d, name, d.Health, d.Tunnel)
+		return p.Print()
+	}
+	printClusterDescription(os.Stdout, d, alertsErr)
+	return nil
+}
+
+// tunnelState checks whether the cluster can be reached through the tunnel of probe-master
+func tunnelState(cluster *kubeproberv1.Cluster, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ch := make(chan error, 1)
+	go func() {
+		c, err := GenerateProbeClient(cluster)
+		if err != nil {
+			ch <- err
+			return
+		}
+		ch <- c.Get(ctx, client.ObjectKey{
+			Namespace: cluster.Spec.ClusterConfig.ProbeNamespaces,
+			Name:      "probe-agent",
+		}, &appv1.Deployment{})
+	}()
+	select {
+	case err := <-ch:
+		if err != nil {
+			return fmt.Sprintf("%s: %s", tunnelUnreachable, strings.TrimSpace(err.Error()))
+		}
+		return tunnelConnected
+	case <-ctx.Done():
+		return fmt.Sprintf("%s: timeout after %s", tunnelUnreachable, timeout)
+	}
+}
+
+func printClusterDescription(w io.Writer, d *ClusterDescription, alertsErr error) {
+	c := d.Cluster
+	now := time.Now()
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	table.AddRow("Name:", c.Name)
+	var labels []string
+	for k, v := range c.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	table.AddRow("Labels:", orDash(strings.Join(labels, ",")))
+	table.AddRow("K8s Version:", orDash(c.Spec.K8sVersion))
+	table.AddRow("Agent Version:", orDash(c.Status.AgentVersion))
+	table.AddRow("Probe Namespace:", orDash(c.Spec.ClusterConfig.ProbeNamespaces))
+	table.AddRow("Node Count:", c.Status.NodeCount)
+	table.AddRow("Checkers (Total/Error):", orDash(c.Status.Checkers))
+	heartbeat := "-"
+	if c.Status.HeartBeatTime != nil {
+		heartbeat = fmt.Sprintf("%s (%s ago)", formatMetaTime(c.Status.HeartBeatTime), heartbeatAge(c, now))
+	}
+	table.AddRow("Heartbeat:", heartbeat)
+	table.AddRow("Health:", d.Health)
+	table.AddRow("Tunnel:", d.Tunnel)
+	table.AddRow("Attached Probes:", orDash(strings.Join(c.Status.AttachedProbes, ",")))
+	fmt.Fprintln(w, table)
+
+	fmt.Fprintln(w, "One-Time Probes:")
+	if len(c.Status.OnceProbeList) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	} else {
+		table = uitable.New()
+		table.AddRow("  ID", "PROBES", "STARTTIME", "COMPLETIONTIME")
+		for i := len(c.Status.OnceProbeList) - 1; i >= 0; i-- {
+			o := c.Status.OnceProbeList[i]
+			table.AddRow("  "+o.ID, strings.Join(o.Probes, ","), formatMetaTime(o.StartTime), formatMetaTime(o.CompletionTime))
+		}
+		fmt.Fprintln(w, table)
+	}
+
+	fmt.Fprintln(w, "Extra Status:")
+	if len(c.Status.ExtraStatus) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	} else {
+		var keys []string
+		for k := range c.Status.ExtraStatus {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		table = uitable.New()
+		table.MaxColWidth = 80
+		table.Wrap = true
+		for _, k := range keys {
+			table.AddRow("  "+k+":", orDash(c.Status.ExtraStatus[k]))
+		}
+		fmt.Fprintln(w, table)
+	}
+
+	fmt.Fprintln(w, "Firing Alerts:")
+	switch {
+	case alertsErr != nil:
+		fmt.Fprintf(w, "  <unavailable: %v>\n", alertsErr)
+	case len(d.Alerts) == 0:
+		fmt.Fprintln(w, "  <none>")
+	default:
+		table = uitable.New()
+		table.MaxColWidth = 60
+		table.Wrap = true
+		table.AddRow("  FINGERPRINT", "PROBER", "CHECKER", "STATUS", "SINCE", "MESSAGE")
+		for _, a := range d.Alerts {
+			table.AddRow("  "+a.Fingerprint, a.Probe, a.Checker, a.Status, formatTime(a.StartsAt), strings.TrimSpace(a.Message))
+		}
+		fmt.Fprintln(w, table)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestSortClusters(t *testing.T) {
+	now := time.Now()
+	clusterHeartbeatTimeout = 5 * time.Minute
+	newCluster := func(name string, heartbeat time.Duration, checkers string) kubeproberv1.Cluster {
+		c := kubeproberv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		c.Status.Checkers = checkers
+		if heartbeat >= 0 {
+			c.Status.HeartBeatTime = &metav1.Time{Time: now.Add(-heartbeat)}
+		}
+		return c
+	}
+	clusters := []kubeproberv1.Cluster{
+		newCluster("d", time.Minute, "10/0"),
+		newCluster("c", time.Minute, "10/2"),
+		newCluster("b", time.Hour, "10/0"),
+		newCluster("a", -1, ""),
+	}
+	names := func() []string {
+		var names []string
+		for _, c := range clusters {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, clusterHealthy, clusterHealth(&clusters[0], now))
+	assert.Equal(t, clusterError, clusterHealth(&clusters[1], now))
+	assert.Equal(t, clusterLost, clusterHealth(&clusters[2], now))
+	assert.Equal(t, clusterUnknown, clusterHealth(&clusters[3], now))
+
+	assert.NoError(t, sortClusters(clusters, "health", now))
+	assert.Equal(t, []string{"b", "a", "c", "d"}, names())
+	assert.NoError(t, sortClusters(clusters, "errors", now))
+	assert.Equal(t, []string{"c", "a", "b", "d"}, names())
+	assert.NoError(t, sortClusters(clusters, "heartbeat", now))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names())
+	assert.NoError(t, sortClusters(clusters, "name", now))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names())
+	assert.Error(t, sortClusters(clusters, "age", now))
+}
@@ -14,7 +14,9 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -34,9 +36,20 @@ var (
 func init() {
 	OnceCmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
 	OnceCmd.PersistentFlags().StringVarP(&probes, "probe", "p", "", "Probe name")
+	OnceCmd.PersistentFlags().DurationVarP(&onceTimeout, "timeout", "", 5*time.Minute, "Timeout of waiting for one-time probes, 0 waits forever")
+	OnceCmd.PersistentFlags().BoolVarP(&onceNoWait, "no-wait", "", false, "Create one-time probes without waiting for their results")
+	OnceCmd.PersistentFlags().BoolVarP(&onceWatch, "watch", "w", false, "Print state changes of one-time probes while waiting")
+	OnceCmd.PersistentFlags().StringVarP(&onceFailOn, "fail-on", "", "", "Exit with code 1 when any checker status reaches it [ERROR, WARN]")
+	OnceCmd.PersistentFlags().StringVarP(&onceJUnit, "junit", "", "", "Write checker results as JUnit XML report into the file")
+	OnceCmd.PersistentFlags().StringVarP(&onceReport, "report", "", "", "Write checker results as diagnostics report into the file, HTML for .html file and Markdown for others")
 
 	StatusCmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
 	StatusCmd.PersistentFlags().StringVarP(&status, "status", "s", "", "Status of probe [PASS, ERROR, INFO, WARN]")
+	StatusCmd.PersistentFlags().StringVarP(&probes, "probe", "p", "", "Probe name, drills down into its checkers with --all-clusters")
+	StatusCmd.PersistentFlags().BoolVarP(&statusAllClusters, "all-clusters", "A", false, "Print worst status of probes in all clusters")
+	StatusCmd.PersistentFlags().StringVarP(&statusSelector, "selector", "l", "", "Label selector of clusters to print, e.g. env=prod")
+	StatusCmd.PersistentFlags().IntVarP(&statusConcurrency, "concurrency", "", 10, "Number of clusters queried in parallel")
+	StatusCmd.PersistentFlags().DurationVarP(&statusClusterTimeout, "cluster-timeout", "", 30*time.Second, "Timeout of querying a cluster")
 
 	OnceStatusCmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
 	OnceStatusCmd.PersistentFlags().StringVarP(&onceID, "id", "i", "", "Id of one-time probe, print laste one-time default")
@@ -46,17 +59,136 @@ func init() {
 	OpsCmd.PersistentFlags().StringVarP(&agentImage, "set-agent-image", "", "", "Set image of agent")
 	OpsCmd.PersistentFlags().StringVarP(&agentMemoryLimit, "set-agent-memory", "", "", "Set Memory limit of agent")
 	OpsCmd.PersistentFlags().StringVarP(&agentCpuLimit, "set-agent-cpu", "", "", "Set Cpu limit of agent")
+	OpsCmd.Flags().StringVarP(&rolloutSelector, "selector", "l", "", "Label selector of clusters to roll out agent setting to")
+	OpsCmd.Flags().StringVarP(&rolloutContainer, "agent-container", "", "probe-agent", "Container name of agent in probe-agent deployment")
+	OpsCmd.Flags().IntVarP(&rolloutBatchSize, "batch-size", "", 1, "Number of clusters updated in one batch")
+	OpsCmd.Flags().DurationVarP(&rolloutPause, "pause", "", 30*time.Second, "Pause between batches")
+	OpsCmd.Flags().DurationVarP(&rolloutHealthTimeout, "health-timeout", "", 5*time.Minute, "Time to wait for agent ready and a new heartbeat of a cluster")
+	OpsCmd.Flags().BoolVarP(&rolloutDryRun, "dry-run", "", false, "Only print the rollout plan")
+	OpsCmd.Flags().BoolVarP(&rolloutRollback, "rollback", "", true, "Roll back the failed batch to its previous setting")
+	OpsCmd.Flags().BoolVarP(&listRollouts, "list-rollouts", "", false, "List records of agent rollouts")
 
 	TerminalCmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
+
+	HistoryCmd.PersistentFlags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
+	HistoryCmd.PersistentFlags().StringVarP(&probes, "probe", "p", "", "Probe name")
+	HistoryCmd.PersistentFlags().StringVarP(&historyChecker, "checker", "", "", "Checker name")
+	HistoryCmd.PersistentFlags().StringVarP(&historySince, "since", "", "24h", "Only return results newer than a relative duration like 30m or 3h")
+	HistoryCmd.PersistentFlags().StringVarP(&historyStart, "start", "", "", "Start time of results, RFC3339 format, override --since")
+	HistoryCmd.PersistentFlags().StringVarP(&historyEnd, "end", "", "", "End time of results, RFC3339 format, now default")
+	HistoryCmd.PersistentFlags().BoolVarP(&historyTimeline, "timeline", "", false, "Print status changes of checkers")
+	HistoryCmd.PersistentFlags().BoolVarP(&historyPassRate, "pass-rate", "", false, "Print pass rate of checkers per period")
+	HistoryCmd.PersistentFlags().StringVarP(&historyPeriod, "period", "", "1h", "Period of pass rate")
+
+	SilenceCreateCmd.Flags().StringVarP(&clusterName, "cluster", "c", "", "Name of cluster to silence")
+	SilenceCreateCmd.Flags().StringVarP(&probes, "probe", "p", "", "Name of a single probe to silence")
+	SilenceCreateCmd.Flags().StringVarP(&silenceChecker, "checker", "", "", "Name of checker to silence")
+	SilenceCreateCmd.Flags().StringSliceVarP(&silenceSeverities, "severity", "", nil, "Status of checker to silence [ERROR, WARN, INFO], all status default")
+	SilenceCreateCmd.Flags().StringVarP(&silenceDuration, "duration", "d", "1h", "Duration of silence")
+	SilenceCreateCmd.Flags().StringVarP(&silenceStart, "start", "", "", "Start time of silence, RFC3339 format, now default")
+	SilenceCreateCmd.Flags().StringVarP(&silenceEnd, "end", "", "", "End time of silence, RFC3339 format, override --duration")
+	SilenceCreateCmd.Flags().StringVarP(&silenceCreatedBy, "created-by", "", "", "Creator of silence, $USER default")
+	SilenceCreateCmd.Flags().StringVarP(&silenceComment, "comment", "", "", "Reason of silence")
+	SilenceListCmd.Flags().BoolVarP(&silenceAll, "all", "A", false, "Also list expired silences")
+
+	for _, cmd := range []*cobra.Command{AttachCmd, DetachCmd} {
+		cmd.Flags().StringVarP(&clusterName, "cluster", "c", "", "Comma separated names of clusters")
+		cmd.Flags().StringVarP(&attachSelector, "selector", "l", "", "Label selector of clusters, e.g. env=prod")
+		cmd.Flags().BoolVarP(&attachAll, "all", "", false, "All clusters")
+		cmd.Flags().BoolVarP(&attachDryRun, "dry-run", "", false, "Only print what would be changed")
+	}
+
+	ClusterListCmd.Flags().StringVarP(&clusterSelector, "selector", "l", "", "Label selector of clusters, e.g. env=prod")
+	ClusterListCmd.Flags().StringVarP(&clusterSortBy, "sort-by", "", "name", "Sort clusters by [name, heartbeat, version, nodes, errors, health]")
+	for _, cmd := range []*cobra.Command{ClusterListCmd, ClusterDescribeCmd} {
+		cmd.Flags().DurationVarP(&clusterHeartbeatTimeout, "heartbeat-timeout", "", 5*time.Minute, "Cluster is lost when its heartbeat is older than it")
+	}
+	ClusterDescribeCmd.Flags().DurationVarP(&clusterTunnelTimeout, "tunnel-timeout", "", 10*time.Second, "Timeout of checking the tunnel of cluster")
+
+	LogsCmd.Flags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
+	LogsCmd.Flags().StringVarP(&logsChecker, "checker", "", "", "Only print log lines of the checker")
+	LogsCmd.Flags().BoolVarP(&logsPrevious, "previous", "", false, "Print logs of the previous terminated container")
+	LogsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Stream logs")
+	LogsCmd.Flags().Int64VarP(&logsTail, "tail", "", -1, "Lines of recent logs to print, all logs default")
+
+	DiffCmd.Flags().StringVarP(&probes, "probe", "p", "", "Only compare checkers of the probe")
+	DiffCmd.Flags().StringVarP(&diffAt, "at", "", "", "Compare with the snapshot of baseline at the time from result store, RFC3339 or duration before now like 24h or 7d")
+	DiffCmd.Flags().DurationVarP(&diffWindow, "window", "", time.Hour, "Results reported in the window before --at make up the snapshot")
+	DiffCmd.Flags().DurationVarP(&diffClusterTimeout, "cluster-timeout", "", 30*time.Second, "Timeout of querying a cluster")
+	DiffCmd.Flags().BoolVarP(&diffShowAll, "all", "", false, "Also print checkers and inventory items which are the same")
+
+	ReportCmd.Flags().StringVarP(&reportSelector, "selector", "l", "", "Label selector of clusters to report, e.g. env=prod")
+	ReportCmd.Flags().StringVarP(&probes, "probe", "p", "", "Only report checkers of the probe")
+	ReportCmd.Flags().StringVarP(&reportFormat, "format", "", "", "Format of report [markdown, html], inferred from extension of --file, markdown default")
+	ReportCmd.Flags().StringVarP(&reportFile, "file", "f", "", "Write report into the file instead of stdout")
+	ReportCmd.Flags().StringVarP(&reportTitle, "title", "", "", "Title of report")
+	ReportCmd.Flags().DurationVarP(&reportSince, "since", "", 24*time.Hour, "Trends of checkers are computed from history in the duration")
+	ReportCmd.Flags().IntVarP(&reportConcurrency, "concurrency", "", 10, "Number of clusters queried in parallel")
+	ReportCmd.Flags().DurationVarP(&reportClusterTimeout, "cluster-timeout", "", 30*time.Second, "Timeout of querying a cluster")
+
+	RunLocalCmd.Flags().StringVarP(&runLocalMode, "mode", "", runLocalModeDocker, "Where the prober runs [docker, pod]")
+	RunLocalCmd.Flags().StringVarP(&runLocalListen, "listen", "", ":0", "Listen address of probe-status receiver, random port default")
+	RunLocalCmd.Flags().StringVarP(&runLocalReportAddr, "report-addr", "", "", "Address of probe-status receiver reached by the prober, 127.0.0.1 for docker and the first non-loopback ip for pod default")
+	RunLocalCmd.Flags().StringVarP(&runLocalNamespace, "namespace", "n", KBNAMESPACE, "Namespace of the probe when it is not set")
+	RunLocalCmd.Flags().StringVarP(&runLocalKubeconfig, "kubeconfig", "", "", "Kubeconfig mounted into docker, $KUBECONFIG or ~/.kube/config default")
+	RunLocalCmd.Flags().DurationVarP(&runLocalTimeout, "timeout", "", 10*time.Minute, "Timeout of running the prober, 0 waits forever")
+	RunLocalCmd.Flags().BoolVarP(&runLocalKeep, "keep", "", false, "Keep the pod after it finishes in pod mode")
+
+	AlertsListCmd.Flags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
+	AlertsListCmd.Flags().BoolVarP(&alertsAll, "all", "A", false, "Also list resolved alerts")
+	AlertsAckCmd.Flags().StringVarP(&alertsAckBy, "by", "", "", "Who acknowledges the alerts, $USER default")
+	AlertsAckCmd.Flags().StringVarP(&alertsComment, "comment", "", "", "Comment of acknowledgement")
+
+	AlertsTopCmd.Flags().StringVarP(&clusterName, "cluster", "c", "", "Name of specify cluster")
+	AlertsTopCmd.Flags().StringVarP(&alertsBy, "by", "", "cluster", "Comma separated keys to group alerts by [cluster, type, checker, level, receiver]")
+	AlertsTopCmd.Flags().IntVarP(&alertsLimit, "limit", "n", 10, "Number of groups to print, 0 prints all")
+	AlertsTopCmd.Flags().StringVarP(&alertsType, "type", "", "", "Probe of checker alerts or type of node alerts")
+	AlertsTopCmd.Flags().StringVarP(&alertsLevel, "level", "", "", "Level of alerts, e.g. ERROR or critical")
+	AlertsTopCmd.Flags().StringVarP(&alertsReceiver, "receiver", "", "", "Receiver of alerts")
+	AlertsTopCmd.Flags().StringVarP(&historySince, "since", "", "24h", "Only count alerts newer than a relative duration like 30m or 3h")
+	AlertsTopCmd.Flags().StringVarP(&historyStart, "start", "", "", "Start time of alerts, RFC3339 format, override --since")
+	AlertsTopCmd.Flags().StringVarP(&historyEnd, "end", "", "", "End time of alerts, RFC3339 format, now default")
+}
+
+// ExitError makes kubectl-probe exit with Code instead of panic, so pipelines can gate on it
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code carried by err, 0 if err doesn't carry one
+func ExitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 0
 }
 
 // NewCmdProbeStatusManager creates a *cobra.Command object with default parameters
 func NewCmdProbeStatusManager(stopCh <-chan struct{}) *cobra.Command {
 	cmd := &cobra.Command{
 		Use: "kubectl-probe",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := time.LoadLocation(displayTimezone); err != nil {
+				return fmt.Errorf("invalid timezone %q: %v", displayTimezone, err)
+			}
+			return validateOutput(outputFormat)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println("Kubeprober CLI Version: v0.0.3 -- HEAD")
 		},
 	}
+	cmd.PersistentFlags().StringVarP(&displayTimezone, "timezone", "", "", "Timezone to display times, e.g. UTC or Asia/Shanghai, local timezone default")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format of read commands [json, yaml, wide, name, custom-columns=<header>:<json-path>,..., jsonpath=<template>]")
+	cmd.PersistentFlags().BoolVarP(&noHeaders, "no-headers", "", false, "Don't print headers of table output")
 	return cmd
 }
@@ -0,0 +1,332 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/report"
+)
+
+const (
+	diffKindChecker   = "checker"
+	diffKindInventory = "inventory"
+
+	diffRegression = "REGRESSION"
+	diffChanged    = "Changed"
+	diffAdded      = "Added"
+	diffRemoved    = "Removed"
+	diffImproved   = "Improved"
+	diffSame       = "Same"
+)
+
+var (
+	diffAt             string
+	diffWindow         time.Duration
+	diffClusterTimeout time.Duration
+	diffShowAll        bool
+)
+
+// ClusterSnapshot is the checker results and inventory of a cluster now or at a point in time
+type ClusterSnapshot struct {
+	Cluster string `json:"cluster"`
+	// zero for the current state of cluster
+	Time      time.Time                  `json:"time,omitempty"`
+	Checkers  []apistructs.CheckerResult `json:"checkers"`
+	Inventory map[string]string          `json:"inventory,omitempty"`
+}
+
+// Name is the cluster name, with the time of snapshot if it is from history
+func (s *ClusterSnapshot) Name() string {
+	if s.Time.IsZero() {
+		return s.Cluster
+	}
+	return s.Cluster + "@" + formatTime(s.Time)
+}
+
+// DiffEntry is one difference of checker status or inventory item between baseline and target
+type DiffEntry struct {
+	Kind     string `json:"kind"`
+	Item     string `json:"item"`
+	Baseline string `json:"baseline"`
+	Target   string `json:"target"`
+	Change   string `json:"change"`
+	// message of target checker
+	Message string `json:"message,omitempty"`
+}
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff CLUSTER [BASELINE_CLUSTER]",
+	Short: "Compare checker results and inventory of a cluster with a baseline",
+	Long: "Compare checker results and inventory (k8s version, kernel, OS image, addon hosts...) of a cluster with a baseline, " +
+		"the baseline is another cluster or the cluster itself at --at from the result store of probe-master, " +
+		"e.g. kubectl probe diff prod-a prod-b, kubectl probe diff prod-a --at 7d",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 && diffAt == "" {
+			return errors.New("a baseline cluster or --at is required")
+		}
+		cmd.SilenceUsage = true
+		baseline := args[0]
+		if len(args) == 2 {
+			baseline = args[1]
+		}
+		return DiffClusters(args[0], baseline, diffAt)
+	},
+}
+
+func DiffClusters(target string, baseline string, at string) error {
+	t, err := currentSnapshot(target)
+	if err != nil {
+		return errors.Errorf("get status of cluster %s error: %v", target, err)
+	}
+
+	var b *ClusterSnapshot
+	if at == "" {
+		b, err = currentSnapshot(baseline)
+	} else {
+		var atTime time.Time
+		if atTime, err = parseDiffTime(at, time.Now()); err != nil {
+			return err
+		}
+		b, err = historySnapshot(baseline, atTime, diffWindow)
+	}
+	if err != nil {
+		return errors.Errorf("get status of cluster %s error: %v", baseline, err)
+	}
+
+	entries := diffSnapshots(b, t, probes)
+	printDiffSummary(b, t, entries)
+	p := newPrinter("KIND", "ITEM", strings.ToUpper(b.Name()), strings.ToUpper(t.Name()), "CHANGE").Wide("MESSAGE")
+	p.MaxColWidth = 60
+	for _, e := range entries {
+		if e.Change == diffSame && !diffShowAll {
+			continue
+		}
+		p.AddRow(e.Kind+"/"+e.Item, e, e.Kind, e.Item, orDash(e.Baseline), orDash(e.Target), e.Change, e.Message)
+	}
+	return p.Print()
+}
+
+// currentSnapshot reads checker results through the tunnel and inventory from the cluster resource
+func currentSnapshot(name string) (*ClusterSnapshot, error) {
+	cluster := &kubeproberv1.Cluster{}
+	if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+		Namespace: metav1.NamespaceDefault,
+		Name:      name,
+	}, cluster); err != nil {
+		return nil, err
+	}
+	results, err := queryClusterResults(context.Background(), cluster, diffClusterTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterSnapshot{
+		Cluster:   name,
+		Checkers:  results,
+		Inventory: apistructs.NewClusterInventory(cluster, heartbeatTime(cluster)).Items,
+	}, nil
+}
+
+// historySnapshot takes the latest checker results and inventory of cluster in window before at
+func historySnapshot(name string, at time.Time, window time.Duration) (*ClusterSnapshot, error) {
+	params := map[string]string{
+		"cluster": name,
+		"start":   at.Add(-window).Format(time.RFC3339),
+		"end":     at.Format(time.RFC3339),
+	}
+	var results []apistructs.CheckerResult
+	if err := queryMasterHistory("/api/history/results", params, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, errors.Errorf("no checker results in result store between %s and %s",
+			formatTime(at.Add(-window)), formatTime(at))
+	}
+
+	s := &ClusterSnapshot{Cluster: name, Time: at, Checkers: report.LatestResults(results)}
+	var inventories []apistructs.ClusterInventory
+	if err := queryMasterHistory("/api/history/inventory", params, &inventories); err != nil {
+		printProgress("inventory of %s is not compared: %v\n", s.Name(), err)
+	} else if len(inventories) == 0 {
+		printProgress("inventory of %s is not compared: no heartbeat in result store\n", s.Name())
+	} else {
+		s.Inventory = inventories[len(inventories)-1].Items
+	}
+	return s, nil
+}
+
+// parseDiffTime accepts RFC3339 timestamps or a duration before now like 24h, -24h or 7d
+func parseDiffTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d := strings.TrimPrefix(s, "-")
+	if strings.HasSuffix(d, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(d, "d")); err == nil && days >= 0 {
+			return now.Add(-time.Duration(days) * 24 * time.Hour), nil
+		}
+	} else if dur, err := time.ParseDuration(d); err == nil && dur >= 0 {
+		return now.Add(-dur), nil
+	}
+	return time.Time{}, errors.Errorf("invalid time %q, RFC3339 or duration like 24h or 7d is expected", s)
+}
+
+// statusSeverity orders checker status from healthy to failed
+func statusSeverity(s string) int {
+	switch kubeproberv1.CheckerStatus(s) {
+	case kubeproberv1.CheckerStatusPass, kubeproberv1.CheckerStatusInfo:
+		return 0
+	case kubeproberv1.CheckerStatusUNKNOWN, "":
+		return 1
+	case kubeproberv1.CheckerStatusWARN:
+		return 2
+	}
+	return 3
+}
+
+// diffSnapshots compares checker status and inventory of target with baseline,
+// regressions are listed first, checkers are filtered by probe if given
+func diffSnapshots(baseline, target *ClusterSnapshot, probe string) []DiffEntry {
+	var entries []DiffEntry
+
+	checkers := func(s *ClusterSnapshot) map[string]apistructs.CheckerResult {
+		m := make(map[string]apistructs.CheckerResult)
+		for _, r := range s.Checkers {
+			if probe == "" || r.Probe == probe {
+				m[r.Probe+"/"+r.Checker] = r
+			}
+		}
+		return m
+	}
+	b, t := checkers(baseline), checkers(target)
+	keys := make(map[string]bool)
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range t {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		br, inBaseline := b[k]
+		tr, inTarget := t[k]
+		e := DiffEntry{Kind: diffKindChecker, Item: k, Baseline: string(br.Status), Target: string(tr.Status),
+			Message: strings.TrimSpace(tr.Message)}
+		switch {
+		case !inTarget:
+			e.Change = diffRemoved
+		case !inBaseline:
+			e.Change = diffAdded
+			if statusSeverity(e.Target) > 0 {
+				e.Change = diffRegression
+			}
+		case statusSeverity(e.Target) > statusSeverity(e.Baseline):
+			e.Change = diffRegression
+		case statusSeverity(e.Target) < statusSeverity(e.Baseline):
+			e.Change = diffImproved
+		case e.Target != e.Baseline:
+			e.Change = diffChanged
+		default:
+			e.Change = diffSame
+		}
+		entries = append(entries, e)
+	}
+
+	// inventory is unknown for snapshots without heartbeat in result store
+	if baseline.Inventory != nil && target.Inventory != nil {
+		keys = make(map[string]bool)
+		for k := range baseline.Inventory {
+			keys[k] = true
+		}
+		for k := range target.Inventory {
+			keys[k] = true
+		}
+		for _, k := range sortedKeys(keys) {
+			bv, inBaseline := baseline.Inventory[k]
+			tv, inTarget := target.Inventory[k]
+			e := DiffEntry{Kind: diffKindInventory, Item: k, Baseline: bv, Target: tv, Change: diffSame}
+			switch {
+			case !inTarget:
+				e.Change = diffRemoved
+			case !inBaseline:
+				e.Change = diffAdded
+			case !sameInventoryValue(bv, tv):
+				e.Change = diffChanged
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return diffChangeOrder(entries[i].Change) < diffChangeOrder(entries[j].Change)
+	})
+	return entries
+}
+
+// sameInventoryValue ignores the order of comma separated values like kernelVersions
+func sameInventoryValue(a, b string) bool {
+	if a == b {
+		return true
+	}
+	as, bs := strings.Split(a, ","), strings.Split(b, ",")
+	sort.Strings(as)
+	sort.Strings(bs)
+	return strings.Join(as, ",") == strings.Join(bs, ",")
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffChangeOrder(change string) int {
+	switch change {
+	case diffRegression:
+		return 0
+	case diffRemoved:
+		return 1
+	case diffAdded:
+		return 2
+	case diffChanged:
+		return 3
+	case diffImproved:
+		return 4
+	}
+	return 5
+}
+
+func printDiffSummary(baseline, target *ClusterSnapshot, entries []DiffEntry) {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[e.Change]++
+	}
+	printProgress("%s compared with %s: %d regressions, %d improved, %d changed, %d added, %d removed, %d same\n",
+		target.Name(), baseline.Name(), counts[diffRegression], counts[diffImproved], counts[diffChanged],
+		counts[diffAdded], counts[diffRemoved], counts[diffSame])
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func TestParseDiffTime(t *testing.T) {
+	now := time.Date(2021, 10, 8, 12, 0, 0, 0, time.UTC)
+	for s, want := range map[string]time.Time{
+		"2021-10-01T12:00:00Z": time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		"24h":                  now.Add(-24 * time.Hour),
+		"-30m":                 now.Add(-30 * time.Minute),
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+	} {
+		got, err := parseDiffTime(s, now)
+		assert.NoError(t, err, s)
+		assert.True(t, want.Equal(got), s)
+	}
+	for _, s := range []string{"yesterday", "7w", "-d"} {
+		_, err := parseDiffTime(s, now)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	baseline := &ClusterSnapshot{
+		Cluster: "prod-b",
+		Checkers: []apistructs.CheckerResult{
+			{Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusPass},
+			{Probe: "k8s", Checker: "etcd", Status: kubeproberv1.CheckerStatusWARN},
+			{Probe: "k8s", Checker: "node", Status: kubeproberv1.CheckerStatusPass},
+			{Probe: "k8s", Checker: "pods", Status: kubeproberv1.CheckerStatusPass},
+			{Probe: "addon", Checker: "mysql", Status: kubeproberv1.CheckerStatusPass},
+		},
+		Inventory: map[string]string{"k8sVersion": "v1.20.0", "kernelVersions": "5.4,4.19", "mysqlHost": "10.0.0.1"},
+	}
+	target := &ClusterSnapshot{
+		Cluster: "prod-a",
+		Checkers: []apistructs.CheckerResult{
+			{Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusError, Message: " timeout "},
+			{Probe: "k8s", Checker: "etcd", Status: kubeproberv1.CheckerStatusPass},
+			{Probe: "k8s", Checker: "node", Status: kubeproberv1.CheckerStatusPass},
+			{Probe: "k8s", Checker: "ingress", Status: kubeproberv1.CheckerStatusWARN},
+			{Probe: "addon", Checker: "mysql", Status: kubeproberv1.CheckerStatusError},
+		},
+		Inventory: map[string]string{"k8sVersion": "v1.21.0", "kernelVersions": "4.19,5.4", "osImages": "CentOS 7"},
+	}
+
+	entries := diffSnapshots(baseline, target, "k8s")
+	assert.Equal(t, []DiffEntry{
+		{Kind: diffKindChecker, Item: "k8s/dns", Baseline: "PASS", Target: "ERROR", Change: diffRegression, Message: "timeout"},
+		{Kind: diffKindChecker, Item: "k8s/ingress", Target: "WARN", Change: diffRegression},
+		{Kind: diffKindChecker, Item: "k8s/pods", Baseline: "PASS", Change: diffRemoved},
+		{Kind: diffKindInventory, Item: "mysqlHost", Baseline: "10.0.0.1", Change: diffRemoved},
+		{Kind: diffKindInventory, Item: "osImages", Target: "CentOS 7", Change: diffAdded},
+		{Kind: diffKindInventory, Item: "k8sVersion", Baseline: "v1.20.0", Target: "v1.21.0", Change: diffChanged},
+		{Kind: diffKindChecker, Item: "k8s/etcd", Baseline: "WARN", Target: "PASS", Change: diffImproved},
+		{Kind: diffKindChecker, Item: "k8s/node", Baseline: "PASS", Target: "PASS", Change: diffSame},
+		{Kind: diffKindInventory, Item: "kernelVersions", Baseline: "5.4,4.19", Target: "4.19,5.4", Change: diffSame},
+	}, entries)
+
+	// inventory of history snapshots without heartbeat is not compared
+	baseline.Inventory = nil
+	for _, e := range diffSnapshots(baseline, target, "") {
+		assert.Equal(t, diffKindChecker, e.Kind)
+	}
+}
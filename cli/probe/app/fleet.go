@@ -0,0 +1,226 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const statusUnreachable = "UNREACHABLE"
+
+var (
+	statusAllClusters    bool
+	statusSelector       string
+	statusConcurrency    int
+	statusClusterTimeout time.Duration
+)
+
+// ClusterProbeStatus is the worst checker status of every probe in a cluster,
+// or of every checker when drilling down into a probe
+type ClusterProbeStatus struct {
+	Cluster  string                                `json:"cluster"`
+	Status   kubeproberv1.CheckerStatus            `json:"status,omitempty"`
+	Probes   map[string]kubeproberv1.CheckerStatus `json:"probes,omitempty"`
+	Checkers map[string]kubeproberv1.CheckerStatus `json:"checkers,omitempty"`
+	Error    string                                `json:"error,omitempty"`
+}
+
+type clusterResults struct {
+	cluster string
+	results []apistructs.CheckerResult
+	err     error
+}
+
+// GetFleetStatus prints a matrix of clusters and probes, probe drills down into its checkers
+func GetFleetStatus(selector string, probe string, status string) error {
+	clusters, err := listClusters(selector)
+	if err != nil {
+		fmt.Printf("Get cluster list error: %+v\n", err)
+		return err
+	}
+
+	fleet := collectFleetResults(context.Background(), clusters, statusConcurrency, statusClusterTimeout)
+	statuses, columns := aggregateFleetStatus(fleet, probe, status)
+
+	p := newPrinter(append([]string{"CLUSTER", "STATUS"}, columns...)...)
+	var unreachable []ClusterProbeStatus
+	for _, s := range statuses {
+		cells := []interface{}{s.Cluster, orDash(string(s.Status))}
+		items := s.Probes
+		if probe != "" {
+			items = s.Checkers
+		}
+		if s.Error != "" {
+			cells[1] = statusUnreachable
+			unreachable = append(unreachable, s)
+		}
+		for _, c := range columns {
+			cells = append(cells, orDash(string(items[c])))
+		}
+		p.AddRow(s.Cluster, s, cells...)
+	}
+	if err := p.Print(); err != nil {
+		return err
+	}
+
+	if len(unreachable) > 0 {
+		printProgress("\n%d of %d clusters are unreachable:\n", len(unreachable), len(fleet))
+		for _, s := range unreachable {
+			printProgress("  %s: %s\n", s.Cluster, s.Error)
+		}
+	}
+	return nil
+}
+
+// listClusters returns clusters matching the label selector, all clusters if selector is empty
+func listClusters(selector string) ([]kubeproberv1.Cluster, error) {
+	opts := []client.ListOption{client.InNamespace(metav1.NamespaceDefault)}
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: s})
+	}
+	clusterList := &kubeproberv1.ClusterList{}
+	if err := k8sRestClient.List(context.Background(), clusterList, opts...); err != nil {
+		return nil, err
+	}
+	return clusterList.Items, nil
+}
+
+// collectFleetResults queries checker status of clusters in parallel, every cluster has its own timeout
+func collectFleetResults(ctx context.Context, clusters []kubeproberv1.Cluster, concurrency int, timeout time.Duration) []clusterResults {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	fleet := make([]clusterResults, len(clusters))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range clusters {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results, err := queryClusterResults(ctx, &clusters[i], timeout)
+			fleet[i] = clusterResults{cluster: clusters[i].Name, results: results, err: err}
+		}(i)
+	}
+	wg.Wait()
+	return fleet
+}
+
+func queryClusterResults(ctx context.Context, cluster *kubeproberv1.Cluster, timeout time.Duration) ([]apistructs.CheckerResult, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	type response struct {
+		results []apistructs.CheckerResult
+		err     error
+	}
+	// creating client dials the tunnel without context, so wait for it in background
+	ch := make(chan response, 1)
+	go func() {
+		c, err := GenerateProbeClient(cluster)
+		if err != nil {
+			ch <- response{err: err}
+			return
+		}
+		results, err := listCheckerResults(ctx, c, cluster.Name)
+		ch <- response{results: results, err: err}
+	}()
+	select {
+	case r := <-ch:
+		return r.results, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("query probe status timeout after %s", timeout)
+	}
+}
+
+// aggregateFleetStatus returns the worst status of every cluster and the sorted names of matrix columns,
+// clusters without results of the status are omitted when status is given
+func aggregateFleetStatus(fleet []clusterResults, probe string, status string) ([]ClusterProbeStatus, []string) {
+	columnSet := make(map[string]bool)
+	var statuses []ClusterProbeStatus
+	for _, f := range fleet {
+		s := ClusterProbeStatus{Cluster: f.cluster}
+		if f.err != nil {
+			s.Error = f.err.Error()
+			statuses = append(statuses, s)
+			continue
+		}
+		items := make(map[string]kubeproberv1.CheckerStatus)
+		for _, r := range f.results {
+			if status != "" && string(r.Status) != status {
+				continue
+			}
+			key := r.Probe
+			if probe != "" {
+				if r.Probe != probe {
+					continue
+				}
+				key = r.Checker
+			}
+			if old, ok := items[key]; !ok || r.Status.Priority() > old.Priority() {
+				items[key] = r.Status
+			}
+			if s.Status == "" || r.Status.Priority() > s.Status.Priority() {
+				s.Status = r.Status
+			}
+			columnSet[key] = true
+		}
+		if len(items) == 0 && status != "" {
+			continue
+		}
+		if probe == "" {
+			s.Probes = items
+		} else {
+			s.Checkers = items
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Cluster < statuses[j].Cluster
+	})
+
+	var columns []string
+	for c := range columnSet {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+	return statuses, columns
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func TestAggregateFleetStatus(t *testing.T) {
+	fleet := []clusterResults{
+		{cluster: "b", results: []apistructs.CheckerResult{
+			{Cluster: "b", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusPass},
+			{Cluster: "b", Probe: "k8s", Checker: "etcd", Status: kubeproberv1.CheckerStatusWARN},
+			{Cluster: "b", Probe: "node", Checker: "disk", Status: kubeproberv1.CheckerStatusPass},
+		}},
+		{cluster: "a", results: []apistructs.CheckerResult{
+			{Cluster: "a", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusError},
+		}},
+		{cluster: "c", err: errors.New("timeout")},
+	}
+
+	statuses, columns := aggregateFleetStatus(fleet, "", "")
+	assert.Equal(t, []string{"k8s", "node"}, columns)
+	assert.Equal(t, []ClusterProbeStatus{
+		{Cluster: "a", Status: kubeproberv1.CheckerStatusError, Probes: map[string]kubeproberv1.CheckerStatus{"k8s": kubeproberv1.CheckerStatusError}},
+		{Cluster: "b", Status: kubeproberv1.CheckerStatusWARN, Probes: map[string]kubeproberv1.CheckerStatus{
+			"k8s": kubeproberv1.CheckerStatusWARN, "node": kubeproberv1.CheckerStatusPass}},
+		{Cluster: "c", Error: "timeout"},
+	}, statuses)
+
+	statuses, columns = aggregateFleetStatus(fleet, "k8s", "")
+	assert.Equal(t, []string{"dns", "etcd"}, columns)
+	assert.Equal(t, map[string]kubeproberv1.CheckerStatus{
+		"dns": kubeproberv1.CheckerStatusPass, "etcd": kubeproberv1.CheckerStatusWARN}, statuses[1].Checkers)
+
+	statuses, columns = aggregateFleetStatus(fleet, "", "ERROR")
+	assert.Equal(t, []string{"k8s"}, columns)
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, "a", statuses[0].Cluster)
+	assert.Equal(t, "c", statuses[1].Cluster)
+}
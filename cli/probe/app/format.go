@@ -0,0 +1,50 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const displayTimeLayout = "2006-01-02 15:04:05"
+
+// timezone used to display times, local timezone default
+var displayTimezone string
+
+func displayLocation() *time.Location {
+	if displayTimezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(displayTimezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(displayLocation()).Format(displayTimeLayout)
+}
+
+func formatMetaTime(t *metav1.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return formatTime(t.Time)
+}
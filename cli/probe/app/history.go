@@ -0,0 +1,146 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/erda-project/kubeprober/apistructs"
+	tunnelclient "github.com/erda-project/kubeprober/cli/probe/tunnel-client"
+)
+
+var (
+	historyChecker  string
+	historySince    string
+	historyStart    string
+	historyEnd      string
+	historyTimeline bool
+	historyPassRate bool
+	historyPeriod   string
+)
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Print history checker results collected by probe-master",
+	Long:  "Print history checker results collected by probe-master",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyTimeline {
+			return GetCheckerTimeline()
+		}
+		if historyPassRate {
+			return GetCheckerPassRate()
+		}
+		return GetCheckerHistory()
+	},
+}
+
+func GetCheckerHistory() error {
+	var results []apistructs.CheckerResult
+	if err := queryMasterHistory("/api/history/results", nil, &results); err != nil {
+		return err
+	}
+
+	p := newPrinter("CLUSTER", "PROBER", "CHECKER", "STATUS", "MESSAGE", "TIME")
+	p.MaxColWidth = 45
+	for _, r := range results {
+		p.AddRow(r.Key(), r, r.Cluster, r.Probe, r.Checker, r.Status, strings.TrimSpace(r.Message), formatTime(r.Time))
+	}
+	return p.Print()
+}
+
+func GetCheckerTimeline() error {
+	var changes []apistructs.StatusChange
+	if err := queryMasterHistory("/api/history/timeline", nil, &changes); err != nil {
+		return err
+	}
+
+	p := newPrinter("CLUSTER", "PROBER", "CHECKER", "STATUS", "SINCE", "UNTIL", "COUNT", "MESSAGE")
+	p.MaxColWidth = 45
+	for _, c := range changes {
+		p.AddRow(fmt.Sprintf("%s/%s/%s", c.Cluster, c.Probe, c.Checker), c, c.Cluster, c.Probe, c.Checker, c.Status,
+			formatTime(c.Since), formatTime(c.Until), c.Count, strings.TrimSpace(c.Message))
+	}
+	return p.Print()
+}
+
+func GetCheckerPassRate() error {
+	var rates []apistructs.PassRate
+	if err := queryMasterHistory("/api/history/passrate", map[string]string{"period": historyPeriod}, &rates); err != nil {
+		return err
+	}
+
+	p := newPrinter("START", "END", "TOTAL", "PASS", "RATE")
+	for _, r := range rates {
+		rate := "-"
+		if r.Total > 0 {
+			rate = fmt.Sprintf("%.2f%%", r.Rate*100)
+		}
+		p.AddRow(formatTime(r.Start), r, formatTime(r.Start), formatTime(r.End), r.Total, r.Pass, rate)
+	}
+	return p.Print()
+}
+
+// queryMasterHistory requests the history api of probe-master and decodes the data of response into data
+func queryMasterHistory(path string, params map[string]string, data interface{}) error {
+	u, err := tunnelclient.GetMasterURL(path)
+	if err != nil {
+		return err
+	}
+
+	start := historyStart
+	if start == "" && historySince != "" {
+		start = "-" + strings.TrimPrefix(historySince, "-")
+	}
+	q := u.Query()
+	for k, v := range map[string]string{
+		"cluster": clusterName,
+		"probe":   probes,
+		"checker": historyChecker,
+		"start":   start,
+		"end":     historyEnd,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("query history from probe-master failed, status: %d, body: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	r := apistructs.HistoryResponse{Data: data}
+	return json.Unmarshal(body, &r)
+}
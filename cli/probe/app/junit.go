@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// newJUnitReport makes a test suite of every probe and a test case of every checker,
+// checkers reaching failOn are failures, failed probe jobs are errors and unknown checkers are skipped
+func newJUnitReport(cluster string, onceID string, once []onceProbe, results []apistructs.CheckerResult, failOn kubeproberv1.CheckerStatus) *junitTestSuites {
+	name := "kubeprober"
+	if cluster != "" {
+		name = "kubeprober." + cluster
+	}
+	report := &junitTestSuites{Name: name}
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05")
+	for _, o := range once {
+		suite := junitTestSuite{Name: o.Name, Timestamp: timestamp}
+		for _, r := range results {
+			if r.Probe != o.Job {
+				continue
+			}
+			c := junitTestCase{ClassName: name + "." + o.Name, Name: r.Checker, Time: "0"}
+			switch {
+			case r.Status.Priority() >= failOn.Priority():
+				c.Failure = &junitMessage{Message: fmt.Sprintf("%s is %s", r.Checker, r.Status), Type: string(r.Status), Text: r.Message}
+				suite.Failures++
+			case r.Status == kubeproberv1.CheckerStatusUNKNOWN:
+				c.Skipped = &junitMessage{Message: r.Message}
+				suite.Skipped++
+			default:
+				c.SystemOut = r.Message
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		if o.State != onceStateSucceeded && len(suite.Cases) == 0 {
+			state := o.State
+			if state == "" {
+				state = onceStatePending
+			}
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: name + "." + o.Name,
+				Name:      "job",
+				Time:      "0",
+				Error:     &junitMessage{Message: fmt.Sprintf("job %s of one-time probe %s is %s", o.Job, onceID, strings.ToLower(state))},
+			})
+			suite.Errors++
+		}
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+func writeJUnitReport(path string, cluster string, onceID string, once []onceProbe, results []apistructs.CheckerResult, failOn kubeproberv1.CheckerStatus) error {
+	data, err := xml.MarshalIndent(newJUnitReport(cluster, onceID, once, results, failOn), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
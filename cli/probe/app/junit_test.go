@@ -0,0 +1,54 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func TestNewJUnitReport(t *testing.T) {
+	once := []onceProbe{
+		{Name: "k8s", Job: "k8s-once-1", State: onceStateSucceeded},
+		{Name: "node", Job: "node-once-1", State: onceStateFailed},
+	}
+	results := []apistructs.CheckerResult{
+		{Probe: "k8s-once-1", Checker: "dns", Status: kubeproberv1.CheckerStatusPass},
+		{Probe: "k8s-once-1", Checker: "etcd", Status: kubeproberv1.CheckerStatusWARN, Message: "slow"},
+		{Probe: "k8s-once-1", Checker: "apiserver", Status: kubeproberv1.CheckerStatusError, Message: "down"},
+		{Probe: "k8s-once-1", Checker: "coredns", Status: kubeproberv1.CheckerStatusUNKNOWN},
+	}
+
+	report := newJUnitReport("prod", "1", once, results, kubeproberv1.CheckerStatusError)
+	assert.Equal(t, "kubeprober.prod", report.Name)
+	assert.Equal(t, 5, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, "kubeprober.prod.k8s", report.Suites[0].Cases[2].ClassName)
+	assert.Equal(t, "down", report.Suites[0].Cases[2].Failure.Text)
+	assert.Equal(t, "job", report.Suites[1].Cases[0].Name)
+	assert.NotNil(t, report.Suites[1].Cases[0].Error)
+
+	report = newJUnitReport("", "1", once, results, kubeproberv1.CheckerStatusWARN)
+	assert.Equal(t, 2, report.Failures)
+
+	_, err := xml.Marshal(report)
+	assert.NoError(t, err)
+}
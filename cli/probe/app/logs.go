@@ -0,0 +1,203 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	tunnelclient "github.com/erda-project/kubeprober/cli/probe/tunnel-client"
+)
+
+var (
+	logsChecker  string
+	logsPrevious bool
+	logsFollow   bool
+	logsTail     int64
+)
+
+var LogsCmd = &cobra.Command{
+	Use:   "logs PROBE|ONCE_ID",
+	Short: "Print logs of the latest pod of a probe or a one-time probe",
+	Long: "Print logs of the latest pod of a probe, or pods of all probes in a one-time probe run, " +
+		"e.g. kubectl probe logs -c prod k8s --checker dns -f",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return GetProbeLogs(clusterName, args[0])
+	},
+}
+
+// probePod is the latest pod of a probe
+type probePod struct {
+	probe string
+	pod   corev1.Pod
+}
+
+func GetProbeLogs(clusterName string, target string) error {
+	var config *rest.Config
+	var err error
+	namespace := KBNAMESPACE
+	if clusterName == "" {
+		if config, err = localRestConfig(); err != nil {
+			return err
+		}
+	} else {
+		cluster := &kubeproberv1.Cluster{}
+		if err = k8sRestClient.Get(context.Background(), client.ObjectKey{
+			Namespace: metav1.NamespaceDefault,
+			Name:      clusterName,
+		}, cluster); err != nil {
+			fmt.Printf("Get cluster info error: %+v\n", err)
+			return err
+		}
+		if config, err = tunnelclient.GenerateProbeClientConf(cluster); err != nil {
+			return err
+		}
+		namespace = cluster.Spec.ClusterConfig.ProbeNamespaces
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	podList, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: kubeproberv1.LabelKeyProbeName})
+	if err != nil {
+		return err
+	}
+	pods := latestProbePods(podList.Items, target)
+	if len(pods) == 0 {
+		return errors.Errorf("no pod of probe or one-time probe %s is found in namespace %s", target, namespace)
+	}
+
+	containers := 0
+	for _, p := range pods {
+		containers += len(p.pod.Spec.Containers)
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errCh := make(chan error, containers)
+	for _, p := range pods {
+		for _, c := range p.pod.Spec.Containers {
+			prefix := ""
+			if containers > 1 {
+				prefix = fmt.Sprintf("[%s/%s] ", p.probe, c.Name)
+			}
+			printProgress("==> probe: %s, job: %s, pod: %s, container: %s <==\n",
+				p.probe, p.pod.Labels["job-name"], p.pod.Name, c.Name)
+			req := clientset.CoreV1().Pods(namespace).GetLogs(p.pod.Name, &corev1.PodLogOptions{
+				Container: c.Name,
+				Follow:    logsFollow,
+				Previous:  logsPrevious,
+				TailLines: tailLines(logsTail),
+			})
+			// followed logs never end, so stream them at the same time
+			if logsFollow {
+				wg.Add(1)
+				go func(req *rest.Request, prefix string) {
+					defer wg.Done()
+					if err := streamLogs(ctx, req, os.Stdout, &mu, prefix, logsChecker); err != nil {
+						errCh <- err
+					}
+				}(req, prefix)
+				continue
+			}
+			if err = streamLogs(ctx, req, os.Stdout, &mu, prefix, logsChecker); err != nil {
+				return err
+			}
+		}
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+// latestProbePods returns the latest pod of the probe named target,
+// or the latest pod of every probe in the one-time probe run whose id is target
+func latestProbePods(pods []corev1.Pod, target string) []probePod {
+	latest := make(map[string]corev1.Pod)
+	for _, pod := range pods {
+		name := pod.Labels[kubeproberv1.LabelKeyProbeName]
+		if name != target && !strings.HasSuffix(name, "-once-"+target) && !strings.HasSuffix(name, "-oncelocal-"+target) {
+			continue
+		}
+		if old, ok := latest[name]; !ok || old.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest[name] = pod
+		}
+	}
+	var result []probePod
+	for name, pod := range latest {
+		result = append(result, probePod{probe: name, pod: pod})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].probe < result[j].probe
+	})
+	return result
+}
+
+func tailLines(n int64) *int64 {
+	if n < 0 {
+		return nil
+	}
+	return &n
+}
+
+// streamLogs copies log lines to w with prefix, only lines containing checker are copied if checker is given
+func streamLogs(ctx context.Context, req *rest.Request, w io.Writer, mu *sync.Mutex, prefix string, checker string) error {
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return copyLogLines(stream, w, mu, prefix, checker)
+}
+
+func copyLogLines(r io.Reader, w io.Writer, mu *sync.Mutex, prefix string, checker string) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 && (checker == "" || strings.Contains(strings.ToLower(line), strings.ToLower(checker))) {
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n"
+			}
+			mu.Lock()
+			_, werr := io.WriteString(w, prefix+line)
+			mu.Unlock()
+			if werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestLatestProbePods(t *testing.T) {
+	now := time.Now()
+	newPod := func(name, probe string, age time.Duration) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{kubeproberv1.LabelKeyProbeName: probe},
+			CreationTimestamp: metav1.Time{Time: now.Add(-age)},
+		}}
+	}
+	pods := []corev1.Pod{
+		newPod("k8s-1", "k8s", time.Hour),
+		newPod("k8s-2", "k8s", time.Minute),
+		newPod("k8s-once-1", "k8s-once-1630000000", time.Minute),
+		newPod("node-once-1", "node-once-1630000000", time.Minute),
+		newPod("node-once-2", "node-once-1630000001", time.Minute),
+	}
+
+	result := latestProbePods(pods, "k8s")
+	assert.Len(t, result, 1)
+	assert.Equal(t, "k8s-2", result[0].pod.Name)
+
+	result = latestProbePods(pods, "1630000000")
+	assert.Len(t, result, 2)
+	assert.Equal(t, "k8s-once-1630000000", result[0].probe)
+	assert.Equal(t, "node-once-1", result[1].pod.Name)
+
+	assert.Empty(t, latestProbePods(pods, "dns"))
+}
+
+func TestCopyLogLines(t *testing.T) {
+	var buf bytes.Buffer
+	logs := "checker dns passed\nchecker etcd failed\nDNS resolves slowly"
+	assert.NoError(t, copyLogLines(strings.NewReader(logs), &buf, &sync.Mutex{}, "[k8s] ", "dns"))
+	assert.Equal(t, "[k8s] checker dns passed\n[k8s] DNS resolves slowly\n", buf.String())
+}
@@ -21,9 +21,11 @@ import (
 	"time"
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
-	"github.com/gosuri/uitable"
+	"github.com/erda-project/kubeprober/apistructs"
 	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
@@ -33,11 +35,45 @@ import (
 
 const KBNAMESPACE = "kubeprober"
 
+const (
+	onceStatePending   = "Pending"
+	onceStateRunning   = "Running"
+	onceStateSucceeded = "Succeeded"
+	onceStateFailed    = "Failed"
+
+	// exit code when the worst checker status reaches --fail-on
+	exitCodeCheckerFailed = 1
+	// exit code when one-time probes don't finish in --timeout, the same as timeout(1)
+	exitCodeTimeout = 124
+)
+
+var (
+	onceTimeout  time.Duration
+	onceNoWait   bool
+	onceWatch    bool
+	onceFailOn   string
+	onceJUnit    string
+	onceReport   string
+	oncePollTime = 5 * time.Second
+)
+
+// onceProbe is a probe running once, Job is the name of the one-time probe and its job
+type onceProbe struct {
+	Name  string
+	Job   string
+	State string
+}
+
 var OnceCmd = &cobra.Command{
 	Use:   "once",
 	Short: "Perform one-time diagnostics of remote cluster or local cluster",
-	Long:  "Perform one-time diagnostics of remote cluster or local cluster",
+	Long: "Perform one-time diagnostics of remote cluster or local cluster. " +
+		"It exits with code 1 when the worst checker status reaches --fail-on, and 124 when probes don't finish in --timeout",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if onceFailOn != "" && onceFailOn != string(kubeproberv1.CheckerStatusError) && onceFailOn != string(kubeproberv1.CheckerStatusWARN) {
+			return fmt.Errorf("invalid --fail-on %q, allowed values are: ERROR, WARN", onceFailOn)
+		}
+		cmd.SilenceUsage = true
 		if clusterName == "" {
 			return DoOnceProbeLocal(probes)
 		} else {
@@ -70,9 +106,11 @@ func DoOnceProbeLocal(probes string) error {
 		}
 	}
 
+	var once []onceProbe
 	for _, i := range onceProbeList {
 		onceProbeNameList = append(onceProbeNameList, i.Name)
 		name := fmt.Sprintf("%s-oncelocal-%s", i.Name, onceId)
+		once = append(once, onceProbe{Name: i.Name, Job: name})
 
 		i.Spec.Policy.RunInterval = 0
 		pp := &kubeproberv1.Probe{
@@ -92,29 +130,16 @@ func DoOnceProbeLocal(probes string) error {
 			return err
 		}
 	}
-	//wait for once probe finish
-	now := time.Now()
-	for i := 0; i < 30; i++ {
-		time.Sleep(10 * time.Second)
-		status, err = getOncePorbeStatus(k8sRestClient, KBNAMESPACE, onceId)
-		if err != nil {
-			return err
-		}
-		sub := time.Now().Sub(now)
-		fmt.Printf("\rTime: %ds,   Status: %s,   One-Time Probe: %s", int(sub/time.Second), status, onceProbeNameList)
-		if status == "Succeeded" {
-			break
-		}
+	if onceNoWait {
+		fmt.Printf("one-time probe %s created: %s\n", onceId, onceProbeNameList)
+		return nil
 	}
-	fmt.Println()
-	if status == "Succeeded" {
-		if err = PrintOnceProbeStatus(k8sRestClient, KBNAMESPACE, onceId); err != nil {
-			return err
-		}
-	} else {
-		return errors.New("get once probe status timeout!")
+
+	waitErr := waitOnceProbe(k8sRestClient, KBNAMESPACE, once)
+	if waitErr != nil && !isTimeout(waitErr) {
+		return waitErr
 	}
-	return nil
+	return finishOnceProbe(k8sRestClient, KBNAMESPACE, onceId, once, waitErr)
 }
 
 func DoOnceProbe(clusterName string, probes string) error {
@@ -122,7 +147,6 @@ func DoOnceProbe(clusterName string, probes string) error {
 	var c client.Client
 	var onceProbeNameList []string
 	var onceProbeList []kubeproberv1.Probe
-	var status string
 
 	onceId := fmt.Sprintf("%d", int32(time.Now().Unix()))
 	cluster := &kubeproberv1.Cluster{}
@@ -154,8 +178,10 @@ func DoOnceProbe(clusterName string, probes string) error {
 		onceProbeList = append(onceProbeList, *probe)
 	}
 
+	var once []onceProbe
 	for _, i := range onceProbeList {
 		name := fmt.Sprintf("%s-once-%s", i.Name, onceId)
+		once = append(once, onceProbe{Name: i.Name, Job: name})
 
 		i.Spec.Policy.RunInterval = 0
 		pp := &kubeproberv1.Probe{
@@ -180,64 +206,163 @@ func DoOnceProbe(clusterName string, probes string) error {
 	if err = updateClusterOnceProbeStatus(cluster, onceId, onceProbeNameList); err != nil {
 		return err
 	}
+	if onceNoWait {
+		fmt.Printf("one-time probe %s created, check it by: kubectl probe oncestatus -c %s -i %s\n", onceId, clusterName, onceId)
+		return nil
+	}
 
-	//wait for once probe finish
-	now := time.Now()
-	for i := 0; i < 30; i++ {
-		time.Sleep(10 * time.Second)
-		status, err = getOncePorbeStatus(c, namespace, onceId)
-		if err != nil {
-			return err
-		}
-		sub := time.Now().Sub(now)
-		fmt.Printf("\rTime: %ds,   Status: %s,   One-Time Probe: %s", int(sub/time.Second), status, onceProbeNameList)
-		if status == "Succeeded" {
-			break
-		}
+	waitErr := waitOnceProbe(c, namespace, once)
+	if waitErr != nil && !isTimeout(waitErr) {
+		return waitErr
 	}
 	if err = updateOnceProbeStatusFinishTime(cluster, onceId); err != nil {
 		return err
 	}
-	fmt.Println()
-	if status == "Succeeded" {
-		if err = PrintOnceProbeStatus(c, namespace, onceId); err != nil {
-			return err
+	return finishOnceProbe(c, namespace, onceId, once, waitErr)
+}
+
+func isTimeout(err error) bool {
+	return ExitCode(err) == exitCodeTimeout
+}
+
+// waitOnceProbe waits until jobs of all one-time probes complete or fail, states of probes are updated in place
+func waitOnceProbe(c client.Client, ns string, once []onceProbe) error {
+	ctx := context.Background()
+	if onceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, onceTimeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(oncePollTime)
+	defer ticker.Stop()
+
+	now := time.Now()
+	for {
+		finished := 0
+		for i := range once {
+			state, err := getOnceProbeState(ctx, c, ns, once[i].Job)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return err
+			}
+			if onceWatch && state != once[i].State {
+				printProgress("%4ds  %-30s %s\n", int(time.Since(now)/time.Second), once[i].Name, state)
+			}
+			once[i].State = state
+			if state == onceStateSucceeded || state == onceStateFailed {
+				finished++
+			}
+		}
+		if !onceWatch {
+			printProgress("\rTime: %ds,   Finished: %d/%d", int(time.Since(now)/time.Second), finished, len(once))
+		}
+		if finished == len(once) {
+			if !onceWatch {
+				printProgress("\n")
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if !onceWatch {
+				printProgress("\n")
+			}
+			return &ExitError{Code: exitCodeTimeout, Err: fmt.Errorf("one-time probes don't finish in %s", onceTimeout)}
+		case <-ticker.C:
 		}
-	} else {
-		return errors.New("get once probe status timeout!")
 	}
+}
 
-	return nil
+// getOnceProbeState returns the state of job created for the one-time probe
+func getOnceProbeState(ctx context.Context, c client.Client, ns string, name string) (string, error) {
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return onceStatePending, nil
+		}
+		return "", err
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return onceStateSucceeded, nil
+		case batchv1.JobFailed:
+			return onceStateFailed, nil
+		}
+	}
+	if job.Status.Active > 0 {
+		return onceStateRunning, nil
+	}
+	return onceStatePending, nil
 }
 
-func getOncePorbeStatus(c client.Client, ns string, onceID string) (string, error) {
-	var err error
-	var status string
+// finishOnceProbe prints checker results, writes junit and diagnostics reports and maps the worst status into exit code
+func finishOnceProbe(c client.Client, ns string, onceID string, once []onceProbe, waitErr error) error {
+	results, err := listOnceCheckerResults(c, ns, onceID)
+	if err != nil {
+		return err
+	}
+	p := newPrinter("PROBER", "CHECKER", "STATUS", "MESSAGE", "LASTRUN")
+	p.MaxColWidth = 45
+	for _, r := range results {
+		addCheckerRow(p, r)
+	}
+	if err = p.Print(); err != nil {
+		return err
+	}
 
-	status = "Succeeded"
-	podList := &corev1.PodList{}
-	if err = c.List(context.Background(), podList, client.InNamespace(ns)); err != nil {
-		return "", err
+	if onceJUnit != "" {
+		failOn := kubeproberv1.CheckerStatus(onceFailOn)
+		if failOn == "" {
+			failOn = kubeproberv1.CheckerStatusError
+		}
+		if err = writeJUnitReport(onceJUnit, clusterName, onceID, once, results, failOn); err != nil {
+			return err
+		}
 	}
-	//just print once probe status
-	for _, i := range podList.Items {
-		if strings.Contains(i.Name, onceID) {
-			if i.Status.Phase != "Succeeded" {
-				status = string(i.Status.Phase)
-			}
+	if onceReport != "" {
+		if err = writeOnceReport(onceReport, onceID, results); err != nil {
+			return err
+		}
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	if onceFailOn == "" {
+		return nil
+	}
+
+	failOn := kubeproberv1.CheckerStatus(onceFailOn)
+	var failed []string
+	for _, o := range once {
+		if o.State == onceStateFailed {
+			failed = append(failed, fmt.Sprintf("job of probe %s failed", o.Name))
+		}
+	}
+	for _, r := range results {
+		if r.Status.Priority() >= failOn.Priority() {
+			failed = append(failed, fmt.Sprintf("%s/%s is %s", r.Probe, r.Checker, r.Status))
 		}
 	}
-	return status, nil
+	if len(failed) > 0 {
+		return &ExitError{Code: exitCodeCheckerFailed, Err: fmt.Errorf("%d checkers reach %s: %s", len(failed), failOn, strings.Join(failed, ", "))}
+	}
+	return nil
 }
 
 func updateClusterOnceProbeStatus(cluster *kubeproberv1.Cluster, onceID string, onceProbeNameList []string) error {
 	var err error
 	//update once probe status of cluster
 	cluster.Status.OnceProbeList = append(cluster.Status.OnceProbeList, kubeproberv1.OnceProbeItem{
-		ID:         onceID,
-		CreateTime: time.Now().Format("2006-01-02 15:04:05"),
-		FinishTime: time.Now().Format("2006-01-02 15:04:05"),
-		Probes:     onceProbeNameList,
+		ID:        onceID,
+		StartTime: &metav1.Time{Time: time.Now()},
+		Probes:    onceProbeNameList,
 	})
 	if len(cluster.Status.OnceProbeList) > 5 {
 		cluster.Status.OnceProbeList = cluster.Status.OnceProbeList[len(cluster.Status.OnceProbeList)-5:]
@@ -269,7 +394,7 @@ func updateOnceProbeStatusFinishTime(cluster *kubeproberv1.Cluster, onceID strin
 	//update once probe status of cluster
 	for i := range cluster.Status.OnceProbeList {
 		if cluster.Status.OnceProbeList[i].ID == onceID {
-			cluster.Status.OnceProbeList[i].FinishTime = time.Now().Format("2006-01-02 15:04:05")
+			cluster.Status.OnceProbeList[i].CompletionTime = &metav1.Time{Time: time.Now()}
 		}
 	}
 	var patch []byte
@@ -295,24 +420,31 @@ func updateOnceProbeStatusFinishTime(cluster *kubeproberv1.Cluster, onceID strin
 }
 
 func PrintOnceProbeStatus(c client.Client, ns string, onceID string) error {
-	var err error
+	results, err := listOnceCheckerResults(c, ns, onceID)
+	if err != nil {
+		return err
+	}
+	p := newPrinter("PROBER", "CHECKER", "STATUS", "MESSAGE", "LASTRUN")
+	p.MaxColWidth = 45
+	for _, r := range results {
+		addCheckerRow(p, r)
+	}
+	return p.Print()
+}
 
+// listOnceCheckerResults returns checker status of one-time probes, which are named with suffix of the id
+func listOnceCheckerResults(c client.Client, ns string, onceID string) ([]apistructs.CheckerResult, error) {
 	probeStatusList := &kubeproberv1.ProbeStatusList{}
-	if err = c.List(context.Background(), probeStatusList, client.InNamespace(ns)); err != nil {
-		return err
+	if err := c.List(context.Background(), probeStatusList, client.InNamespace(ns)); err != nil {
+		return nil, err
 	}
-	//just print once probe status
-	table := uitable.New()
-	table.MaxColWidth = 45
-	table.Wrap = true
-	table.AddRow("PROBER", "CHECKER", "STATUS", "MESSAGE", "LASTRUN")
+	var results []apistructs.CheckerResult
 	for _, i := range probeStatusList.Items {
-		if strings.Contains(i.Name, onceID) {
+		if strings.HasSuffix(i.Name, "-"+onceID) {
 			for _, j := range i.Spec.Checkers {
-				table.AddRow(i.Name, j.Name, j.Status, strings.TrimSpace(j.Message), j.LastRun.Format("2006-01-02 15:04:05"))
+				results = append(results, checkerResult(clusterName, i.Name, j))
 			}
 		}
 	}
-	fmt.Println(table)
-	return nil
+	return results, nil
 }
@@ -17,7 +17,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -52,15 +51,12 @@ func GetHistoryOnceProbeStatus(clusterName string) error {
 	}
 
 	//just print once probe status
-	table := uitable.New()
-	table.MaxColWidth = 45
-	table.Wrap = true
-	table.AddRow("ID", "PROBES", "CREATETIMN", "FINISHTIME")
+	p := newPrinter("ID", "PROBES", "STARTTIME", "COMPLETIONTIME")
+	p.MaxColWidth = 45
 	for _, i := range cluster.Status.OnceProbeList {
-		table.AddRow(i.ID, i.Probes, i.CreateTime, i.FinishTime)
+		p.AddRow(i.ID, i, i.ID, i.Probes, formatMetaTime(i.StartTime), formatMetaTime(i.CompletionTime))
 	}
-	fmt.Println(table)
-	return nil
+	return p.Print()
 }
 
 func GetOnceProbeStatus(clusterName string, id string) error {
@@ -18,11 +18,8 @@ import (
 	"fmt"
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
-	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 	appv1 "k8s.io/api/apps/v1"
-	apiv1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -35,9 +32,13 @@ var OpsCmd = &cobra.Command{
 		if listAgent {
 			return ListAgent()
 		}
+		if listRollouts {
+			return ListAgentRollouts()
+		}
 
 		if agentImage != "" || agentCpuLimit != "" || agentMemoryLimit != "" {
-			return UpdateAgentSetting(agentImage, agentCpuLimit, agentMemoryLimit)
+			cmd.SilenceUsage = true
+			return RolloutAgent(AgentSetting{Image: agentImage, CPU: agentCpuLimit, Memory: agentMemoryLimit})
 		}
 		return func() error {
 			fmt.Printf("I am ops tool cli of kubeprober!\n")
@@ -46,6 +47,18 @@ var OpsCmd = &cobra.Command{
 	},
 }
 
+// AgentInfo is the deployment setting of probe-agent in a cluster
+type AgentInfo struct {
+	Cluster       string `json:"cluster"`
+	Namespace     string `json:"namespace"`
+	Image         string `json:"image,omitempty"`
+	CPU           string `json:"cpu,omitempty"`
+	Memory        string `json:"memory,omitempty"`
+	Replicas      int32  `json:"replicas"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+	Error         string `json:"error,omitempty"`
+}
+
 func ListAgent() error {
 	var err error
 	clusterList := &kubeproberv1.ClusterList{}
@@ -54,91 +67,51 @@ func ListAgent() error {
 		return err
 	}
 
-	table := uitable.New()
-	table.MaxColWidth = 70
-	table.Wrap = true
-	table.AddRow("CLUSTER", "IMAGE", "CPUSET", "MEMORYSET")
+	p := newPrinter("CLUSTER", "IMAGE", "CPUSET", "MEMORYSET").Wide("NAMESPACE", "READY")
+	p.MaxColWidth = 70
 	for _, v := range clusterList.Items {
-		GetAgentInfo(&v, table)
+		info := GetAgentInfo(&v)
+		if info.Error != "" {
+			p.AddRow(info.Cluster, info, info.Cluster, info.Error)
+			continue
+		}
+		p.AddRow(info.Cluster, info, info.Cluster, info.Image, info.CPU, info.Memory, info.Namespace,
+			fmt.Sprintf("%d/%d", info.ReadyReplicas, info.Replicas))
 	}
-	fmt.Println(table)
-	return nil
+	return p.Print()
 }
 
-func GetAgentInfo(cluster *kubeproberv1.Cluster, table *uitable.Table) {
+func GetAgentInfo(cluster *kubeproberv1.Cluster) AgentInfo {
 	var err error
 	var c client.Client
-	var result string
 
-	agentDeploy := &appv1.Deployment{}
-	if c, err = GenerateProbeClient(cluster); err != nil {
-		result = fmt.Sprintf("%+s\n", err)
-		table.AddRow(cluster.Name, result)
-		return
-	}
-	if err = c.Get(context.Background(), client.ObjectKey{
+	info := AgentInfo{
+		Cluster:   cluster.Name,
 		Namespace: cluster.Spec.ClusterConfig.ProbeNamespaces,
-		Name:      "probe-agent",
-	}, agentDeploy); err != nil {
-		result = fmt.Sprintf("%+s\n", err)
-		table.AddRow(cluster.Name, result)
-		return
-	}
-	table.AddRow(cluster.Name, agentDeploy.Spec.Template.Spec.Containers[0].Image, agentDeploy.Spec.Template.Spec.Containers[0].Resources.Limits.Cpu(), agentDeploy.Spec.Template.Spec.Containers[0].Resources.Limits.Memory())
-}
-
-func UpdateAgentSetting(imageName string, agentCpuLimit string, agentMemoryLimit string) error {
-	var err error
-	clusterList := &kubeproberv1.ClusterList{}
-	if err = k8sRestClient.List(context.Background(), clusterList); err != nil {
-		fmt.Printf("Get cluster list error: %+v\n", err)
-		return err
 	}
-
-	table := uitable.New()
-	table.MaxColWidth = 70
-	table.Wrap = true
-	table.AddRow("CLUSTER", "RESULT")
-	for _, v := range clusterList.Items {
-		SetAgentSeting(&v, imageName, agentCpuLimit, agentMemoryLimit, table)
-	}
-	fmt.Println(table)
-	return nil
-}
-
-func SetAgentSeting(cluster *kubeproberv1.Cluster, imageName string, agentCpuLimit string, agentMemoryLimit string, table *uitable.Table) {
-	var err error
-	var c client.Client
-	var result string
-
 	agentDeploy := &appv1.Deployment{}
 	if c, err = GenerateProbeClient(cluster); err != nil {
-		result = fmt.Sprintf("%+s\n", err)
-		table.AddRow(cluster.Name, result)
-		return
+		info.Error = err.Error()
+		return info
 	}
-
 	if err = c.Get(context.Background(), client.ObjectKey{
 		Namespace: cluster.Spec.ClusterConfig.ProbeNamespaces,
-		Name:      "probe-agent",
+		Name:      kubeproberv1.AgentDeployment,
 	}, agentDeploy); err != nil {
-		result = fmt.Sprintf("%+s\n", err)
-		table.AddRow(cluster.Name, result)
-		return
-	}
-	if imageName != "" {
-		agentDeploy.Spec.Template.Spec.Containers[0].Image = imageName
-	}
-	if agentMemoryLimit != "" {
-		agentDeploy.Spec.Template.Spec.Containers[0].Resources.Limits[apiv1.ResourceMemory] = resource.MustParse(fmt.Sprintf("%v", agentMemoryLimit))
+		info.Error = err.Error()
+		return info
 	}
-	if agentCpuLimit != "" {
-		agentDeploy.Spec.Template.Spec.Containers[0].Resources.Limits[apiv1.ResourceCPU] = resource.MustParse(fmt.Sprintf("%v", agentCpuLimit))
+	container, err := kubeproberv1.FindAgentContainer(agentDeploy, rolloutContainer)
+	if err != nil {
+		info.Error = err.Error()
+		return info
 	}
-	if c.Update(context.Background(), agentDeploy); err != nil {
-		result = fmt.Sprintf("%+s\n", err)
-		table.AddRow(cluster.Name, result)
-		return
+	info.Image = container.Image
+	info.CPU = container.Resources.Limits.Cpu().String()
+	info.Memory = container.Resources.Limits.Memory().String()
+	if agentDeploy.Spec.Replicas != nil {
+		info.Replicas = *agentDeploy.Spec.Replicas
 	}
-	table.AddRow(cluster.Name, "SUCCESS")
+	info.ReadyReplicas = agentDeploy.Status.ReadyReplicas
+	return info
 }
@@ -0,0 +1,297 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputJSON          = "json"
+	outputYAML          = "yaml"
+	outputWide          = "wide"
+	outputName          = "name"
+	outputCustomColumns = "custom-columns="
+	outputJSONPath      = "jsonpath="
+)
+
+var (
+	// output format of read commands, table default
+	outputFormat string
+	noHeaders    bool
+)
+
+// validateOutput checks --output before any command runs
+func validateOutput(output string) error {
+	switch {
+	case output == "", output == outputJSON, output == outputYAML, output == outputWide, output == outputName:
+		return nil
+	case strings.HasPrefix(output, outputCustomColumns):
+		_, err := parseCustomColumns(strings.TrimPrefix(output, outputCustomColumns))
+		return err
+	case strings.HasPrefix(output, outputJSONPath):
+		_, err := parseJSONPath("output", strings.TrimPrefix(output, outputJSONPath))
+		return err
+	}
+	return errors.Errorf("unsupported output format %q, allowed formats are: json, yaml, wide, name, custom-columns=, jsonpath=", output)
+}
+
+// machineOutput reports whether the output is parsed by scripts, progress messages should go to stderr then
+func machineOutput() bool {
+	return outputFormat != "" && outputFormat != outputWide
+}
+
+// printProgress prints progress of long running commands, it goes to stderr to keep machine output parseable
+func printProgress(format string, a ...interface{}) {
+	if machineOutput() {
+		fmt.Fprintf(os.Stderr, format, a...)
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+type printerRow struct {
+	name  string
+	obj   interface{}
+	cells []interface{}
+}
+
+// printer collects objects of a read command together with their table cells,
+// and prints them in the format of --output
+type printer struct {
+	// max width of table columns, wide output never truncates columns
+	MaxColWidth uint
+	header      []string
+	wideHeader  []string
+	rows        []printerRow
+}
+
+func newPrinter(header ...string) *printer {
+	return &printer{header: header}
+}
+
+// Wide adds columns which are only printed with -o wide
+func (p *printer) Wide(header ...string) *printer {
+	p.wideHeader = header
+	return p
+}
+
+// AddRow adds obj with its name printed by -o name, cells are values of header followed by values of wide header
+func (p *printer) AddRow(name string, obj interface{}, cells ...interface{}) {
+	p.rows = append(p.rows, printerRow{name: name, obj: obj, cells: cells})
+}
+
+func (p *printer) Print() error {
+	return p.PrintTo(os.Stdout, outputFormat)
+}
+
+func (p *printer) PrintTo(w io.Writer, output string) error {
+	switch {
+	case output == "" || output == outputWide:
+		return p.printTable(w, output == outputWide)
+	case output == outputName:
+		for _, r := range p.rows {
+			fmt.Fprintln(w, r.name)
+		}
+		return nil
+	case output == outputJSON:
+		data, err := json.MarshalIndent(p.list(), "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case output == outputYAML:
+		data, err := yaml.Marshal(p.list())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case strings.HasPrefix(output, outputJSONPath):
+		j, err := parseJSONPath("output", strings.TrimPrefix(output, outputJSONPath))
+		if err != nil {
+			return err
+		}
+		data, err := toGeneric(p.list())
+		if err != nil {
+			return err
+		}
+		return j.Execute(w, data)
+	case strings.HasPrefix(output, outputCustomColumns):
+		return p.printCustomColumns(w, strings.TrimPrefix(output, outputCustomColumns))
+	}
+	return validateOutput(output)
+}
+
+// list wraps objects like kubectl does, so jsonpath like {.items[*].name} works
+func (p *printer) list() map[string]interface{} {
+	items := make([]interface{}, 0, len(p.rows))
+	for _, r := range p.rows {
+		items = append(items, r.obj)
+	}
+	return map[string]interface{}{
+		"kind":  "List",
+		"items": items,
+	}
+}
+
+func (p *printer) printTable(w io.Writer, wide bool) error {
+	table := uitable.New()
+	if !wide && p.MaxColWidth > 0 {
+		table.MaxColWidth = p.MaxColWidth
+		table.Wrap = true
+	}
+	columns := len(p.header)
+	if wide {
+		columns += len(p.wideHeader)
+	}
+	if !noHeaders {
+		var header []interface{}
+		for _, h := range p.header {
+			header = append(header, h)
+		}
+		if wide {
+			for _, h := range p.wideHeader {
+				header = append(header, h)
+			}
+		}
+		table.AddRow(header...)
+	}
+	for _, r := range p.rows {
+		cells := r.cells
+		if len(cells) > columns {
+			cells = cells[:columns]
+		}
+		table.AddRow(cells...)
+	}
+	if len(table.Rows) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintln(w, table)
+	return err
+}
+
+type customColumn struct {
+	header string
+	path   *jsonpath.JSONPath
+}
+
+// parseCustomColumns parses spec like NAME:.cluster,STATUS:.status
+func parseCustomColumns(spec string) ([]customColumn, error) {
+	if spec == "" {
+		return nil, errors.New("custom-columns format specified but no custom columns given")
+	}
+	var columns []customColumn
+	for _, c := range strings.Split(spec, ",") {
+		parts := strings.SplitN(c, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("unexpected custom-columns spec: %s, expected <header>:<json-path-expr>", c)
+		}
+		j, err := parseJSONPath(parts[0], relaxedJSONPath(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, customColumn{header: parts[0], path: j})
+	}
+	return columns, nil
+}
+
+func (p *printer) printCustomColumns(w io.Writer, spec string) error {
+	columns, err := parseCustomColumns(spec)
+	if err != nil {
+		return err
+	}
+	table := uitable.New()
+	if !noHeaders {
+		var header []interface{}
+		for _, c := range columns {
+			header = append(header, c.header)
+		}
+		table.AddRow(header...)
+	}
+	for _, r := range p.rows {
+		obj, err := toGeneric(r.obj)
+		if err != nil {
+			return err
+		}
+		var cells []interface{}
+		for _, c := range columns {
+			results, err := c.path.FindResults(obj)
+			if err != nil {
+				return err
+			}
+			var values []string
+			for _, result := range results {
+				for _, v := range result {
+					var buf bytes.Buffer
+					if err = c.path.PrintResults(&buf, []reflect.Value{v}); err != nil {
+						return err
+					}
+					values = append(values, buf.String())
+				}
+			}
+			if len(values) == 0 {
+				values = append(values, "<none>")
+			}
+			cells = append(cells, strings.Join(values, ","))
+		}
+		table.AddRow(cells...)
+	}
+	if len(table.Rows) == 0 {
+		return nil
+	}
+	_, err = fmt.Fprintln(w, table)
+	return err
+}
+
+func parseJSONPath(name, template string) (*jsonpath.JSONPath, error) {
+	j := jsonpath.New(name).AllowMissingKeys(true)
+	if err := j.Parse(template); err != nil {
+		return nil, errors.Wrapf(err, "parse jsonpath %s", template)
+	}
+	return j, nil
+}
+
+// relaxedJSONPath allows custom columns like .status or status besides {.status}
+func relaxedJSONPath(path string) string {
+	if strings.HasPrefix(path, "{") {
+		return path
+	}
+	return "{." + strings.TrimPrefix(path, ".") + "}"
+}
+
+// toGeneric converts obj into maps and slices, so jsonpath matches json field names
+func toGeneric(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err = json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type printerTestObj struct {
+	Name   string   `json:"name"`
+	Status string   `json:"status"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+func newTestPrinter() *printer {
+	p := newPrinter("NAME", "STATUS").Wide("LABELS")
+	p.AddRow("a", printerTestObj{Name: "a", Status: "PASS", Labels: []string{"x", "y"}}, "a", "PASS", "x,y")
+	p.AddRow("b", printerTestObj{Name: "b", Status: "ERROR"}, "b", "ERROR", "-")
+	return p
+}
+
+func TestPrinter(t *testing.T) {
+	cases := []struct {
+		output    string
+		noHeaders bool
+		expected  string
+	}{
+		{output: "", expected: "NAME\tSTATUS\na   \tPASS  \nb   \tERROR \n"},
+		{output: "", noHeaders: true, expected: "a\tPASS \nb\tERROR\n"},
+		{output: "wide", expected: "NAME\tSTATUS\tLABELS\na   \tPASS  \tx,y   \nb   \tERROR \t-     \n"},
+		{output: "name", expected: "a\nb\n"},
+		{output: "jsonpath={.items[*].name}", expected: "a b"},
+		{output: "custom-columns=N:.name,L:labels[*]", expected: "N\tL     \na\tx,y   \nb\t<none>\n"},
+		{output: "yaml", expected: "items:\n- labels:\n  - x\n  - \"y\"\n  name: a\n  status: PASS\n- name: b\n  status: ERROR\nkind: List\n"},
+	}
+	defer func() { noHeaders = false }()
+	for _, c := range cases {
+		noHeaders = c.noHeaders
+		var buf bytes.Buffer
+		assert.NoError(t, newTestPrinter().PrintTo(&buf, c.output), c.output)
+		assert.Equal(t, c.expected, buf.String(), c.output)
+	}
+}
+
+func TestPrinterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, newTestPrinter().PrintTo(&buf, "json"))
+	assert.Contains(t, buf.String(), `"kind": "List"`)
+	assert.Contains(t, buf.String(), `"status": "ERROR"`)
+}
+
+func TestValidateOutput(t *testing.T) {
+	for _, output := range []string{"", "json", "yaml", "wide", "name", "custom-columns=NAME:.name", "jsonpath={.items}"} {
+		assert.NoError(t, validateOutput(output), output)
+	}
+	for _, output := range []string{"table", "custom-columns=", "custom-columns=NAME", "jsonpath={.items"} {
+		assert.Error(t, validateOutput(output), output)
+	}
+}
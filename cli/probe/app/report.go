@@ -0,0 +1,200 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/report"
+)
+
+var (
+	reportSelector       string
+	reportFormat         string
+	reportFile           string
+	reportTitle          string
+	reportSince          time.Duration
+	reportConcurrency    int
+	reportClusterTimeout time.Duration
+)
+
+var ReportCmd = &cobra.Command{
+	Use:   "report [CLUSTER...]",
+	Short: "Render a diagnostics report of clusters in Markdown or HTML",
+	Long: "Render a self-contained diagnostics report of clusters with cluster info, checker results, trends from history " +
+		"and a summary score, all clusters or clusters matching --selector default, " +
+		"e.g. kubectl probe report prod-a prod-b --file report.html",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 && reportSelector != "" {
+			return errors.New("clusters and --selector can't be used together")
+		}
+		format, err := reportFileFormat(reportFormat, reportFile)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return GenerateReport(args, format)
+	},
+}
+
+// reportFileFormat infers the format from extension of file when it is not given
+func reportFileFormat(format, file string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".html", ".htm":
+			format = report.FormatHTML
+		default:
+			format = report.FormatMarkdown
+		}
+	}
+	return format, report.ValidateFormat(format)
+}
+
+func GenerateReport(names []string, format string) error {
+	clusters, err := reportClusters(names)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return errors.New("no cluster to report")
+	}
+
+	end := time.Now()
+	start := end.Add(-reportSince)
+	var timeline []apistructs.StatusChange
+	if err = queryMasterHistory("/api/history/timeline", map[string]string{
+		"cluster": singleClusterName(clusters),
+		"start":   start.Format(time.RFC3339),
+		"end":     end.Format(time.RFC3339),
+	}, &timeline); err != nil {
+		printProgress("trends are not reported: %v\n", err)
+	}
+
+	fleet := collectFleetResults(context.Background(), clusters, reportConcurrency, reportClusterTimeout)
+	clusterReports := make([]report.ClusterReport, 0, len(clusters))
+	for i, f := range fleet {
+		if f.err != nil {
+			cr := report.NewClusterReport(&clusters[i], nil, nil)
+			cr.Error = f.err.Error()
+			clusterReports = append(clusterReports, cr)
+			continue
+		}
+		results := f.results
+		if probes != "" {
+			results = filterProbeResults(results, probes)
+		}
+		clusterReports = append(clusterReports, report.NewClusterReport(&clusters[i], results, timeline))
+	}
+
+	title := reportTitle
+	if title == "" {
+		title = fmt.Sprintf("KubeProber Diagnostics Report %s", end.Format("2006-01-02"))
+	}
+	r := report.New(title, start, end, clusterReports)
+	r.Location = displayLocation()
+
+	var w io.Writer = os.Stdout
+	if reportFile != "" {
+		f, err := os.Create(reportFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err = report.Render(w, r, format); err != nil {
+		return err
+	}
+	if reportFile != "" {
+		printProgress("report of %d clusters is written to %s, score %d\n", len(clusters), reportFile, r.Summary().Score)
+	}
+	return nil
+}
+
+// writeOnceReport renders checker results of a one-time probe into path, format is inferred from its extension
+func writeOnceReport(path string, onceID string, results []apistructs.CheckerResult) error {
+	format, err := reportFileFormat("", path)
+	if err != nil {
+		return err
+	}
+	cluster := &kubeproberv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}
+	if clusterName == "" {
+		cluster.Name = "local"
+	} else if err = k8sRestClient.Get(context.Background(), client.ObjectKey{
+		Namespace: metav1.NamespaceDefault,
+		Name:      clusterName,
+	}, cluster); err != nil {
+		printProgress("cluster info is not reported: %v\n", err)
+	}
+
+	r := report.New(fmt.Sprintf("KubeProber One-time Probe %s", onceID), time.Time{}, time.Time{},
+		[]report.ClusterReport{report.NewClusterReport(cluster, results, nil)})
+	r.Location = displayLocation()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return report.Render(f, r, format)
+}
+
+// reportClusters gets clusters by names, or lists clusters by selector
+func reportClusters(names []string) ([]kubeproberv1.Cluster, error) {
+	if len(names) == 0 {
+		return listClusters(reportSelector)
+	}
+	clusters := make([]kubeproberv1.Cluster, 0, len(names))
+	for _, name := range names {
+		c := kubeproberv1.Cluster{}
+		if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+			Namespace: metav1.NamespaceDefault,
+			Name:      name,
+		}, &c); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, nil
+}
+
+// singleClusterName narrows the history query when only one cluster is reported
+func singleClusterName(clusters []kubeproberv1.Cluster) string {
+	if len(clusters) == 1 {
+		return clusters[0].Name
+	}
+	return ""
+}
+
+func filterProbeResults(results []apistructs.CheckerResult, probe string) []apistructs.CheckerResult {
+	var filtered []apistructs.CheckerResult
+	for _, r := range results {
+		if r.Probe == probe {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
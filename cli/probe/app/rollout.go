@@ -0,0 +1,504 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	appv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+const (
+	// AgentRolloutConfigMap keeps the records of agent rollouts in the master cluster
+	AgentRolloutConfigMap = "kubeprober-agent-rollouts"
+	maxAgentRollouts      = 20
+
+	rolloutRunning    = "Running"
+	rolloutSucceeded  = "Succeeded"
+	rolloutFailed     = "Failed"
+	rolloutPending    = "Pending"
+	rolloutPlanned    = "Planned"
+	rolloutHealthy    = "Healthy"
+	rolloutRolledBack = "RolledBack"
+	rolloutSkipped    = "Skipped"
+
+	rolloutPollTime = 5 * time.Second
+)
+
+var (
+	rolloutSelector      string
+	rolloutContainer     string
+	rolloutBatchSize     int
+	rolloutPause         time.Duration
+	rolloutHealthTimeout time.Duration
+	rolloutDryRun        bool
+	rolloutRollback      bool
+	listRollouts         bool
+)
+
+// AgentSetting is the image and resource limits of probe-agent container, empty field means unset
+type AgentSetting struct {
+	Image  string `json:"image,omitempty"`
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+func (s AgentSetting) String() string {
+	var parts []string
+	if s.Image != "" {
+		parts = append(parts, "image="+s.Image)
+	}
+	if s.CPU != "" {
+		parts = append(parts, "cpu="+s.CPU)
+	}
+	if s.Memory != "" {
+		parts = append(parts, "memory="+s.Memory)
+	}
+	return strings.Join(parts, ",")
+}
+
+// RolloutCluster is the rollout state of one cluster
+type RolloutCluster struct {
+	Cluster  string       `json:"cluster"`
+	Batch    int          `json:"batch"`
+	Previous AgentSetting `json:"previous"`
+	State    string       `json:"state"`
+	Error    string       `json:"error,omitempty"`
+	// updated is true once the deployment of the cluster is changed and should be rolled back on failure
+	updated bool
+}
+
+// AgentRollout is the persisted record of a staged rollout of probe-agent
+type AgentRollout struct {
+	ID         string           `json:"id"`
+	CreatedBy  string           `json:"createdBy,omitempty"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	Selector   string           `json:"selector,omitempty"`
+	BatchSize  int              `json:"batchSize"`
+	Target     AgentSetting     `json:"target"`
+	State      string           `json:"state"`
+	Clusters   []RolloutCluster `json:"clusters"`
+}
+
+// validateAgentSetting checks the target setting before touching any cluster
+func validateAgentSetting(s AgentSetting) error {
+	if strings.ContainsAny(s.Image, " \t\n") {
+		return errors.Errorf("invalid agent image %q", s.Image)
+	}
+	if s.CPU != "" {
+		if _, err := resource.ParseQuantity(s.CPU); err != nil {
+			return errors.Errorf("invalid agent cpu limit %q: %v", s.CPU, err)
+		}
+	}
+	if s.Memory != "" {
+		if _, err := resource.ParseQuantity(s.Memory); err != nil {
+			return errors.Errorf("invalid agent memory limit %q: %v", s.Memory, err)
+		}
+	}
+	return nil
+}
+
+// planRollout sorts clusters by name and splits them into batches
+func planRollout(clusters []kubeproberv1.Cluster, batchSize int) ([]RolloutCluster, error) {
+	if batchSize < 1 {
+		return nil, errors.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	names := make([]string, 0, len(clusters))
+	for _, c := range clusters {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	plan := make([]RolloutCluster, 0, len(names))
+	for i, name := range names {
+		plan = append(plan, RolloutCluster{Cluster: name, Batch: i/batchSize + 1, State: rolloutPending})
+	}
+	return plan, nil
+}
+
+func currentAgentSetting(container *apiv1.Container) AgentSetting {
+	s := AgentSetting{Image: container.Image}
+	if q, ok := container.Resources.Limits[apiv1.ResourceCPU]; ok {
+		s.CPU = q.String()
+	}
+	if q, ok := container.Resources.Limits[apiv1.ResourceMemory]; ok {
+		s.Memory = q.String()
+	}
+	return s
+}
+
+// applyAgentSetting sets the non-empty fields of s, with restore the empty limits are removed
+func applyAgentSetting(container *apiv1.Container, s AgentSetting, restore bool) error {
+	if s.Image != "" {
+		container.Image = s.Image
+	}
+	for name, value := range map[apiv1.ResourceName]string{
+		apiv1.ResourceCPU:    s.CPU,
+		apiv1.ResourceMemory: s.Memory,
+	} {
+		if value == "" {
+			if restore {
+				delete(container.Resources.Limits, name)
+			}
+			continue
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return err
+		}
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = apiv1.ResourceList{}
+		}
+		container.Resources.Limits[name] = q
+	}
+	return nil
+}
+
+func agentDeployKey(cluster *kubeproberv1.Cluster) client.ObjectKey {
+	return client.ObjectKey{Namespace: cluster.Spec.ClusterConfig.ProbeNamespaces, Name: kubeproberv1.AgentDeployment}
+}
+
+// updateAgent changes the agent setting of a cluster and returns its previous setting
+func updateAgent(c client.Client, cluster *kubeproberv1.Cluster, s AgentSetting, restore bool) (AgentSetting, error) {
+	var previous AgentSetting
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deploy := &appv1.Deployment{}
+		if err := c.Get(context.Background(), agentDeployKey(cluster), deploy); err != nil {
+			return err
+		}
+		container, err := kubeproberv1.FindAgentContainer(deploy, rolloutContainer)
+		if err != nil {
+			return err
+		}
+		previous = currentAgentSetting(container)
+		if err = applyAgentSetting(container, s, restore); err != nil {
+			return err
+		}
+		return c.Update(context.Background(), deploy)
+	})
+	return previous, err
+}
+
+// agentDeployReady is true when all replicas of the latest generation are updated and ready
+func agentDeployReady(deploy *appv1.Deployment) bool {
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	s := deploy.Status
+	return s.ObservedGeneration >= deploy.Generation &&
+		s.UpdatedReplicas == replicas && s.ReadyReplicas == replicas &&
+		s.AvailableReplicas == replicas && s.Replicas == replicas
+}
+
+// waitAgentHealthy waits for the agent deployment to be ready and a heartbeat sent after that
+func waitAgentHealthy(c client.Client, cluster *kubeproberv1.Cluster, timeout time.Duration) error {
+	var readyAt time.Time
+	var reason string
+	err := wait.PollImmediate(rolloutPollTime, timeout, func() (bool, error) {
+		if readyAt.IsZero() {
+			deploy := &appv1.Deployment{}
+			if err := c.Get(context.Background(), agentDeployKey(cluster), deploy); err != nil {
+				reason = err.Error()
+				return false, nil
+			}
+			if !agentDeployReady(deploy) {
+				reason = fmt.Sprintf("agent not ready, %d/%d updated, %d ready", deploy.Status.UpdatedReplicas,
+					deploy.Status.Replicas, deploy.Status.ReadyReplicas)
+				return false, nil
+			}
+			// heartbeat time is in seconds
+			readyAt = time.Now().Truncate(time.Second)
+		}
+		latest := &kubeproberv1.Cluster{}
+		if err := k8sRestClient.Get(context.Background(), client.ObjectKeyFromObject(cluster), latest); err != nil {
+			reason = err.Error()
+			return false, nil
+		}
+		if heartbeatTime(latest).Before(readyAt) {
+			reason = "no heartbeat since agent ready"
+			return false, nil
+		}
+		return true, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return errors.Errorf("not healthy after %s: %s", timeout, reason)
+	}
+	return err
+}
+
+// RolloutAgent updates agent setting of clusters batch by batch, a batch is rolled back and
+// the rollout stops when any cluster of it is not healthy
+func RolloutAgent(target AgentSetting) error {
+	if err := validateAgentSetting(target); err != nil {
+		return err
+	}
+	clusters, err := listClusters(rolloutSelector)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return errors.Errorf("no cluster matches selector %q", rolloutSelector)
+	}
+	plan, err := planRollout(clusters, rolloutBatchSize)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*kubeproberv1.Cluster, len(clusters))
+	for i := range clusters {
+		byName[clusters[i].Name] = &clusters[i]
+	}
+
+	if rolloutDryRun {
+		for i := range plan {
+			plan[i].State = rolloutPlanned
+			info := GetAgentInfo(byName[plan[i].Cluster])
+			plan[i].Previous = AgentSetting{Image: info.Image, CPU: info.CPU, Memory: info.Memory}
+			plan[i].Error = info.Error
+		}
+		return printRolloutClusters(plan, target)
+	}
+
+	rollout := &AgentRollout{
+		ID:        time.Now().Format("20060102-150405"),
+		CreatedBy: os.Getenv("USER"),
+		StartedAt: time.Now(),
+		Selector:  rolloutSelector,
+		BatchSize: rolloutBatchSize,
+		Target:    target,
+		State:     rolloutRunning,
+		Clusters:  plan,
+	}
+	if err = saveAgentRollout(rollout); err != nil {
+		return errors.Errorf("save rollout record error: %v", err)
+	}
+	printProgress("agent rollout %s started, %d clusters in %d batches\n", rollout.ID, len(plan), plan[len(plan)-1].Batch)
+
+	failed := false
+	for start := 0; start < len(plan); {
+		end := start
+		for end < len(plan) && plan[end].Batch == plan[start].Batch {
+			end++
+		}
+		batch := plan[start:end]
+		if start > 0 {
+			printProgress("pause %s before batch %d\n", rolloutPause, batch[0].Batch)
+			time.Sleep(rolloutPause)
+		}
+		printProgress("rolling out batch %d: %s\n", batch[0].Batch, rolloutClusterNames(batch))
+		if !rolloutBatch(batch, byName, target) {
+			failed = true
+			if rolloutRollback {
+				rollbackBatch(batch, byName)
+			}
+			for i := end; i < len(plan); i++ {
+				plan[i].State = rolloutSkipped
+			}
+		}
+		if failed {
+			rollout.State = rolloutFailed
+		}
+		if err = saveAgentRollout(rollout); err != nil {
+			printProgress("save rollout record error: %v\n", err)
+		}
+		if failed {
+			break
+		}
+		start = end
+	}
+	if !failed {
+		rollout.State = rolloutSucceeded
+	}
+	now := time.Now()
+	rollout.FinishedAt = &now
+	if err = saveAgentRollout(rollout); err != nil {
+		printProgress("save rollout record error: %v\n", err)
+	}
+	if err = printRolloutClusters(plan, target); err != nil {
+		return err
+	}
+	if failed {
+		return &ExitError{Code: 1, Err: errors.Errorf("agent rollout %s failed", rollout.ID)}
+	}
+	return nil
+}
+
+// rolloutBatch updates clusters of a batch then waits for them in parallel, returns false if any fails
+func rolloutBatch(batch []RolloutCluster, byName map[string]*kubeproberv1.Cluster, target AgentSetting) bool {
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		go func(rc *RolloutCluster) {
+			defer wg.Done()
+			cluster := byName[rc.Cluster]
+			c, err := GenerateProbeClient(cluster)
+			if err == nil {
+				rc.Previous, err = updateAgent(c, cluster, target, false)
+			}
+			if err != nil {
+				rc.State, rc.Error = rolloutFailed, err.Error()
+				return
+			}
+			rc.updated = true
+			if err = waitAgentHealthy(c, cluster, rolloutHealthTimeout); err != nil {
+				rc.State, rc.Error = rolloutFailed, err.Error()
+				return
+			}
+			rc.State = rolloutHealthy
+		}(&batch[i])
+	}
+	wg.Wait()
+	for _, rc := range batch {
+		if rc.State != rolloutHealthy {
+			return false
+		}
+	}
+	return true
+}
+
+// rollbackBatch restores the previous setting of updated clusters in a failed batch
+func rollbackBatch(batch []RolloutCluster, byName map[string]*kubeproberv1.Cluster) {
+	for i := range batch {
+		rc := &batch[i]
+		if !rc.updated {
+			continue
+		}
+		cluster := byName[rc.Cluster]
+		c, err := GenerateProbeClient(cluster)
+		if err == nil {
+			_, err = updateAgent(c, cluster, rc.Previous, true)
+		}
+		if err != nil {
+			rc.Error = strings.TrimPrefix(rc.Error+"; ", "; ") + "rollback error: " + err.Error()
+			continue
+		}
+		rc.State = rolloutRolledBack
+	}
+}
+
+func rolloutClusterNames(batch []RolloutCluster) string {
+	names := make([]string, 0, len(batch))
+	for _, rc := range batch {
+		names = append(names, rc.Cluster)
+	}
+	return strings.Join(names, ",")
+}
+
+func printRolloutClusters(plan []RolloutCluster, target AgentSetting) error {
+	p := newPrinter("CLUSTER", "BATCH", "STATE", "PREVIOUS", "TARGET", "ERROR")
+	p.MaxColWidth = 70
+	for _, rc := range plan {
+		p.AddRow(rc.Cluster, rc, rc.Cluster, rc.Batch, rc.State, orDash(rc.Previous.String()), target.String(), rc.Error)
+	}
+	return p.Print()
+}
+
+// saveAgentRollout stores the record as a key of the rollout configmap and keeps the latest records
+func saveAgentRollout(rollout *AgentRollout) error {
+	data, err := json.Marshal(rollout)
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &apiv1.ConfigMap{}
+		err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+			Namespace: metav1.NamespaceDefault,
+			Name:      AgentRolloutConfigMap,
+		}, cm)
+		if k8serrors.IsNotFound(err) {
+			cm = &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: AgentRolloutConfigMap},
+				Data:       map[string]string{rollout.ID: string(data)},
+			}
+			return k8sRestClient.Create(context.Background(), cm)
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[rollout.ID] = string(data)
+		pruneAgentRollouts(cm.Data, maxAgentRollouts)
+		return k8sRestClient.Update(context.Background(), cm)
+	})
+}
+
+// pruneAgentRollouts removes the oldest records, ids are sortable by time
+func pruneAgentRollouts(data map[string]string, max int) {
+	ids := make([]string, 0, len(data))
+	for id := range data {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for i := 0; i < len(ids)-max; i++ {
+		delete(data, ids[i])
+	}
+}
+
+func ListAgentRollouts() error {
+	cm := &apiv1.ConfigMap{}
+	err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+		Namespace: metav1.NamespaceDefault,
+		Name:      AgentRolloutConfigMap,
+	}, cm)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	rollouts := make([]AgentRollout, 0, len(cm.Data))
+	for id, v := range cm.Data {
+		r := AgentRollout{}
+		if err = json.Unmarshal([]byte(v), &r); err != nil {
+			printProgress("skip invalid rollout record %s: %v\n", id, err)
+			continue
+		}
+		rollouts = append(rollouts, r)
+	}
+	sort.Slice(rollouts, func(i, j int) bool { return rollouts[i].ID > rollouts[j].ID })
+
+	p := newPrinter("ID", "STATE", "TARGET", "HEALTHY", "SELECTOR", "CREATEDBY", "STARTED", "FINISHED")
+	p.MaxColWidth = 70
+	for _, r := range rollouts {
+		healthy := 0
+		for _, rc := range r.Clusters {
+			if rc.State == rolloutHealthy {
+				healthy++
+			}
+		}
+		finished := "-"
+		if r.FinishedAt != nil {
+			finished = formatTime(*r.FinishedAt)
+		}
+		p.AddRow(r.ID, r, r.ID, r.State, r.Target.String(), fmt.Sprintf("%d/%d", healthy, len(r.Clusters)),
+			orDash(r.Selector), orDash(r.CreatedBy), formatTime(r.StartedAt), finished)
+	}
+	return p.Print()
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestValidateAgentSetting(t *testing.T) {
+	assert.NoError(t, validateAgentSetting(AgentSetting{Image: "kubeprober/probe-agent:v0.1.0", CPU: "500m", Memory: "512Mi"}))
+	assert.Error(t, validateAgentSetting(AgentSetting{CPU: "half"}))
+	assert.Error(t, validateAgentSetting(AgentSetting{Memory: "1 Gi"}))
+	assert.Error(t, validateAgentSetting(AgentSetting{Image: "probe agent"}))
+}
+
+func TestPlanRollout(t *testing.T) {
+	clusters := []kubeproberv1.Cluster{
+		{ObjectMeta: metav1.ObjectMeta{Name: "c"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b"}},
+	}
+	plan, err := planRollout(clusters, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []RolloutCluster{
+		{Cluster: "a", Batch: 1, State: rolloutPending},
+		{Cluster: "b", Batch: 1, State: rolloutPending},
+		{Cluster: "c", Batch: 2, State: rolloutPending},
+	}, plan)
+
+	_, err = planRollout(clusters, 0)
+	assert.Error(t, err)
+}
+
+func TestApplyAgentSetting(t *testing.T) {
+	deploy := &appv1.Deployment{}
+	deploy.Spec.Template.Spec.Containers = []apiv1.Container{
+		{Name: "sidecar", Image: "sidecar:v1"},
+		{Name: "probe-agent", Image: "probe-agent:v1", Resources: apiv1.ResourceRequirements{
+			Limits: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("1")},
+		}},
+	}
+	container, err := kubeproberv1.FindAgentContainer(deploy, "probe-agent")
+	assert.NoError(t, err)
+	previous := currentAgentSetting(container)
+	assert.Equal(t, AgentSetting{Image: "probe-agent:v1", CPU: "1"}, previous)
+
+	assert.NoError(t, applyAgentSetting(container, AgentSetting{Image: "probe-agent:v2", Memory: "256Mi"}, false))
+	assert.Equal(t, AgentSetting{Image: "probe-agent:v2", CPU: "1", Memory: "256Mi"}, currentAgentSetting(container))
+
+	assert.NoError(t, applyAgentSetting(container, previous, true))
+	assert.Equal(t, previous, currentAgentSetting(container))
+
+	_, err = kubeproberv1.FindAgentContainer(deploy, "agent")
+	assert.Error(t, err)
+}
+
+func TestAgentDeployReady(t *testing.T) {
+	replicas := int32(2)
+	deploy := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+	deploy.Spec.Replicas = &replicas
+	deploy.Status = appv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2}
+	assert.False(t, agentDeployReady(deploy))
+
+	deploy.Status.ObservedGeneration = 3
+	deploy.Status.Replicas = 3
+	deploy.Status.UpdatedReplicas = 1
+	assert.False(t, agentDeployReady(deploy))
+
+	deploy.Status.Replicas = 2
+	deploy.Status.UpdatedReplicas = 2
+	assert.True(t, agentDeployReady(deploy))
+}
+
+func TestPruneAgentRollouts(t *testing.T) {
+	data := map[string]string{
+		"20211001-100000": "{}",
+		"20211002-100000": "{}",
+		"20211003-100000": "{}",
+	}
+	pruneAgentRollouts(data, 2)
+	assert.Equal(t, map[string]string{"20211002-100000": "{}", "20211003-100000": "{}"}, data)
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const (
+	runLocalModeDocker = "docker"
+	runLocalModePod    = "pod"
+)
+
+var (
+	runLocalMode       string
+	runLocalListen     string
+	runLocalReportAddr string
+	runLocalNamespace  string
+	runLocalKubeconfig string
+	runLocalTimeout    time.Duration
+	runLocalKeep       bool
+)
+
+var RunLocalCmd = &cobra.Command{
+	Use:   "run-local PROBE_YAML|IMAGE",
+	Short: "Run a prober locally and print the checker results it reports",
+	Long: "Run a prober locally and print the checker results it reports. The prober runs in docker against the kubeconfig, " +
+		"or as a one-off pod in the current kube context with --mode pod, and reports to a receiver started by the command, " +
+		"e.g. kubectl probe run-local config/samples/kubeprobe_v1_probe.yaml",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RunLocal(args[0])
+	},
+}
+
+// statusReceiver receives checker status reported by probers like the /probe-status api of probe-agent
+type statusReceiver struct {
+	mu      sync.Mutex
+	reports []kubeproberv1.ReportProbeStatusSpec
+	// notified on every report
+	received chan struct{}
+}
+
+func newStatusReceiver() *statusReceiver {
+	return &statusReceiver{received: make(chan struct{}, 1)}
+}
+
+func (r *statusReceiver) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rp := kubeproberv1.ReportProbeStatusSpec{}
+	if err := json.NewDecoder(req.Body).Decode(&rp); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(err.Error()))
+		return
+	}
+	for i := range rp.Checkers {
+		rp.Checkers[i].Status = kubeproberv1.CheckerStatus(strings.ToUpper(string(rp.Checkers[i].Status)))
+	}
+	r.mu.Lock()
+	r.reports = append(r.reports, rp)
+	r.mu.Unlock()
+	select {
+	case r.received <- struct{}{}:
+	default:
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// Results returns the latest status of every reported checker
+func (r *statusReceiver) Results() []apistructs.CheckerResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var results []apistructs.CheckerResult
+	index := make(map[string]int)
+	for _, rp := range r.reports {
+		for _, c := range rp.Checkers {
+			result := checkerResult("", rp.ProbeName, c)
+			key := result.Probe + "/" + result.Checker
+			if i, ok := index[key]; ok {
+				results[i] = result
+				continue
+			}
+			index[key] = len(results)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func RunLocal(source string) error {
+	if runLocalMode != runLocalModeDocker && runLocalMode != runLocalModePod {
+		return errors.Errorf("invalid --mode %q, allowed values are: docker, pod", runLocalMode)
+	}
+	probe, err := loadLocalProbe(source)
+	if err != nil {
+		return err
+	}
+	if probe.Namespace == "" {
+		probe.Namespace = runLocalNamespace
+	}
+
+	listener, err := net.Listen("tcp", runLocalListen)
+	if err != nil {
+		return err
+	}
+	receiver := newStatusReceiver()
+	mux := http.NewServeMux()
+	mux.Handle("/probe-status", receiver)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	reportURL, err := runLocalReportURL(listener.Addr().(*net.TCPAddr).Port)
+	if err != nil {
+		return err
+	}
+	printProgress("probe-status receiver is listening on %s, probers report to %s\n", listener.Addr(), reportURL)
+
+	ctx := context.Background()
+	if runLocalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runLocalTimeout)
+		defer cancel()
+	}
+	if runLocalMode == runLocalModeDocker {
+		err = runProbeInDocker(ctx, probe, reportURL)
+	} else {
+		err = runProbeInPod(ctx, probe, reportURL, receiver)
+	}
+	if err != nil {
+		return err
+	}
+
+	results := receiver.Results()
+	if len(results) == 0 {
+		return errors.Errorf("probe %s exits without reporting checker status to %s", probe.Name, reportURL)
+	}
+	p := newPrinter("PROBER", "CHECKER", "STATUS", "MESSAGE", "LASTRUN")
+	p.MaxColWidth = 45
+	for _, r := range results {
+		addCheckerRow(p, r)
+	}
+	return p.Print()
+}
+
+// loadLocalProbe reads the probe from yaml file, or makes a probe running the image
+func loadLocalProbe(source string) (*kubeproberv1.Probe, error) {
+	probe := &kubeproberv1.Probe{}
+	if _, err := os.Stat(source); err == nil {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		if err = yaml.UnmarshalStrict(data, probe); err != nil {
+			return nil, errors.Wrapf(err, "parse probe %s", source)
+		}
+		if len(probe.Spec.Template.Containers) == 0 {
+			return nil, errors.Errorf("probe %s has no container", source)
+		}
+		return probe, nil
+	}
+
+	name := source[strings.LastIndex(source, "/")+1:]
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+	probe.Name = name
+	probe.Spec.Template.Containers = []corev1.Container{{Name: name, Image: source}}
+	return probe, nil
+}
+
+// runLocalReportURL returns the url of receiver which can be reached by probers
+func runLocalReportURL(port int) (string, error) {
+	host := runLocalReportAddr
+	if host == "" && runLocalMode == runLocalModeDocker {
+		// docker runs with host network
+		host = "127.0.0.1"
+	}
+	if host == "" {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return "", err
+		}
+		for _, addr := range addrs {
+			if ip, ok := addr.(*net.IPNet); ok && !ip.IP.IsLoopback() && ip.IP.To4() != nil {
+				host = ip.IP.String()
+				break
+			}
+		}
+		if host == "" {
+			return "", errors.New("no address can be reached by the pod, set it by --report-addr")
+		}
+	}
+	if !strings.Contains(host, ":") {
+		host = fmt.Sprintf("%s:%d", host, port)
+	}
+	return fmt.Sprintf("http://%s/probe-status", host), nil
+}
+
+// extraConfigEnvs returns envs of the extra config which probe-agent injects into probers
+func extraConfigEnvs(namespace string) ([]corev1.EnvVar, error) {
+	if k8sRestClient == nil {
+		return nil, errors.New("no kube context is found")
+	}
+	cm := &corev1.ConfigMap{}
+	if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+		Namespace: namespace,
+		Name:      kubeproberv1.ExtraCMName,
+	}, cm); err != nil {
+		return nil, err
+	}
+	var envs []corev1.EnvVar
+	for k, v := range cm.Data {
+		envs = append(envs, corev1.EnvVar{Name: k, Value: v})
+	}
+	return envs, nil
+}
+
+func runProbeInDocker(ctx context.Context, probe *kubeproberv1.Probe, reportURL string) error {
+	envs, _ := kubeproberv1.ProbeEnvs(*probe, reportURL)
+	extra, err := extraConfigEnvs(probe.Namespace)
+	if err != nil {
+		printProgress("configmap %s/%s is not injected: %v\n", probe.Namespace, kubeproberv1.ExtraCMName, err)
+	}
+	envs = append(extra, envs...)
+	kubeconfig := runLocalKubeconfig
+	if kubeconfig == "" {
+		kubeconfig = os.Getenv("KUBECONFIG")
+	}
+	if kubeconfig == "" {
+		home, _ := os.UserHomeDir()
+		kubeconfig = filepath.Join(home, ".kube", "config")
+	}
+
+	for _, c := range probe.Spec.Template.Containers {
+		args := []string{"run", "--rm", "--network", "host",
+			"-v", kubeconfig + ":/root/.kube/config:ro", "-e", "KUBECONFIG=/root/.kube/config"}
+		for _, e := range append(append([]corev1.EnvVar{}, c.Env...), envs...) {
+			if e.ValueFrom != nil {
+				printProgress("env %s of container %s is not supported by docker mode\n", e.Name, c.Name)
+				continue
+			}
+			args = append(args, "-e", e.Name+"="+e.Value)
+		}
+		if len(c.Command) > 0 {
+			args = append(args, "--entrypoint", c.Command[0])
+		}
+		args = append(args, c.Image)
+		if len(c.Command) > 1 {
+			args = append(args, c.Command[1:]...)
+		}
+		args = append(args, c.Args...)
+
+		printProgress("==> running container %s: docker %s\n", c.Name, strings.Join(args, " "))
+		cmd := exec.CommandContext(ctx, "docker", args...)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return &ExitError{Code: exitCodeTimeout, Err: errors.Errorf("probe %s doesn't finish in %s", probe.Name, runLocalTimeout)}
+			}
+			printProgress("container %s exits: %v\n", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// runLocalPod returns the pod running probe once, labels of probe pods are not set,
+// otherwise probe-agent reports the pod as a checker of the probe in the real ProbeStatus
+func runLocalPod(probe *kubeproberv1.Probe, envs []corev1.EnvVar, envFrom []corev1.EnvFromSource, now time.Time) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-runlocal-%d", probe.Name, now.Unix()),
+			Namespace: probe.Namespace,
+			Labels:    map[string]string{kubeproberv1.LabelKeyRunLocal: "true"},
+		},
+		Spec: *probe.Spec.Template.DeepCopy(),
+	}
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, envs...)
+		pod.Spec.Containers[i].EnvFrom = append(pod.Spec.Containers[i].EnvFrom, envFrom...)
+	}
+	return pod
+}
+
+func runProbeInPod(ctx context.Context, probe *kubeproberv1.Probe, reportURL string, receiver *statusReceiver) error {
+	if k8sRestClient == nil {
+		return errors.New("no kube context is found")
+	}
+	envs, envFrom := kubeproberv1.ProbeEnvs(*probe, reportURL)
+	if _, err := extraConfigEnvs(probe.Namespace); err != nil {
+		// pod can't start with missing configmap
+		printProgress("configmap %s/%s is not injected: %v\n", probe.Namespace, kubeproberv1.ExtraCMName, err)
+		envFrom = nil
+	}
+	pod := runLocalPod(probe, envs, envFrom, time.Now())
+	if err := k8sRestClient.Create(ctx, pod); err != nil {
+		return err
+	}
+	printProgress("==> pod %s/%s is created\n", pod.Namespace, pod.Name)
+	if !runLocalKeep {
+		defer func() {
+			if err := k8sRestClient.Delete(context.Background(), pod); err != nil && !apierrors.IsNotFound(err) {
+				printProgress("delete pod %s/%s error: %v\n", pod.Namespace, pod.Name, err)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(oncePollTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return &ExitError{Code: exitCodeTimeout, Err: errors.Errorf("probe %s doesn't finish in %s", probe.Name, runLocalTimeout)}
+		case <-receiver.received:
+			printProgress("checker status is reported\n")
+		case <-ticker.C:
+		}
+		current := &corev1.Pod{}
+		if err := k8sRestClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, current); err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return err
+		}
+		if current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed {
+			printProgress("==> pod %s/%s is %s\n", pod.Namespace, pod.Name, current.Status.Phase)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestStatusReceiver(t *testing.T) {
+	r := newStatusReceiver()
+	report := func(body string) int {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/probe-status", strings.NewReader(body)))
+		return rw.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, report("{"))
+	assert.Equal(t, http.StatusOK, report(`{"probeName":"k8s","checkers":[{"name":"dns","status":"error","message":"timeout"},{"name":"etcd","status":"pass"}]}`))
+	assert.Equal(t, http.StatusOK, report(`{"probeName":"k8s","checkers":[{"name":"dns","status":"pass"}]}`))
+
+	results := r.Results()
+	assert.Len(t, results, 2)
+	assert.Equal(t, "dns", results[0].Checker)
+	assert.Equal(t, kubeproberv1.CheckerStatusPass, results[0].Status)
+	assert.Equal(t, kubeproberv1.CheckerStatusPass, results[1].Status)
+	select {
+	case <-r.received:
+	default:
+		t.Fatal("report is not notified")
+	}
+}
+
+func TestLoadLocalProbeImage(t *testing.T) {
+	probe, err := loadLocalProbe("kubeprober/probe-k8s:v0.1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "probe-k8s", probe.Name)
+	assert.Equal(t, "kubeprober/probe-k8s:v0.1.0", probe.Spec.Template.Containers[0].Image)
+}
+
+func TestLoadLocalProbeFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "probe-*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`apiVersion: kubeprober.erda.cloud/v1
+kind: Probe
+metadata:
+  name: k8s
+spec:
+  template:
+    containers:
+      - name: k8s
+        image: kubeprober/probe-k8s:v0.1.0
+`)
+	assert.NoError(t, err)
+	f.Close()
+
+	probe, err := loadLocalProbe(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "k8s", probe.Name)
+	assert.Equal(t, "kubeprober/probe-k8s:v0.1.0", probe.Spec.Template.Containers[0].Image)
+
+	assert.NoError(t, ioutil.WriteFile(f.Name(), []byte("spec:\n  probeList: []\n"), 0644))
+	_, err = loadLocalProbe(f.Name())
+	assert.Error(t, err)
+}
+
+func TestRunLocalPod(t *testing.T) {
+	probe, err := loadLocalProbe("kubeprober/probe-k8s:v0.1.0")
+	assert.NoError(t, err)
+	probe.Namespace = "kubeprober"
+	envs := []corev1.EnvVar{{Name: kubeproberv1.ProbeStatusReportUrl, Value: "http://127.0.0.1:8080/probe-status"}}
+
+	pod := runLocalPod(probe, envs, nil, time.Unix(1630000000, 0))
+	assert.Equal(t, "probe-k8s-runlocal-1630000000", pod.Name)
+	assert.Equal(t, "kubeprober", pod.Namespace)
+	// probe-agent watches pods of the app label and reports them in ProbeStatus
+	assert.Equal(t, map[string]string{kubeproberv1.LabelKeyRunLocal: "true"}, pod.Labels)
+	assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	assert.Equal(t, envs, pod.Spec.Containers[0].Env)
+	assert.Empty(t, probe.Spec.Template.Containers[0].Env)
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+var (
+	silenceChecker    string
+	silenceSeverities []string
+	silenceDuration   string
+	silenceStart      string
+	silenceEnd        string
+	silenceCreatedBy  string
+	silenceComment    string
+	silenceAll        bool
+)
+
+var SilenceCmd = &cobra.Command{
+	Use:   "silence",
+	Short: "Manage silences which mute alerts and tickets of clusters and checkers",
+	Long:  "Manage silences which mute alerts and tickets of clusters and checkers",
+}
+
+var SilenceCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a silence",
+	Long:  "Create a silence, e.g. kubectl probe silence create -c prod -p k8s --duration 2h --comment upgrade",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return CreateSilence()
+	},
+}
+
+var SilenceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pending and active silences",
+	Long:  "List pending and active silences",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ListSilences()
+	},
+}
+
+var SilenceExpireCmd = &cobra.Command{
+	Use:   "expire NAME...",
+	Short: "Expire silences now",
+	Long:  "Expire silences now",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ExpireSilences(args)
+	},
+}
+
+func init() {
+	SilenceCmd.AddCommand(SilenceCreateCmd, SilenceListCmd, SilenceExpireCmd)
+}
+
+func CreateSilence() error {
+	if clusterName == "" && probes == "" && silenceChecker == "" {
+		return errors.New("at least one of --cluster, --probe and --checker is required")
+	}
+	// a silence matches a single probe, unlike the comma separated --probe of other commands
+	if strings.Contains(probes, ",") {
+		return errors.Errorf("--probe of a silence must be a single probe, got %q", probes)
+	}
+	now := time.Now()
+	start := now
+	if silenceStart != "" {
+		t, err := time.Parse(time.RFC3339, silenceStart)
+		if err != nil {
+			return errors.Wrap(err, "invalid --start")
+		}
+		start = t
+	}
+	var end time.Time
+	if silenceEnd != "" {
+		t, err := time.Parse(time.RFC3339, silenceEnd)
+		if err != nil {
+			return errors.Wrap(err, "invalid --end")
+		}
+		end = t
+	} else {
+		d, err := time.ParseDuration(silenceDuration)
+		if err != nil {
+			return errors.Wrap(err, "invalid --duration")
+		}
+		end = start.Add(d)
+	}
+	if !end.After(start) || !end.After(now) {
+		return errors.New("end of silence must be after its start and now")
+	}
+
+	var severities []kubeproberv1.CheckerStatus
+	for _, s := range silenceSeverities {
+		severities = append(severities, kubeproberv1.CheckerStatus(strings.ToUpper(s)))
+	}
+	createdBy := silenceCreatedBy
+	if createdBy == "" {
+		createdBy = os.Getenv("USER")
+	}
+	silence := &kubeproberv1.Silence{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    metav1.NamespaceDefault,
+			GenerateName: "silence-",
+		},
+		Spec: kubeproberv1.SilenceSpec{
+			Matchers: kubeproberv1.SilenceMatchers{
+				Cluster:    clusterName,
+				Probe:      probes,
+				Checker:    silenceChecker,
+				Severities: severities,
+			},
+			StartsAt:  metav1.Time{Time: start},
+			EndsAt:    metav1.Time{Time: end},
+			CreatedBy: createdBy,
+			Comment:   silenceComment,
+		},
+	}
+	if err := k8sRestClient.Create(context.Background(), silence); err != nil {
+		return err
+	}
+	fmt.Printf("silence %s created, ends at %s\n", silence.Name, formatTime(end))
+	return nil
+}
+
+func ListSilences() error {
+	silences := &kubeproberv1.SilenceList{}
+	if err := k8sRestClient.List(context.Background(), silences, client.InNamespace(metav1.NamespaceDefault)); err != nil {
+		return err
+	}
+	sort.Slice(silences.Items, func(i, j int) bool {
+		return silences.Items[i].Spec.EndsAt.Before(&silences.Items[j].Spec.EndsAt)
+	})
+
+	now := time.Now()
+	p := newPrinter("NAME", "CLUSTER", "PROBER", "CHECKER", "SEVERITIES", "STATE", "STARTS", "ENDS", "CREATEDBY", "COMMENT")
+	p.MaxColWidth = 45
+	for _, s := range silences.Items {
+		state := s.State(now)
+		if state == kubeproberv1.SilenceStateExpired && !silenceAll {
+			continue
+		}
+		m := s.Spec.Matchers
+		var severities []string
+		for _, severity := range m.Severities {
+			severities = append(severities, string(severity))
+		}
+		p.AddRow(s.Name, s, s.Name, orAny(m.Cluster), orAny(m.Probe), orAny(m.Checker), orAny(strings.Join(severities, ",")),
+			state, formatTime(s.Spec.StartsAt.Time), formatTime(s.Spec.EndsAt.Time), s.Spec.CreatedBy, s.Spec.Comment)
+	}
+	return p.Print()
+}
+
+func ExpireSilences(names []string) error {
+	now := metav1.Now()
+	for _, name := range names {
+		silence := &kubeproberv1.Silence{}
+		if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+			Namespace: metav1.NamespaceDefault,
+			Name:      name,
+		}, silence); err != nil {
+			return err
+		}
+		if silence.State(now.Time) == kubeproberv1.SilenceStateExpired {
+			fmt.Printf("silence %s already expired\n", name)
+			continue
+		}
+		patch := client.MergeFrom(silence.DeepCopy())
+		if silence.Spec.StartsAt.After(now.Time) {
+			silence.Spec.StartsAt = now
+		}
+		silence.Spec.EndsAt = now
+		if err := k8sRestClient.Patch(context.Background(), silence, patch); err != nil {
+			return err
+		}
+		fmt.Printf("silence %s expired\n", name)
+	}
+	return nil
+}
+
+func orAny(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
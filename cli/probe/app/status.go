@@ -15,6 +15,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -22,7 +23,7 @@ import (
 	"time"
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
-	"github.com/gosuri/uitable"
+	"github.com/erda-project/kubeprober/apistructs"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -39,18 +40,10 @@ var (
 )
 
 func init() {
-	userHomeDir, err := os.UserHomeDir()
-	if err != nil {
-		userHomeDir = ""
-	}
-	kubeConfig := filepath.Join(userHomeDir, ".kube", "config")
-	config, err := rest.InClusterConfig()
+	config, err := localRestConfig()
 	if err != nil {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
-		if err != nil {
-			klog.Errorf("[remote dialer server] get kubernetes client config error: %+v\n", err)
-			return
-		}
+		klog.Errorf("[remote dialer server] get kubernetes client config error: %+v\n", err)
+		return
 	}
 
 	scheme := runtime.NewScheme()
@@ -62,11 +55,32 @@ func init() {
 	}
 }
 
+// localRestConfig returns config of the cluster running probe-master, in cluster config or ~/.kube/config
+func localRestConfig() (*rest.Config, error) {
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		userHomeDir = ""
+	}
+	kubeConfig := filepath.Join(userHomeDir, ".kube", "config")
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
+	}
+	return config, err
+}
+
 var StatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Print probe status of remote cluster or local cluster",
-	Long:  "Print probe status of remote cluster or local cluster",
+	Long: "Print probe status of remote cluster or local cluster, " +
+		"--all-clusters or --selector prints the worst status of probes in every cluster, and -p drills down into checkers of a probe",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if statusAllClusters || statusSelector != "" {
+			if clusterName != "" {
+				return errors.New("--cluster can't be used with --all-clusters or --selector")
+			}
+			return GetFleetStatus(statusSelector, probes, status)
+		}
 		return GetProbeStatus(clusterName, status)
 	},
 }
@@ -74,10 +88,6 @@ var StatusCmd = &cobra.Command{
 func GetProbeStatus(clusterName string, status string) error {
 	var err error
 	var c client.Client
-	var probeNames []string
-
-	probeStatusList := &kubeproberv1.ProbeStatusList{}
-	probeList := &kubeproberv1.ProbeList{}
 
 	if clusterName == "" {
 		c = k8sRestClient
@@ -96,11 +106,37 @@ func GetProbeStatus(clusterName string, status string) error {
 			return err
 		}
 	}
-	if err = c.List(context.Background(), probeStatusList, client.InNamespace("kubeprober")); err != nil {
+	results, err := listCheckerResults(context.Background(), c, clusterName)
+	if err != nil {
 		return err
 	}
-	if err = c.List(context.Background(), probeList, client.InNamespace("kubeprober")); err != nil {
-		return err
+
+	p := newPrinter("PROBER", "CHECKER", "STATUS", "MESSAGE", "LASTRUN")
+	p.MaxColWidth = 45
+	for _, r := range results {
+		if status != "" && string(r.Status) != status {
+			continue
+		}
+		if probes != "" && r.Probe != probes {
+			continue
+		}
+		addCheckerRow(p, r)
+	}
+	return p.Print()
+}
+
+// listCheckerResults returns checker status of cron probes which run in 4 hours
+func listCheckerResults(ctx context.Context, c client.Client, cluster string) ([]apistructs.CheckerResult, error) {
+	var probeNames []string
+	var results []apistructs.CheckerResult
+
+	probeStatusList := &kubeproberv1.ProbeStatusList{}
+	probeList := &kubeproberv1.ProbeList{}
+	if err := c.List(ctx, probeStatusList, client.InNamespace(KBNAMESPACE)); err != nil {
+		return nil, err
+	}
+	if err := c.List(ctx, probeList, client.InNamespace(KBNAMESPACE)); err != nil {
+		return nil, err
 	}
 	//just print cron probe status
 	for _, i := range probeList.Items {
@@ -108,10 +144,6 @@ func GetProbeStatus(clusterName string, status string) error {
 			probeNames = append(probeNames, i.Name)
 		}
 	}
-	table := uitable.New()
-	table.MaxColWidth = 45
-	table.Wrap = true
-	table.AddRow("PROBER", "CHECKER", "STATUS", "MESSAGE", "LASTRUN")
 	d, _ := time.ParseDuration("-4h")
 	oneDayAgo := time.Now().Add(d)
 	for _, i := range probeStatusList.Items {
@@ -123,18 +155,30 @@ func GetProbeStatus(clusterName string, status string) error {
 				if j.LastRun.Before(&metav1.Time{Time: oneDayAgo}) {
 					continue
 				}
-				if string(j.Status) == status && status != "" {
-					table.AddRow(i.Name, j.Name, j.Status, strings.TrimSpace(j.Message), j.LastRun.Format("2006-01-02 15:04:05"))
-				}
-				if status == "" {
-					table.AddRow(i.Name, j.Name, j.Status, strings.TrimSpace(j.Message), j.LastRun.Format("2006-01-02 15:04:05"))
-				}
+				results = append(results, checkerResult(cluster, i.Name, j))
 			}
 		}
 	}
-	fmt.Println(table)
+	return results, nil
+}
+
+// checkerResult converts checker status into the same structure as history results
+func checkerResult(cluster, prober string, checker kubeproberv1.ProbeCheckerStatus) apistructs.CheckerResult {
+	r := apistructs.CheckerResult{
+		Cluster: cluster,
+		Probe:   prober,
+		Checker: checker.Name,
+		Status:  checker.Status,
+		Message: strings.TrimSpace(checker.Message),
+	}
+	if checker.LastRun != nil {
+		r.Time = checker.LastRun.Time
+	}
+	return r
+}
 
-	return nil
+func addCheckerRow(p *printer, r apistructs.CheckerResult) {
+	p.AddRow(r.Probe+"/"+r.Checker, r, r.Probe, r.Checker, r.Status, r.Message, formatTime(r.Time))
 }
 
 func IsContain(items []string, item string) bool {
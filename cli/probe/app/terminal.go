@@ -16,10 +16,8 @@ package app
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"os"
 
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/remotecommand"
@@ -61,18 +59,10 @@ func terminal() error {
 	}
 	sizeQueue := t.MonitorSize(t.GetSize())
 
-	url, err := url.Parse(tunnelclient.MasterAddr)
+	url, err := tunnelclient.GetMasterURL(fmt.Sprintf("/api/k8s/clusters/%s", clusterName))
 	if err != nil {
 		return err
 	}
-	if url.Scheme == "ws" {
-		url.Scheme = "http"
-	} else if url.Scheme == "wss" {
-		url.Scheme = "https"
-	} else {
-		return errors.Errorf("invalid schema %s in %s", url.Scheme, tunnelclient.MasterAddr)
-	}
-	url.Path = fmt.Sprintf("/api/k8s/clusters/%s", clusterName)
 	fn := func() error {
 		exec, err := remotecommand.NewSPDYExecutor(conf, "POST", url)
 		if err != nil {
@@ -15,6 +15,7 @@ package main
 
 import (
 	"math/rand"
+	"os"
 	"time"
 
 	"github.com/erda-project/kubeprober/cli/probe/app"
@@ -34,7 +35,21 @@ func main() {
 	cmd.AddCommand(app.OnceStatusCmd)
 	cmd.AddCommand(app.OpsCmd)
 	cmd.AddCommand(app.TerminalCmd)
+	cmd.AddCommand(app.HistoryCmd)
+	cmd.AddCommand(app.SilenceCmd)
+	cmd.AddCommand(app.AlertsCmd)
+	cmd.AddCommand(app.AttachCmd)
+	cmd.AddCommand(app.DetachCmd)
+	cmd.AddCommand(app.ClusterCmd)
+	cmd.AddCommand(app.LogsCmd)
+	cmd.AddCommand(app.RunLocalCmd)
+	cmd.AddCommand(app.DiffCmd)
+	cmd.AddCommand(app.ReportCmd)
 	if err := cmd.Execute(); err != nil {
+		if code := app.ExitCode(err); code != 0 {
+			klog.Flush()
+			os.Exit(code)
+		}
 		panic(err)
 	}
 }
@@ -19,10 +19,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
 
+	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/flowcontrol"
@@ -46,8 +48,9 @@ type KpConfig struct {
 }
 
 const (
-	ManageProxy  = "proxy"
-	KpConfigFlie = ".kubeprober/config"
+	ManageProxy       = "proxy"
+	KpConfigFlie      = ".kubeprober/config"
+	DefaultMasterAddr = "ws://probe-master.kubeprober.svc.cluster.local:8088"
 )
 
 var MasterAddr string
@@ -133,11 +136,32 @@ var once sync.Once
 
 func Init() {
 	if MasterAddr == "" {
-		MasterAddr = "ws://probe-master.kubeprober.svc.cluster.local:8088"
+		MasterAddr = DefaultMasterAddr
 	}
 	clusterdialer.InitSession(MasterAddr)
 }
 
+// GetMasterURL converts the websocket address of probe-master to a http url with path
+func GetMasterURL(path string) (*url.URL, error) {
+	addr := MasterAddr
+	if addr == "" {
+		addr = DefaultMasterAddr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "ws" {
+		u.Scheme = "http"
+	} else if u.Scheme == "wss" {
+		u.Scheme = "https"
+	} else {
+		return nil, errors.Errorf("invalid schema %s in %s", u.Scheme, addr)
+	}
+	u.Path = path
+	return u, nil
+}
+
 func GetDialerRestConfig(clusterName string, c *ManageConfig) (*rest.Config, error) {
 	once.Do(func() { Init() })
 
@@ -34,7 +34,9 @@ import (
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
 	"github.com/erda-project/kubeprober/apistructs"
 	"github.com/erda-project/kubeprober/cmd/probe-master/options"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/message"
 	"github.com/erda-project/kubeprober/pkg/probe-master/controller"
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
 	server "github.com/erda-project/kubeprober/pkg/probe-master/tunnel-server"
 	// +kubebuilder:scaffold:imports
 )
@@ -85,6 +87,10 @@ func NewCmdProbeMasterManager(stopCh <-chan struct{}) *cobra.Command {
 				klog.V(1).Infof("FLAG: --%s=%q", flag.Name, flag.Value)
 			})
 			klog.Errorf("config %+v\n", ProbeMasterOptions)
+			if err := options.ValidateOptions(ProbeMasterOptions); err != nil {
+				klog.Errorf("invalid options: %+v", err)
+				return
+			}
 			Run(ProbeMasterOptions)
 		},
 	}
@@ -98,6 +104,14 @@ func Run(opts *options.ProbeMasterOptions) {
 		Development: false,
 	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&optts)))
+	if err := timezone.Set(opts.Timezone); err != nil {
+		setupLog.Error(err, "unable to set timezone")
+		os.Exit(1)
+	}
+	if err := message.SetDefaultLanguage(opts.Language); err != nil {
+		setupLog.Error(err, "unable to set language")
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -165,6 +179,32 @@ func Run(opts *options.ProbeMasterOptions) {
 		ProjectId:    opts.ErdaProjectId,
 	}
 
+	alertConfig := &apistructs.AlertConfig{
+		GroupWait:       opts.AlertGroupWait,
+		GroupInterval:   opts.AlertGroupInterval,
+		RepeatInterval:  opts.AlertRepeatInterval,
+		ResolveTimeout:  opts.AlertResolveTimeout,
+		AlertmanagerURL: opts.AlertmanagerURL,
+	}
+
+	ticketConfig := &apistructs.TicketConfig{
+		Jira: apistructs.JiraConfig{
+			URL:              opts.JiraURL,
+			Username:         opts.JiraUsername,
+			Token:            opts.JiraToken,
+			Project:          opts.JiraProject,
+			IssueType:        opts.JiraIssueType,
+			CloseTransition:  opts.JiraCloseTransition,
+			ReopenTransition: opts.JiraReopenTransition,
+		},
+		GitHub: apistructs.GitHubConfig{
+			URL:   opts.GitHubURL,
+			Token: opts.GitHubToken,
+			Repo:  opts.GitHubRepo,
+		},
+		CommentInterval: opts.TicketCommentInterval,
+	}
+
 	ctx := ctrl.SetupSignalHandler()
 	//start remote cluster dialer
 	klog.Infof("starting probe-master remote dialer server on :8088")
@@ -173,7 +213,7 @@ func Run(opts *options.ProbeMasterOptions) {
 		Timeout:            0,
 		Listen:             opts.ProbeMasterListenAddr,
 		BypassAuthPassword: os.Getenv("BYPASS_PUSH_METRIC_PASSWORD"),
-	}, influxdbConfig, erdaConfig)
+	}, influxdbConfig, erdaConfig, alertConfig, ticketConfig)
 
 	setupLog.Info("starting manager")
 	time.Sleep(10 * time.Second)
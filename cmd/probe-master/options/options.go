@@ -14,7 +14,12 @@
 package options
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/pflag"
+
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
 )
 
 type ProbeMasterOptions struct {
@@ -41,6 +46,24 @@ type ProbeMasterOptions struct {
 	ErdaOrg                 string
 	ErdaProjectId           uint64
 	ErdaTicketEnable        bool
+	Timezone                string
+	Language                string
+	AlertGroupWait          time.Duration
+	AlertGroupInterval      time.Duration
+	AlertRepeatInterval     time.Duration
+	AlertResolveTimeout     time.Duration
+	AlertmanagerURL         string
+	JiraURL                 string
+	JiraUsername            string
+	JiraToken               string
+	JiraProject             string
+	JiraIssueType           string
+	JiraCloseTransition     string
+	JiraReopenTransition    string
+	GitHubURL               string
+	GitHubToken             string
+	GitHubRepo              string
+	TicketCommentInterval   time.Duration
 }
 
 // NewProbeMasterOptions creates a new NewProbeMasterOptions with a default config.
@@ -58,6 +81,13 @@ func NewProbeMasterOptions() *ProbeMasterOptions {
 		ConfigFile:              "",
 		InfluxdbEnable:          false,
 		ErdaTicketEnable:        false,
+		Timezone:                timezone.DefaultTimezone,
+		Language:                "zh",
+		AlertGroupWait:          30 * time.Second,
+		AlertGroupInterval:      5 * time.Minute,
+		AlertRepeatInterval:     4 * time.Hour,
+		AlertResolveTimeout:     24 * time.Hour,
+		TicketCommentInterval:   time.Hour,
 	}
 
 	return o
@@ -65,7 +95,12 @@ func NewProbeMasterOptions() *ProbeMasterOptions {
 
 // ValidateOptions validates YurtAppOptions
 func ValidateOptions(options *ProbeMasterOptions) error {
-	// TODO
+	if _, err := time.LoadLocation(options.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", options.Timezone, err)
+	}
+	if options.Language != "zh" && options.Language != "en" {
+		return fmt.Errorf("invalid language %q, supported: zh, en", options.Language)
+	}
 	return nil
 }
 
@@ -92,5 +127,23 @@ func (o *ProbeMasterOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.ErdaUsername, "erda_username", o.ErdaUsername, "erda username.")
 	fs.StringVar(&o.ErdaPassword, "erda_password", o.ErdaPassword, "erda password.")
 	fs.StringVar(&o.ErdaOrg, "erda_org", o.ErdaOrg, "erda organization.")
+	fs.StringVar(&o.Timezone, "timezone", o.Timezone, "timezone of daily alert statistics and weekly tickets, e.g. UTC or Europe/Berlin.")
+	fs.StringVar(&o.Language, "language", o.Language, "language of built-in alert and ticket templates, zh or en.")
+	fs.DurationVar(&o.AlertGroupWait, "alert_group_wait", o.AlertGroupWait, "how long to wait before notifying a receiver of new alerts.")
+	fs.DurationVar(&o.AlertGroupInterval, "alert_group_interval", o.AlertGroupInterval, "how long to wait before notifying a receiver of changed or resolved alerts.")
+	fs.DurationVar(&o.AlertRepeatInterval, "alert_repeat_interval", o.AlertRepeatInterval, "how long to wait before sending a firing alert again.")
+	fs.DurationVar(&o.AlertResolveTimeout, "alert_resolve_timeout", o.AlertResolveTimeout, "firing alerts without new checker results in this duration are resolved.")
+	fs.StringVar(&o.AlertmanagerURL, "alertmanager_url", o.AlertmanagerURL, "url of alertmanager which checker failures are forwarded to, e.g. http://alertmanager:9093, disabled if empty.")
 	fs.Uint64Var(&o.ErdaProjectId, "erda_project_id", o.ErdaProjectId, "erda project id.")
+	fs.DurationVar(&o.TicketCommentInterval, "ticket_comment_interval", o.TicketCommentInterval, "minimum interval between comments on a ticket, failures in the interval are merged into the next comment.")
+	fs.StringVar(&o.JiraURL, "jira_url", o.JiraURL, "jira url, tickets of jira are disabled if empty.")
+	fs.StringVar(&o.JiraUsername, "jira_username", o.JiraUsername, "jira username.")
+	fs.StringVar(&o.JiraToken, "jira_token", o.JiraToken, "jira api token or password.")
+	fs.StringVar(&o.JiraProject, "jira_project", o.JiraProject, "key of jira project.")
+	fs.StringVar(&o.JiraIssueType, "jira_issue_type", "Task", "jira issue type of tickets.")
+	fs.StringVar(&o.JiraCloseTransition, "jira_close_transition", "Done", "jira transition to close tickets.")
+	fs.StringVar(&o.JiraReopenTransition, "jira_reopen_transition", "To Do", "jira transition to reopen tickets.")
+	fs.StringVar(&o.GitHubURL, "github_url", "https://api.github.com", "github api url.")
+	fs.StringVar(&o.GitHubToken, "github_token", o.GitHubToken, "github token.")
+	fs.StringVar(&o.GitHubRepo, "github_repo", o.GitHubRepo, "owner/repo of github issues, tickets of github are disabled if empty.")
 }
@@ -378,42 +378,7 @@ func genJob(probe *kubeproberv1.Probe) (j batchv1.Job, err error) {
 }
 
 func envInject(probe kubeproberv1.Probe) (envs []corev1.EnvVar, envFromSources []corev1.EnvFromSource) {
-	envs = make([]corev1.EnvVar, 0)
-	envFromSources = make([]corev1.EnvFromSource, 0)
-
-	// env from
-	envFromSources = append(envFromSources,
-		corev1.EnvFromSource{
-			ConfigMapRef: &corev1.ConfigMapEnvSource{
-				LocalObjectReference: corev1.LocalObjectReference{
-					Name: kubeproberv1.ExtraCMName,
-				}}},
-	)
-
-	// envs
-	envs = []corev1.EnvVar{
-		{
-			Name:  kubeproberv1.ProbeNamespace,
-			Value: probe.Namespace,
-		},
-		{
-			Name:  kubeproberv1.ProbeName,
-			Value: probe.Name,
-		},
-		{
-			Name:  kubeproberv1.ProbeStatusReportUrl,
-			Value: options.ProbeAgentConf.GetProbeStatusReportUrl(),
-		},
-	}
-
-	// env from probe configs
-	for i := range probe.Spec.Configs {
-		for j := range probe.Spec.Configs[i].Env {
-			envs = append(envs, probe.Spec.Configs[i].Env[j])
-		}
-	}
-
-	return
+	return kubeproberv1.ProbeEnvs(probe, options.ProbeAgentConf.GetProbeStatusReportUrl())
 }
 
 type ProbePredicates struct {
@@ -30,6 +30,7 @@ import (
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
 	"github.com/erda-project/kubeprober/apistructs"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -174,6 +175,7 @@ func sendHeartBeat(heartBeatAddr string, clusterName string) error {
 		NodeCount:      len(nodes.Items),
 		Checkers:       checkerStatus,
 		ExtraStatus:    extraStatus,
+		AgentVersion:   getAgentVersion(k8sRestClient, os.Getenv("POD_NAMESPACE")),
 	}
 	json_data, _ := json.Marshal(hbData)
 	if rsp, err = http.Post(heartBeatAddr, "application/json", bytes.NewBuffer(json_data)); err != nil {
@@ -226,6 +228,25 @@ func getCheckerStatus(k8sRestClient client.Client) (string, error) {
 	checkerStatus := fmt.Sprintf("%s/%s", strconv.Itoa(totalChecker), strconv.Itoa(ErrorChecker))
 	return checkerStatus, nil
 }
+
+// getAgentVersion returns image version of agent container in probe-agent deployment, empty if it can't be found
+func getAgentVersion(k8sRestClient client.Client, namespace string) string {
+	deploy := &appsv1.Deployment{}
+	if err := k8sRestClient.Get(context.Background(), client.ObjectKey{
+		Namespace: namespace,
+		Name:      kubeproberv1.AgentDeployment,
+	}, deploy); err != nil {
+		klog.Errorf("[heartbeat] get probe-agent deployment error: %+v\n", err)
+		return ""
+	}
+	container, err := kubeproberv1.FindAgentContainer(deploy, kubeproberv1.AgentContainer)
+	if err != nil {
+		klog.Errorf("[heartbeat] %+v\n", err)
+		return ""
+	}
+	return kubeproberv1.ImageVersion(container.Image)
+}
+
 func getClusterName(clientset *kubernetes.Clientset) (string, error) {
 	var cm *v1.ConfigMap
 	var err error
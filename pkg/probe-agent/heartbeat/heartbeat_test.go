@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package heartbeat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetAgentVersion(t *testing.T) {
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "kubeprober", Name: "probe-agent"}}
+	deploy.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "sidecar", Image: "registry.example.com:5000/sidecar:v1"},
+		{Name: "probe-agent", Image: "registry.example.com:5000/kubeprober/probe-agent:v0.1.0"},
+	}
+	c := fake.NewClientBuilder().WithObjects(deploy).Build()
+	assert.Equal(t, "v0.1.0", getAgentVersion(c, "kubeprober"))
+	assert.Equal(t, "", getAgentVersion(c, "default"))
+
+	for image, version := range map[string]string{
+		"probe-agent":                            "probe-agent",
+		"registry.example.com:5000/probe-agent":  "registry.example.com:5000/probe-agent",
+		"probe-agent@sha256:0123abcd":            "sha256:0123abcd",
+		"probe-agent:v0.1.0@sha256:0123abcd":     "v0.1.0",
+		"registry.example.com:5000/p@sha256:0ab": "sha256:0ab",
+	} {
+		deploy.Spec.Template.Spec.Containers[1].Image = image
+		c = fake.NewClientBuilder().WithObjects(deploy.DeepCopy()).Build()
+		assert.Equal(t, version, getAgentVersion(c, "kubeprober"), image)
+	}
+}
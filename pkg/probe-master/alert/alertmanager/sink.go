@@ -0,0 +1,228 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const (
+	alertName     = "KubeProberCheckerFailed"
+	flushInterval = 5 * time.Second
+	// failed flushes are retried with exponential backoff up to maxBackoff
+	maxBackoff = 5 * time.Minute
+)
+
+// Sink forwards checker failures to Alertmanager v2 api, alerts are resolved
+// when checkers pass again, or expire after ttl without new results
+type Sink struct {
+	sync.Mutex
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	// status of checkers firing in Alertmanager, keyed by checker key
+	firing map[string]kubeproberv1.CheckerStatus
+	// latest alert of every label set, keyed by checker key and severity
+	pending map[string]apistructs.AlertmanagerPostableAlert
+}
+
+func NewSink(url string, ttl time.Duration) *Sink {
+	return &Sink{
+		url:     strings.TrimSuffix(url, "/") + "/api/v2/alerts",
+		ttl:     ttl,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		firing:  make(map[string]kubeproberv1.CheckerStatus),
+		pending: make(map[string]apistructs.AlertmanagerPostableAlert),
+	}
+}
+
+// Start posts pending alerts to Alertmanager until ctx is done
+func (s *Sink) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		var retryAt time.Time
+		var backoff time.Duration
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if now.Before(retryAt) {
+					continue
+				}
+				if err := s.Flush(ctx); err != nil {
+					backoff = nextBackoff(backoff)
+					retryAt = now.Add(backoff)
+					klog.Errorf("[alertmanager] failed to post alerts, retry in %s: %+v\n", backoff, err)
+					continue
+				}
+				backoff = 0
+			}
+		}
+	}()
+}
+
+// Receive queues a firing alert for ERROR/WARN results, and a resolved alert
+// for PASS results of firing checkers
+func (s *Sink) Receive(r *apistructs.CheckerResult) {
+	now := r.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	key := r.Key()
+
+	s.Lock()
+	defer s.Unlock()
+	firing, ok := s.firing[key]
+	switch r.Status {
+	case kubeproberv1.CheckerStatusError, kubeproberv1.CheckerStatusWARN:
+		// severity is a label, resolve the alert of previous severity
+		if ok && firing != r.Status {
+			s.resolve(r, firing, now)
+		}
+		s.firing[key] = r.Status
+		s.queue(r, postableAlert(r, r.Status, now, now.Add(s.ttl)))
+	case kubeproberv1.CheckerStatusPass:
+		if ok {
+			delete(s.firing, key)
+			s.resolve(r, firing, now)
+		}
+	}
+}
+
+// resolve queues the resolved alert, labels must be the same as the firing one
+func (s *Sink) resolve(r *apistructs.CheckerResult, firing kubeproberv1.CheckerStatus, now time.Time) {
+	// startsAt is ignored by Alertmanager for existing alerts
+	s.queue(r, postableAlert(r, firing, now, now))
+}
+
+// queue replaces the pending alert of the same label set, Alertmanager only needs the latest one
+func (s *Sink) queue(r *apistructs.CheckerResult, a apistructs.AlertmanagerPostableAlert) {
+	s.pending[r.Key()+"/"+a.Labels["severity"]] = a
+}
+
+// Flush posts pending alerts, failed alerts are kept and retried in next flush
+// unless newer alerts of the same label set are received
+func (s *Sink) Flush(ctx context.Context) error {
+	s.Lock()
+	pending := s.pending
+	s.pending = make(map[string]apistructs.AlertmanagerPostableAlert)
+	s.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(pending))
+	for k := range pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	alerts := make([]apistructs.AlertmanagerPostableAlert, 0, len(keys))
+	for _, k := range keys {
+		alerts = append(alerts, pending[k])
+	}
+	err := s.post(ctx, alerts)
+	if err == nil {
+		return nil
+	}
+
+	s.Lock()
+	for k, a := range pending {
+		if _, ok := s.pending[k]; !ok {
+			s.pending[k] = a
+		}
+	}
+	s.Unlock()
+	return err
+}
+
+// nextBackoff doubles the backoff from flushInterval to maxBackoff
+func nextBackoff(d time.Duration) time.Duration {
+	if d < flushInterval {
+		return flushInterval
+	}
+	if d *= 2; d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func (s *Sink) post(ctx context.Context, alerts []apistructs.AlertmanagerPostableAlert) error {
+	b, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("unexpected status code %d, body: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func postableAlert(r *apistructs.CheckerResult, status kubeproberv1.CheckerStatus, startsAt,
+	endsAt time.Time) apistructs.AlertmanagerPostableAlert {
+	return apistructs.AlertmanagerPostableAlert{
+		Labels: map[string]string{
+			"alertname": alertName,
+			"source":    "kubeprober",
+			"cluster":   r.Cluster,
+			"probe":     r.Probe,
+			"checker":   r.Checker,
+			"severity":  severity(status),
+		},
+		Annotations: map[string]string{
+			"summary":     r.Key() + " " + string(r.Status),
+			"description": r.Message,
+		},
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+	}
+}
+
+// severity maps checker status to the severity convention of Alertmanager
+func severity(status kubeproberv1.CheckerStatus) string {
+	switch status {
+	case kubeproberv1.CheckerStatusError:
+		return "critical"
+	case kubeproberv1.CheckerStatusWARN:
+		return "warning"
+	}
+	return "info"
+}
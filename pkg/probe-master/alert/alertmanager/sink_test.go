@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func TestSink(t *testing.T) {
+	var posted [][]apistructs.AlertmanagerPostableAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/api/v2/alerts", req.URL.Path)
+		var alerts []apistructs.AlertmanagerPostableAlert
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&alerts))
+		posted = append(posted, alerts)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSink(srv.URL+"/", time.Hour)
+	result := func(status kubeproberv1.CheckerStatus) *apistructs.CheckerResult {
+		return &apistructs.CheckerResult{Cluster: "c1", Probe: "k8s", Checker: "dns", Status: status, Time: now}
+	}
+
+	// pass results of checkers not firing are not posted
+	s.Receive(result(kubeproberv1.CheckerStatusPass))
+	assert.NoError(t, s.Flush(ctx))
+	assert.Len(t, posted, 0)
+
+	s.Receive(result(kubeproberv1.CheckerStatusError))
+	assert.NoError(t, s.Flush(ctx))
+	assert.Len(t, posted, 1)
+	a := posted[0][0]
+	assert.Equal(t, "c1", a.Labels["cluster"])
+	assert.Equal(t, "dns", a.Labels["checker"])
+	assert.Equal(t, "critical", a.Labels["severity"])
+	assert.Equal(t, now.Add(time.Hour), a.EndsAt.UTC())
+
+	// severity changes resolve the previous alert, only the latest alert of a label set is posted
+	s.Receive(result(kubeproberv1.CheckerStatusWARN))
+	s.Receive(result(kubeproberv1.CheckerStatusPass))
+	assert.NoError(t, s.Flush(ctx))
+	assert.Len(t, posted[1], 2)
+	assert.Equal(t, "critical", posted[1][0].Labels["severity"])
+	assert.Equal(t, now, posted[1][0].EndsAt.UTC())
+	assert.Equal(t, "warning", posted[1][1].Labels["severity"])
+	assert.Equal(t, now, posted[1][1].EndsAt.UTC())
+}
+
+func TestSinkRetry(t *testing.T) {
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if fail {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewSink(srv.URL, time.Hour)
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		s.Receive(&apistructs.CheckerResult{Cluster: "c1", Status: kubeproberv1.CheckerStatusError, Time: now.Add(time.Duration(i) * time.Minute)})
+		assert.Error(t, s.Flush(context.Background()))
+	}
+	// pending alerts do not grow while Alertmanager is down
+	assert.Len(t, s.pending, 1)
+	for _, a := range s.pending {
+		assert.Equal(t, now.Add(9*time.Minute+time.Hour), a.EndsAt)
+	}
+
+	fail = false
+	assert.NoError(t, s.Flush(context.Background()))
+	assert.Len(t, s.pending, 0)
+}
+
+func TestNextBackoff(t *testing.T) {
+	assert.Equal(t, flushInterval, nextBackoff(0))
+	assert.Equal(t, 2*flushInterval, nextBackoff(flushInterval))
+	assert.Equal(t, maxBackoff, nextBackoff(4*time.Minute))
+	assert.Equal(t, maxBackoff, nextBackoff(maxBackoff))
+}
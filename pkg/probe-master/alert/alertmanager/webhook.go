@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"strings"
+
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+// labels of Alertmanager alerts mapped to node alert fields, in priority order
+var (
+	clusterLabels   = []string{"cluster", "cluster_name"}
+	nodeLabels      = []string{"node", "nodename", "hostname", "instance"}
+	componentLabels = []string{"component", "container", "pod", "job"}
+	levelLabels     = []string{"severity", "level"}
+	messageKeys     = []string{"description", "message", "summary"}
+)
+
+// NodeAlert is an Alertmanager alert mapped to the fields of node alerts
+type NodeAlert struct {
+	apistructs.AlertRecord
+	Resolved bool
+}
+
+// ParseWebhook maps the alerts of Alertmanager webhook payload to node alerts,
+// labels of every alert override common labels
+func ParseWebhook(msg *apistructs.AlertmanagerWebhookMessage) []NodeAlert {
+	var alerts []NodeAlert
+	for _, a := range msg.Alerts {
+		labels := merge(msg.CommonLabels, a.Labels)
+		annotations := merge(msg.CommonAnnotations, a.Annotations)
+		n := NodeAlert{
+			AlertRecord: apistructs.AlertRecord{
+				Cluster:   first(labels, clusterLabels),
+				Node:      stripPort(first(labels, nodeLabels)),
+				Type:      labels["alertname"],
+				Component: first(labels, componentLabels),
+				Level:     strings.ToLower(first(labels, levelLabels)),
+				Message:   first(annotations, messageKeys),
+				Time:      a.StartsAt,
+			},
+			Resolved: a.Status == "resolved",
+		}
+		if n.Resolved && !a.EndsAt.IsZero() {
+			n.Time = a.EndsAt
+		}
+		alerts = append(alerts, n)
+	}
+	return alerts
+}
+
+func merge(common, labels map[string]string) map[string]string {
+	m := make(map[string]string, len(common)+len(labels))
+	for k, v := range common {
+		m[k] = v
+	}
+	for k, v := range labels {
+		m[k] = v
+	}
+	return m
+}
+
+func first(m map[string]string, keys []string) string {
+	for _, k := range keys {
+		if v := m[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// stripPort removes the port of instance label like 10.0.0.1:9100
+func stripPort(instance string) string {
+	if i := strings.LastIndex(instance, ":"); i > 0 && !strings.Contains(instance[i+1:], "]") {
+		return instance[:i]
+	}
+	return instance
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const webhookPayload = `{
+  "version": "4",
+  "status": "firing",
+  "receiver": "kubeprober",
+  "commonLabels": {"cluster": "prod", "severity": "warning"},
+  "commonAnnotations": {"summary": "node problem"},
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "NodeDiskFull", "instance": "10.0.0.1:9100", "severity": "Critical"},
+      "annotations": {"description": "disk usage 95%"},
+      "startsAt": "2021-08-01T00:00:00Z"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "KubeletDown", "node": "node-2", "job": "kubelet"},
+      "startsAt": "2021-08-01T00:00:00Z",
+      "endsAt": "2021-08-01T01:00:00Z"
+    }
+  ]
+}`
+
+func TestParseWebhook(t *testing.T) {
+	msg := &apistructs.AlertmanagerWebhookMessage{}
+	assert.NoError(t, json.Unmarshal([]byte(webhookPayload), msg))
+
+	alerts := ParseWebhook(msg)
+	assert.Len(t, alerts, 2)
+
+	assert.Equal(t, "prod", alerts[0].Cluster)
+	assert.Equal(t, "10.0.0.1", alerts[0].Node)
+	assert.Equal(t, "NodeDiskFull", alerts[0].Type)
+	assert.Equal(t, "critical", alerts[0].Level)
+	assert.Equal(t, "disk usage 95%", alerts[0].Message)
+	assert.False(t, alerts[0].Resolved)
+
+	assert.Equal(t, "node-2", alerts[1].Node)
+	assert.Equal(t, "kubelet", alerts[1].Component)
+	assert.Equal(t, "warning", alerts[1].Level)
+	assert.Equal(t, "node problem", alerts[1].Message)
+	assert.True(t, alerts[1].Resolved)
+	assert.Equal(t, 1, alerts[1].Time.Hour())
+}
@@ -14,15 +14,9 @@
 package dingding
 
 import (
-	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -37,9 +31,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
-	"github.com/erda-project/kubeprober/apistructs"
 	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
 	_ "github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
 )
 
 const DINGDING_ALERT_NAME = "dingding"
@@ -47,7 +41,6 @@ const DINGDING_ALERT_NAME = "dingding"
 var (
 	dingdingAlert *kubeproberv1.Alert
 	ci            = make(chan int, 100)
-	sendMsgCh     = make(chan string, 100)
 )
 
 type alertStruct struct {
@@ -79,7 +72,6 @@ type AlertItemStuct struct {
 
 func init() {
 	var proxyCount = 0
-	var sendMsg = ""
 
 	// init dingding alert
 	if err := getDingDingAlert(); err != nil {
@@ -88,7 +80,6 @@ func init() {
 
 	go func() {
 		dingTicker := time.NewTicker(60 * time.Second)
-		senderTicker := time.NewTicker(10 * time.Second)
 		for {
 			select {
 			case <-ci:
@@ -105,69 +96,32 @@ func init() {
 					}
 					proxyCount = 0
 				}
-			case msg := <-sendMsgCh:
-				sendMsg = sendMsg + fmt.Sprintf("%s", msg)
-			case <-senderTicker.C:
-				if sendMsg != "" {
-					if err := sendAlertAfterAggregation(sendMsg); err != nil {
-						klog.Errorf("failed to send dingding proxy: %+v\n", err)
-					}
-				}
-				sendMsg = ""
 			}
 		}
 	}()
 }
 
-func CheckBlacklist(alertStr string) bool {
-	if dingdingAlert == nil {
-		return true
-	}
-
-	ignore := false
-	// ignore if in black list
-	for _, word := range dingdingAlert.Spec.BlackList {
-		if strings.Contains(alertStr, word) {
-			fmt.Printf("ignore alert, keywork: %s, alert: %s\n", word, alertStr)
-			ignore = true
-			break
-		}
-	}
-
-	return ignore
-}
-
 func ProxyAlert(w http.ResponseWriter, r *http.Request) {
 	if dingdingAlert == nil || dingdingAlert.Spec.Address == "" {
 		return
 	}
 
 	u, _ := url.Parse(dingdingAlert.Spec.Address)
-	fmt.Printf("forwarding to -> %s, blacklist: %v\n", u, dingdingAlert.Spec.BlackList)
+	fmt.Printf("forwarding to -> %s\n", u)
 	proxy := NewProxy(u)
 	proxy.Transport = &DebugTransport{}
 	proxy.ServeHTTP(w, r)
 }
 
-func SendAlert(ps *apistructs.CollectProbeStatusReq) error {
-	if dingdingAlert == nil || dingdingAlert.Spec.Token == "" || dingdingAlert.Spec.Sign == "" {
-		return nil
-	}
-
-	istr := "[类别]: " + ps.ProbeName + "\n" +
-		"[检查项]：" + ps.CheckerName + "\n" +
-		"[集群]：" + ps.ClusterName + "\n" +
-		"[状态]: " + string(ps.Status) + "\n" +
-		"[错误信息]: " + ps.Message + "\n\n"
-	sendMsgCh <- istr
-	return nil
+// CountAlert counts an emitted alert into the daily alert count of dingding Alert
+func CountAlert() {
+	ci <- 1
 }
 
 func ParseAlert(alertStr string) (*AlertItemStuct, error) {
 	asItem := &AlertItemStuct{}
 
 	if !strings.Contains(alertStr, "恢复") {
-		ci <- 1
 		asItem.Status = AlertEmit
 	} else {
 		asItem.Status = AlertRecover
@@ -224,21 +178,20 @@ func alertCount(count int) error {
 
 	var err error
 	now := time.Now()
-	loc, _ := time.LoadLocation("Asia/Shanghai")
-	nowDay := now.In(loc).Format("2006-01-02")
+	nowDay := timezone.Day(now)
 	if dingdingAlert.Status.AlertCount == nil {
 		dingdingAlert.Status.AlertCount = make(map[string]int)
 	}
 	dingdingAlert.Status.AlertCount[nowDay] = dingdingAlert.Status.AlertCount[nowDay] + count
 
 	if len(dingdingAlert.Status.AlertCount) > 200 {
-		deleteDay := now.AddDate(0, 0, -200).Format("2006-01-02")
+		deleteDay := timezone.Day(now.AddDate(0, 0, -200))
 		delete(dingdingAlert.Status.AlertCount, deleteDay)
 	}
-	statusPatchBody := kubeproberv1.Alert{
-		Status: dingdingAlert.Status,
-	}
-	statusPatch, _ := json.Marshal(statusPatchBody)
+	// only patch alertCount, other status fields are updated by others
+	statusPatch, _ := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"alertCount": dingdingAlert.Status.AlertCount},
+	})
 	err = k8sclient.RestClient.Status().Patch(context.Background(), &kubeproberv1.Alert{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      DINGDING_ALERT_NAME,
@@ -292,107 +245,3 @@ func singleJoiningSlash(a, b string) string {
 	}
 	return a + b
 }
-
-func sendAlertAfterAggregation(msg string) error {
-	var err error
-	var signUrl string
-	var resp *http.Response
-	var result []byte
-
-	if dingdingAlert == nil {
-		return nil
-	}
-	if dingdingAlert.Spec.Address == "" || dingdingAlert.Spec.Token == "" || dingdingAlert.Spec.Sign == "" {
-		return errors.New("address or token or sign is emtpy in this alert spec")
-	}
-
-	addr := fmt.Sprintf("%s/robot/send?access_token=%s", dingdingAlert.Spec.Address, dingdingAlert.Spec.Token)
-	if signUrl, err = getSignURL(addr, dingdingAlert.Spec.Sign); err != nil {
-		return err
-	}
-
-	content, data := make(map[string]string), make(map[string]interface{})
-	content["content"] = substrByByte(msg, 1800)
-	data["msgtype"] = "text"
-	data["text"] = content
-	b, _ := json.Marshal(data)
-
-	body := bytes.NewBuffer(b)
-
-	resp, err = http.Post(signUrl, "application/json;charset=utf-8", body)
-	if err != nil {
-		klog.Errorf("faile to send msg to dingding: %+v\n", err)
-		return err
-	}
-	result, err = ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		klog.Errorf("faile to send msg to dingding: %+v\n", err)
-		return err
-	}
-	klog.Infof("dingding return msg: %s\n", result)
-	return nil
-}
-
-func getSignURL(addr string, sign string) (string, error) {
-	if sign == "" {
-		return "", nil
-	}
-
-	tm := time.Now().UnixNano() / 1e6
-	strToHash := fmt.Sprintf("%d\n%s", tm, sign)
-	hmac256 := hmac.New(sha256.New, []byte(sign))
-	hmac256.Write([]byte(strToHash))
-	data := hmac256.Sum(nil)
-	dataStr := base64.StdEncoding.EncodeToString(data)
-
-	u, err := url.Parse(addr)
-	if err != nil {
-		return "", err
-	}
-	values := u.Query()
-	values.Set("timestamp", fmt.Sprintf("%d", tm))
-	values.Set("sign", dataStr)
-	u.RawQuery = values.Encode()
-
-	return u.String(), nil
-}
-
-func substrByByte(str string, length int) string {
-	var bs []byte
-	s := []byte(str)
-	if len(s) > length {
-		bs = s[:length]
-	} else {
-		bs = s
-	}
-
-	bl := 0
-	for i := len(bs) - 1; i >= 0; i-- {
-		switch {
-		case bs[i] >= 0 && bs[i] <= 127:
-			return string(bs[:i+1])
-		case bs[i] >= 128 && bs[i] <= 191:
-			bl++
-		case bs[i] >= 192 && bs[i] <= 253:
-			cl := 0
-			switch {
-			case bs[i]&252 == 252:
-				cl = 6
-			case bs[i]&248 == 248:
-				cl = 5
-			case bs[i]&240 == 240:
-				cl = 4
-			case bs[i]&224 == 224:
-				cl = 3
-			default:
-				cl = 2
-			}
-			if bl+1 == cl {
-				return string(bs[:i+cl])
-			}
-			return string(bs[:i])
-		}
-	}
-	return ""
-}
@@ -0,0 +1,617 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/notifier"
+)
+
+const (
+	refreshInterval = 60 * time.Second
+	tickInterval    = 5 * time.Second
+	// resolved alerts are kept for this duration before removed from state
+	resolvedRetention = 24 * time.Hour
+)
+
+// ErrNotFound is returned when acknowledging an unknown alert
+var ErrNotFound = errors.New("alert not found")
+
+var DefaultConfig = apistructs.AlertConfig{
+	GroupWait:      30 * time.Second,
+	GroupInterval:  5 * time.Minute,
+	RepeatInterval: 4 * time.Hour,
+	ResolveTimeout: 24 * time.Hour,
+}
+
+// Router decides the receivers of checker results
+type Router interface {
+	Refresh(ctx context.Context) error
+	Match(r *apistructs.CheckerResult) *apistructs.AlertRouteDecision
+}
+
+// Receivers provides the notifiers of receivers
+type Receivers interface {
+	Refresh(ctx context.Context) error
+	Get(name string) notifier.Notifier
+	List() []notifier.Notifier
+}
+
+// Silencer mutes notifications of alerts
+type Silencer interface {
+	// Silenced returns the names of active silences matching the alert
+	Silenced(cluster, probe, checker string, status kubeproberv1.CheckerStatus, now time.Time) []string
+}
+
+// Recorder records alerts sent to receivers for alert statistics
+type Recorder interface {
+	WriteNotification(n *apistructs.AlertNotification) error
+}
+
+// group is the pending changes of alerts of one receiver
+type group struct {
+	// keys of alerts changed since last notification
+	pending      map[string]bool
+	firstPending time.Time
+	lastFlush    time.Time
+}
+
+// Manager keeps the firing/resolved state of alerts, and notifies receivers in groups
+type Manager struct {
+	sync.Mutex
+	cfg       apistructs.AlertConfig
+	router    Router
+	receivers Receivers
+	silencer  Silencer
+	persister Persister
+	recorder  Recorder
+
+	states  map[string]*apistructs.AlertState
+	groups  map[string]*group
+	dirty   bool
+	results chan apistructs.CheckerResult
+}
+
+func New(cfg apistructs.AlertConfig, router Router, receivers Receivers, silencer Silencer, persister Persister) *Manager {
+	return &Manager{
+		cfg:       withDefaults(cfg),
+		router:    router,
+		receivers: receivers,
+		silencer:  silencer,
+		persister: persister,
+		states:    make(map[string]*apistructs.AlertState),
+		groups:    make(map[string]*group),
+		results:   make(chan apistructs.CheckerResult, 1000),
+	}
+}
+
+// SetRecorder sets the recorder of sent alerts, sent alerts are not recorded if it is nil
+func (m *Manager) SetRecorder(r Recorder) {
+	m.Lock()
+	defer m.Unlock()
+	m.recorder = r
+}
+
+func withDefaults(cfg apistructs.AlertConfig) apistructs.AlertConfig {
+	if cfg.GroupWait <= 0 {
+		cfg.GroupWait = DefaultConfig.GroupWait
+	}
+	if cfg.GroupInterval <= 0 {
+		cfg.GroupInterval = DefaultConfig.GroupInterval
+	}
+	if cfg.RepeatInterval <= 0 {
+		cfg.RepeatInterval = DefaultConfig.RepeatInterval
+	}
+	if cfg.ResolveTimeout <= 0 {
+		cfg.ResolveTimeout = DefaultConfig.ResolveTimeout
+	}
+	return cfg
+}
+
+// Start restores persisted alert states, then processes checker results and
+// notifies receivers until ctx is done
+func (m *Manager) Start(ctx context.Context) {
+	m.refresh(ctx)
+	if m.persister != nil {
+		states, err := m.persister.Load(ctx)
+		if err != nil {
+			klog.Errorf("[alert] failed to load alert states: %+v\n", err)
+		}
+		m.Restore(states)
+	}
+
+	go func() {
+		refreshTicker := time.NewTicker(refreshInterval)
+		defer refreshTicker.Stop()
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-refreshTicker.C:
+				m.refresh(ctx)
+			case r := <-m.results:
+				m.Process(&r)
+			case <-ticker.C:
+				m.Flush(ctx, time.Now())
+				m.save(ctx)
+			}
+		}
+	}()
+}
+
+func (m *Manager) refresh(ctx context.Context) {
+	if err := m.receivers.Refresh(ctx); err != nil {
+		klog.Errorf("[alert] failed to load alert receivers: %+v\n", err)
+	}
+	if err := m.router.Refresh(ctx); err != nil {
+		klog.Errorf("[alert] failed to load alert routes: %+v\n", err)
+	}
+}
+
+// Receive queues a checker result of any status, results are processed in background
+func (m *Manager) Receive(r *apistructs.CheckerResult) {
+	select {
+	case m.results <- *r:
+	default:
+		klog.Errorf("[alert] result queue is full, drop result of %s\n", r.Key())
+	}
+}
+
+// Restore replaces alert states with persisted ones, receivers notified before
+// restart are not notified again until repeat interval
+func (m *Manager) Restore(states []apistructs.AlertState) {
+	m.Lock()
+	defer m.Unlock()
+	m.states = make(map[string]*apistructs.AlertState)
+	for i := range states {
+		s := states[i]
+		if s.Fingerprint == "" {
+			s.Fingerprint = apistructs.Fingerprint("checker", s.Cluster, s.Probe, s.Checker)
+		}
+		m.states[s.Key()] = &s
+	}
+}
+
+// Process updates the alert state of the checker of result
+func (m *Manager) Process(r *apistructs.CheckerResult) {
+	now := r.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	d := m.router.Match(r)
+	receivers := m.expand(d)
+
+	m.Lock()
+	defer m.Unlock()
+	key := r.Key()
+	s, ok := m.states[key]
+
+	// only pass result resolves the firing alert
+	if r.Status == kubeproberv1.CheckerStatusPass {
+		// keep status and message of the firing alert
+		if ok && s.State == apistructs.AlertFiring {
+			s.State = apistructs.AlertResolved
+			s.EndsAt = now
+			s.LastSeen = now
+			m.markResolved(s, now)
+		}
+		return
+	}
+
+	// the result is not alerted to any receiver, e.g. ERROR turns to WARN under the default route,
+	// the checker is not recovered yet, so keep the firing alert without notifying the change
+	if len(receivers) == 0 {
+		if ok && s.State == apistructs.AlertFiring {
+			s.Status = r.Status
+			s.Message = r.Message
+			s.LastSeen = now
+			m.dirty = true
+		}
+		return
+	}
+
+	if !ok || s.State == apistructs.AlertResolved {
+		s = &apistructs.AlertState{
+			Cluster:     r.Cluster,
+			Probe:       r.Probe,
+			Checker:     r.Checker,
+			Fingerprint: r.Fingerprint(),
+			State:       apistructs.AlertFiring,
+			Status:      r.Status,
+			Message:     r.Message,
+			StartsAt:    now,
+			LastSeen:    now,
+		}
+		m.states[key] = s
+		s.Receivers = receivers
+		s.Tiers = d.Escalations
+		m.mark(key, receivers, now)
+		return
+	}
+
+	// keep notifying the receivers escalated to
+	for _, e := range s.Escalations {
+		for _, receiver := range e.Receivers {
+			if !contains(receivers, receiver) {
+				receivers = append(receivers, receiver)
+			}
+		}
+	}
+
+	// severity changed or new receivers are routed, notify them with group interval
+	var changed []string
+	for _, receiver := range receivers {
+		if s.Status != r.Status || !contains(s.Receivers, receiver) {
+			changed = append(changed, receiver)
+		}
+	}
+	s.Status = r.Status
+	s.Message = r.Message
+	s.LastSeen = now
+	s.Receivers = receivers
+	s.Tiers = d.Escalations
+	m.mark(key, changed, now)
+	m.dirty = true
+}
+
+// Ack acknowledges the firing alert of fingerprint or cluster/probe/checker key,
+// acknowledged alerts are not escalated or repeated until they resolve
+func (m *Manager) Ack(id string, ack apistructs.AlertAck) (*apistructs.AlertState, error) {
+	m.Lock()
+	defer m.Unlock()
+	for _, s := range m.states {
+		if s.Fingerprint != id && s.Key() != id {
+			continue
+		}
+		if s.State != apistructs.AlertFiring {
+			return nil, errors.Errorf("alert %s is %s", id, s.State)
+		}
+		if ack.Time.IsZero() {
+			ack.Time = time.Now()
+		}
+		s.Ack = &ack
+		m.dirty = true
+		c := copyState(s)
+		return &c, nil
+	}
+	return nil, ErrNotFound
+}
+
+// escalate adds receivers of the tiers due to the firing alert, unless it is acknowledged or silenced
+func (m *Manager) escalate(s *apistructs.AlertState, now time.Time) {
+	if s.Ack != nil || s.Status != kubeproberv1.CheckerStatusError || len(s.Escalations) >= len(s.Tiers) {
+		return
+	}
+	if len(m.silenced(s, now)) > 0 {
+		return
+	}
+	for i := len(s.Escalations); i < len(s.Tiers); i++ {
+		tier := s.Tiers[i]
+		if now.Sub(s.StartsAt) < tier.After {
+			return
+		}
+		klog.Infof("[alert] alert %s is not acknowledged in %s, escalate to %v\n", s.Key(), tier.After, tier.Receivers)
+		s.Escalations = append(s.Escalations, apistructs.AlertEscalation{
+			Tier:      i,
+			Receivers: tier.Receivers,
+			Time:      now,
+		})
+		for _, receiver := range tier.Receivers {
+			if !contains(s.Receivers, receiver) {
+				s.Receivers = append(s.Receivers, receiver)
+			}
+		}
+		m.dirty = true
+	}
+}
+
+// expand expands the route decision to receiver names
+func (m *Manager) expand(d *apistructs.AlertRouteDecision) []string {
+	if !d.All {
+		return d.Receivers
+	}
+	var names []string
+	for _, n := range m.receivers.List() {
+		names = append(names, n.Name())
+	}
+	return names
+}
+
+// mark adds alert of key to the pending changes of receivers
+func (m *Manager) mark(key string, receivers []string, now time.Time) {
+	m.dirty = true
+	for _, receiver := range receivers {
+		g, ok := m.groups[receiver]
+		if !ok {
+			g = &group{pending: make(map[string]bool)}
+			m.groups[receiver] = g
+		}
+		if len(g.pending) == 0 {
+			g.firstPending = now
+		}
+		g.pending[key] = true
+	}
+}
+
+// markResolved notifies the receivers which got the firing alert of the recovery
+func (m *Manager) markResolved(s *apistructs.AlertState, now time.Time) {
+	var receivers []string
+	for receiver := range s.Notified {
+		receivers = append(receivers, receiver)
+	}
+	sort.Strings(receivers)
+	m.mark(s.Key(), receivers, now)
+}
+
+type notification struct {
+	receiver string
+	firing   []apistructs.AlertState
+	resolved []apistructs.AlertState
+}
+
+// Flush resolves stale alerts, and notifies the receivers whose groups are due
+func (m *Manager) Flush(ctx context.Context, now time.Time) {
+	notifications := m.due(now)
+	for _, n := range notifications {
+		err := m.notify(ctx, n)
+		m.Lock()
+		if err != nil {
+			// keep pending changes, retry after group interval
+			m.groups[n.receiver].lastFlush = now
+		} else {
+			m.notified(n, now)
+			m.record(n, now)
+		}
+		m.Unlock()
+	}
+	m.prune(now)
+}
+
+func (m *Manager) due(now time.Time) []*notification {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, s := range m.states {
+		if s.State != apistructs.AlertFiring {
+			continue
+		}
+		if now.Sub(s.LastSeen) >= m.cfg.ResolveTimeout {
+			s.State = apistructs.AlertResolved
+			s.EndsAt = now
+			m.markResolved(s, now)
+			continue
+		}
+		m.escalate(s, now)
+		// notify again after repeat interval unless acknowledged, or after silences of the alert expired
+		var repeat []string
+		for _, receiver := range s.Receivers {
+			t, notified := s.Notified[receiver]
+			if notified && s.Ack == nil && now.Sub(t) >= m.cfg.RepeatInterval || !notified && !m.isPending(receiver, s.Key()) {
+				repeat = append(repeat, receiver)
+			}
+		}
+		m.mark(s.Key(), repeat, now)
+	}
+
+	var notifications []*notification
+	for receiver, g := range m.groups {
+		if len(g.pending) == 0 {
+			continue
+		}
+		// group_wait applies to the first changes of an idle group, later
+		// changes are notified every group_interval
+		next := g.lastFlush.Add(m.cfg.GroupInterval)
+		if g.firstPending.After(next) {
+			next = g.firstPending.Add(m.cfg.GroupWait)
+		}
+		if now.Before(next) {
+			continue
+		}
+		n := &notification{receiver: receiver}
+		for key := range g.pending {
+			s, ok := m.states[key]
+			if !ok {
+				continue
+			}
+			if silences := m.silenced(s, now); len(silences) > 0 {
+				klog.V(2).Infof("[alert] alert %s to %s is silenced by %v\n", key, receiver, silences)
+				delete(g.pending, key)
+				continue
+			}
+			switch {
+			case s.State == apistructs.AlertFiring && contains(s.Receivers, receiver):
+				n.firing = append(n.firing, *s)
+			case s.State == apistructs.AlertResolved:
+				if _, notified := s.Notified[receiver]; notified {
+					n.resolved = append(n.resolved, *s)
+				}
+			}
+		}
+		if len(n.firing) == 0 && len(n.resolved) == 0 {
+			g.pending = make(map[string]bool)
+			continue
+		}
+		sortStates(n.firing)
+		sortStates(n.resolved)
+		notifications = append(notifications, n)
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].receiver < notifications[j].receiver
+	})
+	return notifications
+}
+
+func (m *Manager) isPending(receiver, key string) bool {
+	g, ok := m.groups[receiver]
+	return ok && g.pending[key]
+}
+
+func (m *Manager) silenced(s *apistructs.AlertState, now time.Time) []string {
+	if m.silencer == nil {
+		return nil
+	}
+	return m.silencer.Silenced(s.Cluster, s.Probe, s.Checker, s.Status, now)
+}
+
+func (m *Manager) notify(ctx context.Context, n *notification) error {
+	nt := m.receivers.Get(n.receiver)
+	if nt == nil {
+		klog.Errorf("[alert] receiver %s of %d alerts is not found\n", n.receiver, len(n.firing)+len(n.resolved))
+		return nil
+	}
+	if err := nt.Notify(ctx, buildMessage(nt, n.firing, n.resolved)); err != nil {
+		klog.Errorf("[alert] failed to send alert to %s receiver %s: %+v\n", nt.Type(), nt.Name(), err)
+		return err
+	}
+	return nil
+}
+
+// notified records the notification time, and clears the sent changes
+func (m *Manager) notified(n *notification, now time.Time) {
+	g := m.groups[n.receiver]
+	g.lastFlush = now
+	for _, a := range n.firing {
+		s, ok := m.states[a.Key()]
+		if !ok {
+			continue
+		}
+		if s.Notified == nil {
+			s.Notified = make(map[string]time.Time)
+		}
+		s.Notified[n.receiver] = now
+		// keep the change made while sending, e.g. resolved
+		if s.State == a.State && s.Status == a.Status {
+			delete(g.pending, a.Key())
+		}
+	}
+	for _, a := range n.resolved {
+		s, ok := m.states[a.Key()]
+		if !ok || s.State != apistructs.AlertResolved {
+			continue
+		}
+		delete(s.Notified, n.receiver)
+		delete(g.pending, a.Key())
+	}
+	m.dirty = true
+}
+
+// record writes the firing alerts of notification to recorder
+func (m *Manager) record(n *notification, now time.Time) {
+	if m.recorder == nil {
+		return
+	}
+	for _, a := range n.firing {
+		err := m.recorder.WriteNotification(&apistructs.AlertNotification{
+			Cluster:  a.Cluster,
+			Type:     a.Probe,
+			Checker:  a.Checker,
+			Level:    string(a.Status),
+			Receiver: n.receiver,
+			Time:     now,
+		})
+		if err != nil {
+			klog.Errorf("[alert] failed to record alert %s to %s: %+v\n", a.Key(), n.receiver, err)
+		}
+	}
+}
+
+// prune removes resolved alerts after retention
+func (m *Manager) prune(now time.Time) {
+	m.Lock()
+	defer m.Unlock()
+	for key, s := range m.states {
+		if s.State == apistructs.AlertResolved && now.Sub(s.EndsAt) >= resolvedRetention {
+			delete(m.states, key)
+			m.dirty = true
+		}
+	}
+}
+
+func (m *Manager) save(ctx context.Context) {
+	m.Lock()
+	if !m.dirty || m.persister == nil {
+		m.Unlock()
+		return
+	}
+	states := m.list()
+	m.dirty = false
+	m.Unlock()
+
+	if err := m.persister.Save(ctx, states); err != nil {
+		klog.Errorf("[alert] failed to save alert states: %+v\n", err)
+		m.Lock()
+		m.dirty = true
+		m.Unlock()
+	}
+}
+
+// States returns a copy of all alert states ordered by key
+func (m *Manager) States() []apistructs.AlertState {
+	m.Lock()
+	defer m.Unlock()
+	return m.list()
+}
+
+func (m *Manager) list() []apistructs.AlertState {
+	states := make([]apistructs.AlertState, 0, len(m.states))
+	for _, s := range m.states {
+		states = append(states, copyState(s))
+	}
+	sortStates(states)
+	return states
+}
+
+func copyState(s *apistructs.AlertState) apistructs.AlertState {
+	c := *s
+	c.Receivers = append([]string(nil), s.Receivers...)
+	if s.Notified != nil {
+		c.Notified = make(map[string]time.Time, len(s.Notified))
+		for k, v := range s.Notified {
+			c.Notified[k] = v
+		}
+	}
+	c.Escalations = append([]apistructs.AlertEscalation(nil), s.Escalations...)
+	if s.Ack != nil {
+		ack := *s.Ack
+		c.Ack = &ack
+	}
+	return c
+}
+
+func sortStates(states []apistructs.AlertState) {
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Key() < states[j].Key()
+	})
+}
+
+func contains(l []string, s string) bool {
+	for _, i := range l {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
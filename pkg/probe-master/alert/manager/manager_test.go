@@ -0,0 +1,295 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/message"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/notifier"
+)
+
+type fakeRouter struct{}
+
+func (r *fakeRouter) Refresh(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeRouter) Match(result *apistructs.CheckerResult) *apistructs.AlertRouteDecision {
+	if result.Status != kubeproberv1.CheckerStatusError {
+		return &apistructs.AlertRouteDecision{}
+	}
+	return &apistructs.AlertRouteDecision{All: true}
+}
+
+type fakeNotifier struct {
+	messages []*notifier.Message
+}
+
+func (n *fakeNotifier) Name() string {
+	return "ops"
+}
+
+func (n *fakeNotifier) Type() kubeproberv1.ReceiverType {
+	return kubeproberv1.ReceiverTypeWebhook
+}
+
+func (n *fakeNotifier) Template() *message.Template {
+	return message.Default()
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, msg *notifier.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+type fakeReceivers struct {
+	n *fakeNotifier
+}
+
+func (r *fakeReceivers) Refresh(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeReceivers) Get(name string) notifier.Notifier {
+	return r.n
+}
+
+func (r *fakeReceivers) List() []notifier.Notifier {
+	return []notifier.Notifier{r.n}
+}
+
+type fakeSilencer struct {
+	start, end time.Time
+}
+
+func (s *fakeSilencer) Silenced(cluster, probe, checker string, status kubeproberv1.CheckerStatus, now time.Time) []string {
+	if cluster == "c1" && !now.Before(s.start) && now.Before(s.end) {
+		return []string{"upgrade"}
+	}
+	return nil
+}
+
+func result(status kubeproberv1.CheckerStatus, t time.Time) *apistructs.CheckerResult {
+	return &apistructs.CheckerResult{
+		Cluster: "c1",
+		Probe:   "k8s",
+		Checker: "dns",
+		Status:  status,
+		Message: "failed",
+		Time:    t,
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	n := &fakeNotifier{}
+	m := New(apistructs.AlertConfig{
+		GroupWait:      30 * time.Second,
+		GroupInterval:  5 * time.Minute,
+		RepeatInterval: time.Hour,
+	}, &fakeRouter{}, &fakeReceivers{n: n}, nil, nil)
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	// wait for group_wait before the first notification
+	m.Process(result(kubeproberv1.CheckerStatusError, start))
+	m.Flush(ctx, start.Add(10*time.Second))
+	assert.Len(t, n.messages, 0)
+	m.Flush(ctx, start.Add(30*time.Second))
+	assert.Len(t, n.messages, 1)
+	assert.Equal(t, apistructs.AlertFiring, n.messages[0].Alerts[0].State)
+
+	// the same error is deduplicated until repeat interval
+	m.Process(result(kubeproberv1.CheckerStatusError, start.Add(10*time.Minute)))
+	m.Flush(ctx, start.Add(20*time.Minute))
+	assert.Len(t, n.messages, 1)
+	m.Flush(ctx, start.Add(time.Hour+30*time.Second))
+	m.Flush(ctx, start.Add(time.Hour+time.Minute))
+	assert.Len(t, n.messages, 2)
+
+	// pass result resolves the alert after group interval
+	m.Process(result(kubeproberv1.CheckerStatusPass, start.Add(time.Hour+2*time.Minute)))
+	m.Flush(ctx, start.Add(time.Hour+3*time.Minute))
+	assert.Len(t, n.messages, 2)
+	m.Flush(ctx, start.Add(time.Hour+6*time.Minute))
+	assert.Len(t, n.messages, 3)
+	assert.Equal(t, apistructs.AlertResolved, n.messages[2].Alerts[0].State)
+
+	// resolved alerts are not notified again
+	m.Flush(ctx, start.Add(3*time.Hour))
+	assert.Len(t, n.messages, 3)
+}
+
+func TestNotRoutedStatus(t *testing.T) {
+	ctx := context.Background()
+	n := &fakeNotifier{}
+	m := New(apistructs.AlertConfig{
+		GroupInterval:  5 * time.Minute,
+		RepeatInterval: time.Hour,
+	}, &fakeRouter{}, &fakeReceivers{n: n}, nil, nil)
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	m.Process(result(kubeproberv1.CheckerStatusError, start))
+	m.Flush(ctx, start.Add(30*time.Second))
+	assert.Len(t, n.messages, 1)
+
+	// WARN is not routed, the alert keeps firing and no recovery is notified
+	m.Process(result(kubeproberv1.CheckerStatusWARN, start.Add(time.Minute)))
+	m.Flush(ctx, start.Add(10*time.Minute))
+	assert.Len(t, n.messages, 1)
+	assert.Equal(t, apistructs.AlertFiring, m.States()[0].State)
+	assert.Equal(t, kubeproberv1.CheckerStatusWARN, m.States()[0].Status)
+
+	// back to ERROR is notified as a severity change
+	m.Process(result(kubeproberv1.CheckerStatusError, start.Add(11*time.Minute)))
+	m.Flush(ctx, start.Add(20*time.Minute))
+	assert.Len(t, n.messages, 2)
+	assert.Equal(t, apistructs.AlertFiring, n.messages[1].Alerts[0].State)
+
+	// only PASS resolves the alert
+	m.Process(result(kubeproberv1.CheckerStatusPass, start.Add(21*time.Minute)))
+	m.Flush(ctx, start.Add(30*time.Minute))
+	assert.Len(t, n.messages, 3)
+	assert.Equal(t, apistructs.AlertResolved, n.messages[2].Alerts[0].State)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	n := &fakeNotifier{}
+	m := New(apistructs.AlertConfig{RepeatInterval: time.Hour}, &fakeRouter{}, &fakeReceivers{n: n}, nil, nil)
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	m.Restore([]apistructs.AlertState{{
+		Cluster:   "c1",
+		Probe:     "k8s",
+		Checker:   "dns",
+		State:     apistructs.AlertFiring,
+		Status:    kubeproberv1.CheckerStatusError,
+		StartsAt:  start,
+		LastSeen:  start,
+		Receivers: []string{"ops"},
+		Notified:  map[string]time.Time{"ops": start},
+	}})
+	// restored alerts already notified are not notified again after restart
+	m.Process(result(kubeproberv1.CheckerStatusError, start.Add(time.Minute)))
+	m.Flush(ctx, start.Add(10*time.Minute))
+	assert.Len(t, n.messages, 0)
+
+	// silent firing alerts are resolved after resolve timeout
+	m.Flush(ctx, start.Add(25*time.Hour))
+	m.Flush(ctx, start.Add(25*time.Hour+time.Minute))
+	assert.Len(t, n.messages, 1)
+	assert.Equal(t, apistructs.AlertResolved, m.States()[0].State)
+}
+
+func TestSilence(t *testing.T) {
+	ctx := context.Background()
+	n := &fakeNotifier{}
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	m := New(apistructs.AlertConfig{}, &fakeRouter{}, &fakeReceivers{n: n},
+		&fakeSilencer{start: start, end: start.Add(time.Hour)}, nil)
+
+	m.Process(result(kubeproberv1.CheckerStatusError, start))
+	m.Flush(ctx, start.Add(time.Minute))
+	m.Flush(ctx, start.Add(30*time.Minute))
+	assert.Len(t, n.messages, 0)
+
+	// firing alerts are notified after the silence expired
+	m.Flush(ctx, start.Add(time.Hour))
+	m.Flush(ctx, start.Add(time.Hour+time.Minute))
+	assert.Len(t, n.messages, 1)
+}
+
+type fakeRecorder struct {
+	notifications []apistructs.AlertNotification
+}
+
+func (r *fakeRecorder) WriteNotification(n *apistructs.AlertNotification) error {
+	r.notifications = append(r.notifications, *n)
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	n := &fakeNotifier{}
+	rec := &fakeRecorder{}
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	m := New(apistructs.AlertConfig{}, &fakeRouter{}, &fakeReceivers{n: n}, nil, nil)
+	m.SetRecorder(rec)
+
+	m.Process(result(kubeproberv1.CheckerStatusError, start))
+	m.Flush(ctx, start.Add(time.Minute))
+	// recoveries are not counted
+	m.Process(result(kubeproberv1.CheckerStatusPass, start.Add(2*time.Minute)))
+	m.Flush(ctx, start.Add(10*time.Minute))
+	assert.Len(t, n.messages, 2)
+	if assert.Len(t, rec.notifications, 1) {
+		assert.Equal(t, "ERROR", rec.notifications[0].Level)
+		assert.Equal(t, n.Name(), rec.notifications[0].Receiver)
+	}
+}
+
+type escalationRouter struct {
+	fakeRouter
+}
+
+func (r *escalationRouter) Match(result *apistructs.CheckerResult) *apistructs.AlertRouteDecision {
+	if result.Status != kubeproberv1.CheckerStatusError {
+		return &apistructs.AlertRouteDecision{}
+	}
+	return &apistructs.AlertRouteDecision{
+		Receivers: []string{"ops"},
+		Escalations: []apistructs.AlertEscalationTier{
+			{After: 10 * time.Minute, Receivers: []string{"manager"}},
+			{After: 30 * time.Minute, Receivers: []string{"director"}},
+		},
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	ctx := context.Background()
+	n := &fakeNotifier{}
+	m := New(apistructs.AlertConfig{RepeatInterval: time.Hour}, &escalationRouter{}, &fakeReceivers{n: n}, nil, nil)
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	m.Process(result(kubeproberv1.CheckerStatusError, start))
+	m.Flush(ctx, start.Add(time.Minute))
+	assert.Len(t, n.messages, 1)
+
+	// not acknowledged in 10m, escalated to the first tier
+	m.Flush(ctx, start.Add(10*time.Minute))
+	m.Flush(ctx, start.Add(11*time.Minute))
+	assert.Len(t, n.messages, 2)
+	m.Process(result(kubeproberv1.CheckerStatusError, start.Add(12*time.Minute)))
+	states := m.States()
+	assert.Equal(t, []string{"ops", "manager"}, states[0].Receivers)
+	assert.Len(t, states[0].Escalations, 1)
+
+	// acknowledged alerts are neither escalated nor repeated
+	_, err := m.Ack("unknown", apistructs.AlertAck{})
+	assert.Equal(t, ErrNotFound, err)
+	s, err := m.Ack(states[0].Fingerprint, apistructs.AlertAck{By: "alice", Time: start.Add(15 * time.Minute)})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", s.Ack.By)
+	m.Flush(ctx, start.Add(30*time.Minute))
+	m.Flush(ctx, start.Add(2*time.Hour))
+	assert.Len(t, n.messages, 2)
+	assert.Len(t, m.States()[0].Escalations, 1)
+}
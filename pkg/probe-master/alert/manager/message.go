@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"k8s.io/klog"
+
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/message"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/notifier"
+)
+
+// buildMessage renders firing and resolved alerts with templates of the receiver,
+// built-in templates are used if the custom ones fail to render
+func buildMessage(n notifier.Notifier, firing, resolved []apistructs.AlertState) *notifier.Message {
+	data := &message.AlertData{
+		Receiver: n.Name(),
+		Firing:   firing,
+		Resolved: resolved,
+	}
+	tpl := n.Template()
+	title, text, err := tpl.Alert(data)
+	if err != nil {
+		klog.Errorf("[alert] failed to render message of receiver %s, use built-in template: %+v\n", n.Name(), err)
+		title, text, _ = message.Builtin(tpl.Language()).Alert(data)
+	}
+
+	alerts := make([]apistructs.AlertState, 0, len(firing)+len(resolved))
+	alerts = append(alerts, firing...)
+	alerts = append(alerts, resolved...)
+	return &notifier.Message{
+		Title:  title,
+		Text:   text,
+		Alerts: alerts,
+	}
+}
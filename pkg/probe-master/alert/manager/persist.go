@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+const (
+	StateConfigMap = "kubeprober-alert-state"
+	stateKey       = "alerts.json"
+)
+
+// Persister saves alert states, so that a master restart does not notify receivers again
+type Persister interface {
+	Load(ctx context.Context) ([]apistructs.AlertState, error)
+	Save(ctx context.Context, states []apistructs.AlertState) error
+}
+
+// ConfigMapPersister saves alert states in a ConfigMap of default namespace
+type ConfigMapPersister struct{}
+
+func (p *ConfigMapPersister) Load(ctx context.Context) ([]apistructs.AlertState, error) {
+	var states []apistructs.AlertState
+	if err := k8sclient.LoadConfigMapJSON(ctx, StateConfigMap, stateKey, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (p *ConfigMapPersister) Save(ctx context.Context, states []apistructs.AlertState) error {
+	return k8sclient.SaveConfigMapJSON(ctx, StateConfigMap, stateKey, states)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+// builtinTemplates keeps the formats used before templates were introduced for zh
+var builtinTemplates = map[Language]map[string]string{
+	LanguageZh: {
+		AlertTitle: `
+{{- if and .Firing .Resolved }}[KubeProber] {{ len .Firing }} 项巡检异常, {{ len .Resolved }} 项已恢复
+{{- else if .Firing }}[KubeProber] {{ len .Firing }} 项巡检异常
+{{- else }}[KubeProber] {{ len .Resolved }} 项巡检已恢复{{ end }}`,
+		AlertText: `
+{{- if .Firing }}【告警中 {{ len .Firing }}】
+{{ range .Firing }}[类别]: {{ .Probe }}
+[检查项]：{{ .Checker }}
+[集群]：{{ .Cluster }}
+[状态]: {{ .Status }}
+[开始时间]: {{ time .StartsAt }}
+[错误信息]: {{ .Message }}
+
+{{ end }}{{ end }}
+{{- if .Resolved }}【已恢复 {{ len .Resolved }}】
+{{ range .Resolved }}[类别]: {{ .Probe }}
+[检查项]：{{ .Checker }}
+[集群]：{{ .Cluster }}
+[开始时间]: {{ time .StartsAt }}
+[恢复时间]: {{ time .EndsAt }}
+
+{{ end }}{{ end }}`,
+		TicketTitle: `
+{{- if .Node }}异常告警-[集群]: {{ .Cluster }},[节点]: {{ .Node }},[类别]: {{ .Type }},[组件]: {{ .Component }}
+{{- else }}巡检异常-[集群]: {{ .Cluster }},[类别]: {{ .Probe }},[检查项]：{{ .Checker }}{{ end }}`,
+		TicketContent: `
+{{- if .Node }}{{ .Message }}
+{{- else }}[集群]: {{ .Cluster }}
+[类别]: {{ .Probe }}
+[检查项]：{{ .Checker }}
+[检查状态]：{{ .Status }}
+[错误信息]：
+{{ .Message }}{{ end }}`,
+	},
+	LanguageEn: {
+		AlertTitle: `
+{{- if and .Firing .Resolved }}[KubeProber] {{ len .Firing }} checkers failing, {{ len .Resolved }} resolved
+{{- else if .Firing }}[KubeProber] {{ len .Firing }} checkers failing
+{{- else }}[KubeProber] {{ len .Resolved }} checkers resolved{{ end }}`,
+		AlertText: `
+{{- if .Firing }}[FIRING {{ len .Firing }}]
+{{ range .Firing }}[Probe]: {{ .Probe }}
+[Checker]: {{ .Checker }}
+[Cluster]: {{ .Cluster }}
+[Status]: {{ .Status }}
+[Starts at]: {{ time .StartsAt }}
+[Message]: {{ .Message }}
+
+{{ end }}{{ end }}
+{{- if .Resolved }}[RESOLVED {{ len .Resolved }}]
+{{ range .Resolved }}[Probe]: {{ .Probe }}
+[Checker]: {{ .Checker }}
+[Cluster]: {{ .Cluster }}
+[Starts at]: {{ time .StartsAt }}
+[Ends at]: {{ time .EndsAt }}
+
+{{ end }}{{ end }}`,
+		TicketTitle: `
+{{- if .Node }}Alert - cluster: {{ .Cluster }}, node: {{ .Node }}, type: {{ .Type }}, component: {{ .Component }}
+{{- else }}Checker failed - cluster: {{ .Cluster }}, probe: {{ .Probe }}, checker: {{ .Checker }}{{ end }}`,
+		TicketContent: `
+{{- if .Node }}{{ .Message }}
+{{- else }}[Cluster]: {{ .Cluster }}
+[Probe]: {{ .Probe }}
+[Checker]: {{ .Checker }}
+[Status]: {{ .Status }}
+[Message]:
+{{ .Message }}{{ end }}`,
+	},
+}
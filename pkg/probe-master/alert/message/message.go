@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
+)
+
+type Language string
+
+const (
+	LanguageZh Language = "zh"
+	LanguageEn Language = "en"
+)
+
+// names of templates
+const (
+	AlertTitle    = "title"
+	AlertText     = "text"
+	TicketTitle   = "ticket_title"
+	TicketContent = "ticket_content"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+var (
+	defaultLanguage = LanguageZh
+	// built-in templates of languages
+	builtins sync.Map
+)
+
+// SetDefaultLanguage sets the language of receivers without language and tickets
+func SetDefaultLanguage(lang string) error {
+	l := Language(lang)
+	if _, ok := builtinTemplates[l]; !ok {
+		return errors.Errorf("unsupported language %q, supported: zh, en", lang)
+	}
+	defaultLanguage = l
+	return nil
+}
+
+// AlertData is the data of alert templates
+type AlertData struct {
+	Receiver string
+	Firing   []apistructs.AlertState
+	Resolved []apistructs.AlertState
+}
+
+// TicketData is the data of ticket templates, Node is set for node alerts
+// and Probe, Checker are set for checker results
+type TicketData struct {
+	Cluster   string
+	Probe     string
+	Checker   string
+	Node      string
+	Type      string
+	Component string
+	Status    kubeproberv1.CheckerStatus
+	Level     string
+	Message   string
+}
+
+// Template renders alert and ticket messages
+type Template struct {
+	lang Language
+	tpl  *template.Template
+}
+
+// New returns the templates of language, built-in templates are overridden by
+// non-empty custom ones, language of probe-master is used if lang is empty
+func New(lang string, custom map[string]string) (*Template, error) {
+	l := Language(lang)
+	if l == "" {
+		l = defaultLanguage
+	}
+	texts, ok := builtinTemplates[l]
+	if !ok {
+		return nil, errors.Errorf("unsupported language %q, supported: zh, en", lang)
+	}
+
+	tpl := template.New(string(l)).Funcs(funcs)
+	for _, name := range []string{AlertTitle, AlertText, TicketTitle, TicketContent} {
+		text := texts[name]
+		if custom[name] != "" {
+			text = custom[name]
+		}
+		if _, err := tpl.New(name).Parse(text); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s template", name)
+		}
+	}
+	return &Template{lang: l, tpl: tpl}, nil
+}
+
+// Default returns the built-in templates of language of probe-master
+func Default() *Template {
+	return Builtin(defaultLanguage)
+}
+
+// Builtin returns the built-in templates of language
+func Builtin(lang Language) *Template {
+	if t, ok := builtins.Load(lang); ok {
+		return t.(*Template)
+	}
+	t, err := New(string(lang), nil)
+	if err != nil {
+		// built-in templates are always valid
+		panic(err)
+	}
+	builtins.Store(lang, t)
+	return t
+}
+
+func (t *Template) Language() Language {
+	return t.lang
+}
+
+// Alert renders the title and text of alert message
+func (t *Template) Alert(data *AlertData) (string, string, error) {
+	return t.render(AlertTitle, AlertText, data)
+}
+
+// Ticket renders the title and content of ticket
+func (t *Template) Ticket(data *TicketData) (string, string, error) {
+	return t.render(TicketTitle, TicketContent, data)
+}
+
+func (t *Template) render(titleName, textName string, data interface{}) (string, string, error) {
+	var title, text bytes.Buffer
+	if err := t.tpl.ExecuteTemplate(&title, titleName, data); err != nil {
+		return "", "", errors.Wrapf(err, "failed to render %s", titleName)
+	}
+	if err := t.tpl.ExecuteTemplate(&text, textName, data); err != nil {
+		return "", "", errors.Wrapf(err, "failed to render %s", textName)
+	}
+	return strings.TrimSpace(title.String()), text.String(), nil
+}
+
+var funcs = template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.In(timezone.Location()).Format(timeLayout)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"trim":  strings.TrimSpace,
+}
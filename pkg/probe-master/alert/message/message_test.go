@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func TestAlert(t *testing.T) {
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	data := &AlertData{
+		Firing: []apistructs.AlertState{{
+			Cluster:  "c1",
+			Probe:    "k8s",
+			Checker:  "dns",
+			Status:   kubeproberv1.CheckerStatusError,
+			Message:  "timeout",
+			StartsAt: start,
+		}},
+		Resolved: []apistructs.AlertState{{Cluster: "c2", Probe: "k8s", Checker: "dns", StartsAt: start, EndsAt: start}},
+	}
+
+	title, text, err := Builtin(LanguageZh).Alert(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "[KubeProber] 1 项巡检异常, 1 项已恢复", title)
+	assert.Contains(t, text, "[集群]：c1")
+	assert.Contains(t, text, "【已恢复 1】")
+
+	title, text, err = Builtin(LanguageEn).Alert(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "[KubeProber] 1 checkers failing, 1 resolved", title)
+	assert.Contains(t, text, "[Message]: timeout")
+
+	tpl, err := New("en", map[string]string{AlertTitle: `{{ len .Firing }} firing on {{ (index .Firing 0).Cluster }}`})
+	assert.NoError(t, err)
+	title, text, err = tpl.Alert(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "1 firing on c1", title)
+	assert.Contains(t, text, "[RESOLVED 1]")
+
+	_, err = New("en", map[string]string{AlertText: `{{ .Firing`})
+	assert.Error(t, err)
+	_, err = New("fr", nil)
+	assert.Error(t, err)
+}
+
+func TestTicket(t *testing.T) {
+	title, content, err := Builtin(LanguageZh).Ticket(&TicketData{
+		Cluster: "c1",
+		Probe:   "k8s",
+		Checker: "dns",
+		Status:  kubeproberv1.CheckerStatusError,
+		Message: "timeout",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "巡检异常-[集群]: c1,[类别]: k8s,[检查项]：dns", title)
+	assert.Contains(t, content, "[检查状态]：ERROR")
+
+	title, content, err = Builtin(LanguageEn).Ticket(&TicketData{
+		Cluster:   "c1",
+		Node:      "node1",
+		Type:      "machine",
+		Component: "kubelet",
+		Message:   "disk full",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Alert - cluster: c1, node: node1, type: machine, component: kubelet", title)
+	assert.Equal(t, "disk full", content)
+}
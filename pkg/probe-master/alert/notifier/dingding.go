@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+const dingdingMaxBytes = 1800
+
+type dingding struct {
+	base
+	addr string
+	sign string
+}
+
+func newDingDing(b base, spec *kubeproberv1.AlertSpec) (Notifier, error) {
+	if spec.Address == "" || spec.Token == "" {
+		return nil, errors.New("address or token is empty in dingding alert spec")
+	}
+	return &dingding{
+		base: b.withType(kubeproberv1.ReceiverTypeDingDing),
+		addr: fmt.Sprintf("%s/robot/send?access_token=%s", spec.Address, spec.Token),
+		sign: spec.Sign,
+	}, nil
+}
+
+func (d *dingding) Notify(ctx context.Context, msg *Message) error {
+	addr := d.addr
+	if d.sign != "" {
+		var err error
+		if addr, err = signDingDingURL(addr, d.sign, time.Now()); err != nil {
+			return err
+		}
+	}
+	return postRobot(ctx, addr, map[string]interface{}{
+		"msgtype": "text",
+		"text": map[string]string{
+			"content": truncate(joinText(msg), dingdingMaxBytes),
+		},
+	})
+}
+
+// signDingDingURL adds timestamp and sign of the secret to addr
+func signDingDingURL(addr string, secret string, now time.Time) (string, error) {
+	tm := now.UnixNano() / 1e6
+	strToHash := fmt.Sprintf("%d\n%s", tm, secret)
+	hmac256 := hmac.New(sha256.New, []byte(secret))
+	hmac256.Write([]byte(strToHash))
+	sign := base64.StdEncoding.EncodeToString(hmac256.Sum(nil))
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	values := u.Query()
+	values.Set("timestamp", fmt.Sprintf("%d", tm))
+	values.Set("sign", sign)
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
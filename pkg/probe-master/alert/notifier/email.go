@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+const smtpsPort = "465"
+
+type email struct {
+	base
+	cfg  kubeproberv1.EmailConfig
+	host string
+	port string
+}
+
+func newEmail(b base, cfg *kubeproberv1.EmailConfig) (Notifier, error) {
+	if cfg == nil || cfg.SmartHost == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("smarthost, from or to is empty in email config")
+	}
+	host, port, err := net.SplitHostPort(cfg.SmartHost)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid smarthost %s", cfg.SmartHost)
+	}
+	return &email{
+		base: b.withType(kubeproberv1.ReceiverTypeEmail),
+		cfg:  *cfg,
+		host: host,
+		port: port,
+	}, nil
+}
+
+func (e *email) Notify(ctx context.Context, msg *Message) error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if e.port == smtpsPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", e.cfg.SmartHost, &tls.Config{ServerName: e.host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", e.cfg.SmartHost)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.port != smtpsPort {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+				return errors.Wrap(err, "starttls")
+			}
+		}
+	}
+	if e.cfg.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.host)); err != nil {
+			return errors.Wrap(err, "auth")
+		}
+	}
+	if err = c.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err = c.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "rcpt %s", to)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(buildMail(e.cfg.From, e.cfg.To, msg, time.Now())); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMail returns a plain text mail with base64 encoded body
+func buildMail(from string, to []string, msg *Message, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Text))
+	for len(body) > 76 {
+		b.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	b.WriteString(body + "\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+const feishuMaxBytes = 20000
+
+type feishu struct {
+	base
+	cfg kubeproberv1.FeishuConfig
+}
+
+func newFeishu(b base, cfg *kubeproberv1.FeishuConfig) (Notifier, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, errors.New("url is empty in feishu config")
+	}
+	return &feishu{base: b.withType(kubeproberv1.ReceiverTypeFeishu), cfg: *cfg}, nil
+}
+
+func (f *feishu) Notify(ctx context.Context, msg *Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content": map[string]string{
+			"text": truncate(joinText(msg), feishuMaxBytes),
+		},
+	}
+	if f.cfg.Secret != "" {
+		timestamp := time.Now().Unix()
+		payload["timestamp"] = fmt.Sprintf("%d", timestamp)
+		payload["sign"] = signFeishu(f.cfg.Secret, timestamp)
+	}
+	return postRobot(ctx, f.cfg.URL, payload)
+}
+
+// signFeishu uses "timestamp\nsecret" as the key of hmac-sha256 on empty data
+func signFeishu(secret string, timestamp int64) string {
+	h := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
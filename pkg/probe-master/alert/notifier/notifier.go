@@ -0,0 +1,199 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/message"
+)
+
+const requestTimeout = 10 * time.Second
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// Message is the content sent to a receiver
+type Message struct {
+	Title string
+	Text  string
+	// alerts in this message, used by structured receivers like webhook
+	Alerts []apistructs.AlertState
+}
+
+// Notifier sends messages to one receiver, which is an Alert object
+type Notifier interface {
+	// Name returns the name of Alert object
+	Name() string
+	Type() kubeproberv1.ReceiverType
+	// Template returns the message templates of receiver
+	Template() *message.Template
+	Notify(ctx context.Context, msg *Message) error
+}
+
+// New returns the notifier of alert by its receiver type
+func New(alert *kubeproberv1.Alert) (Notifier, error) {
+	spec := &alert.Spec
+	b := base{name: alert.Name}
+	var err error
+	if spec.Template != nil {
+		b.tpl, err = message.New(spec.Template.Language, map[string]string{
+			message.AlertTitle:    spec.Template.Title,
+			message.AlertText:     spec.Template.Text,
+			message.TicketTitle:   spec.Template.TicketTitle,
+			message.TicketContent: spec.Template.TicketContent,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch spec.Type {
+	case "", kubeproberv1.ReceiverTypeDingDing:
+		return newDingDing(b, spec)
+	case kubeproberv1.ReceiverTypeSlack:
+		return newSlack(b, spec.Slack)
+	case kubeproberv1.ReceiverTypeWeCom:
+		return newWeCom(b, spec.WeCom)
+	case kubeproberv1.ReceiverTypeFeishu:
+		return newFeishu(b, spec.Feishu)
+	case kubeproberv1.ReceiverTypeEmail:
+		return newEmail(b, spec.Email)
+	case kubeproberv1.ReceiverTypeWebhook:
+		return newWebhook(b, spec.Webhook)
+	}
+	return nil, errors.Errorf("unknown receiver type %q", spec.Type)
+}
+
+type base struct {
+	name string
+	typ  kubeproberv1.ReceiverType
+	// nil means built-in templates of probe-master language
+	tpl *message.Template
+}
+
+func (b base) withType(typ kubeproberv1.ReceiverType) base {
+	b.typ = typ
+	return b
+}
+
+func (b *base) Name() string {
+	return b.name
+}
+
+func (b *base) Type() kubeproberv1.ReceiverType {
+	return b.typ
+}
+
+func (b *base) Template() *message.Template {
+	if b.tpl == nil {
+		return message.Default()
+	}
+	return b.tpl
+}
+
+// robotResponse covers the error fields of dingding, wecom and feishu responses
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+// postJSON posts payload to url and returns the body of a 2xx response
+func postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}) ([]byte, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("unexpected status code %d, body: %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// postRobot posts payload to the robot of im and checks the error code of response
+func postRobot(ctx context.Context, url string, payload interface{}) error {
+	body, err := postJSON(ctx, url, nil, payload)
+	if err != nil {
+		return err
+	}
+	r := robotResponse{}
+	if err := json.Unmarshal(body, &r); err != nil {
+		// some robots return plain text like "ok"
+		return nil
+	}
+	if r.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	if r.Code != 0 {
+		return fmt.Errorf("code %d: %s", r.Code, r.Msg)
+	}
+	return nil
+}
+
+// truncate cuts s to at most n bytes without breaking utf-8 characters
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// truncateRunes cuts s to at most n characters
+func truncateRunes(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// joinText puts the title before text if it is not empty
+func joinText(msg *Message) string {
+	if msg.Title == "" {
+		return msg.Text
+	}
+	return msg.Title + "\n" + msg.Text
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/message"
+)
+
+type recorder struct {
+	path   string
+	query  map[string][]string
+	header http.Header
+	body   map[string]interface{}
+}
+
+func newTestServer(t *testing.T, resp string) (*httptest.Server, *recorder) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		rec.path = req.URL.Path
+		rec.query = req.URL.Query()
+		rec.header = req.Header
+		assert.NoError(t, json.Unmarshal(b, &rec.body))
+		rw.Write([]byte(resp))
+	}))
+	return srv, rec
+}
+
+func newAlert(name string, spec kubeproberv1.AlertSpec) *kubeproberv1.Alert {
+	return &kubeproberv1.Alert{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+}
+
+func TestNotifiers(t *testing.T) {
+	msg := &Message{Title: "title", Text: "text"}
+
+	srv, rec := newTestServer(t, `{"errcode":0}`)
+	n, err := New(newAlert("dingding", kubeproberv1.AlertSpec{Address: srv.URL, Token: "token", Sign: "sign"}))
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), msg))
+	assert.Equal(t, "/robot/send", rec.path)
+	assert.Equal(t, "token", rec.query["access_token"][0])
+	assert.NotEmpty(t, rec.query["sign"])
+	assert.Equal(t, "title\ntext", rec.body["text"].(map[string]interface{})["content"])
+	srv.Close()
+
+	srv, rec = newTestServer(t, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	n, err = New(newAlert("wecom", kubeproberv1.AlertSpec{
+		Type:  kubeproberv1.ReceiverTypeWeCom,
+		WeCom: &kubeproberv1.WeComConfig{URL: srv.URL, MentionedList: []string{"@all"}},
+	}))
+	assert.NoError(t, err)
+	assert.EqualError(t, n.Notify(context.Background(), msg), "errcode 93000: invalid webhook url")
+	assert.Equal(t, []interface{}{"@all"}, rec.body["text"].(map[string]interface{})["mentioned_list"])
+	srv.Close()
+
+	srv, rec = newTestServer(t, `{"code":0,"msg":"success"}`)
+	n, err = New(newAlert("feishu", kubeproberv1.AlertSpec{
+		Type:   kubeproberv1.ReceiverTypeFeishu,
+		Feishu: &kubeproberv1.FeishuConfig{URL: srv.URL, Secret: "secret"},
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), msg))
+	assert.Equal(t, "text", rec.body["msg_type"])
+	assert.NotEmpty(t, rec.body["sign"])
+	srv.Close()
+
+	srv, rec = newTestServer(t, `{}`)
+	n, err = New(newAlert("hook", kubeproberv1.AlertSpec{
+		Type:    kubeproberv1.ReceiverTypeWebhook,
+		Webhook: &kubeproberv1.WebhookConfig{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer x"}},
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), msg))
+	assert.Equal(t, "Bearer x", rec.header.Get("Authorization"))
+	assert.Equal(t, "hook", rec.body["receiver"])
+	srv.Close()
+}
+
+func TestTicketTemplate(t *testing.T) {
+	n, err := New(newAlert("dingding", kubeproberv1.AlertSpec{
+		Address: "http://127.0.0.1", Token: "token",
+		Template: &kubeproberv1.AlertTemplate{
+			TicketTitle:   "{{.Cluster}}/{{.Checker}}",
+			TicketContent: "{{.Message}}",
+		},
+	}))
+	assert.NoError(t, err)
+	title, content, err := n.Template().Ticket(&message.TicketData{Cluster: "c1", Checker: "dns", Message: "timeout"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c1/dns", title)
+	assert.Equal(t, "timeout", content)
+
+	_, err = New(newAlert("dingding", kubeproberv1.AlertSpec{
+		Address:  "http://127.0.0.1",
+		Template: &kubeproberv1.AlertTemplate{TicketTitle: "{{.Cluster"},
+	}))
+	assert.Error(t, err)
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, spec := range []kubeproberv1.AlertSpec{
+		{},
+		{Type: kubeproberv1.ReceiverTypeSlack},
+		{Type: kubeproberv1.ReceiverTypeEmail, Email: &kubeproberv1.EmailConfig{SmartHost: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}}},
+		{Type: "sms"},
+	} {
+		_, err := New(newAlert("invalid", spec))
+		assert.Error(t, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abc", 2))
+	// "中" is 3 bytes
+	assert.Equal(t, "a", truncate("a中文", 3))
+	assert.Equal(t, "a中", truncate("a中文", 4))
+}
+
+func TestTruncateRunes(t *testing.T) {
+	assert.Equal(t, "abc", truncateRunes("abc", 5))
+	assert.Equal(t, "ab", truncateRunes("abc", 2))
+	assert.Equal(t, "a中文", truncateRunes("a中文", 3))
+	assert.Equal(t, "a中", truncateRunes("a中文", 2))
+}
+
+func TestBuildMail(t *testing.T) {
+	mail := string(buildMail("a@example.com", []string{"b@example.com", "c@example.com"},
+		&Message{Title: "巡检异常", Text: "text"}, time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, strings.Contains(mail, "To: b@example.com, c@example.com\r\n"))
+	assert.True(t, strings.Contains(mail, "Subject: =?utf-8?q?"))
+	assert.True(t, strings.HasSuffix(mail, "\r\n\r\ndGV4dA==\r\n"))
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+// Registry keeps the notifiers of Alert objects in default namespace, keyed by name
+type Registry struct {
+	sync.RWMutex
+	notifiers map[string]Notifier
+}
+
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{notifiers: make(map[string]Notifier)}
+}
+
+// Refresh reloads Alert objects from kubernetes
+func (r *Registry) Refresh(ctx context.Context) error {
+	alerts := &kubeproberv1.AlertList{}
+	if err := k8sclient.RestClient.List(ctx, alerts, client.InNamespace(metav1.NamespaceDefault)); err != nil {
+		return err
+	}
+	r.Load(alerts.Items)
+	return nil
+}
+
+// Load replaces notifiers with the ones of alerts, invalid alerts are skipped
+func (r *Registry) Load(alerts []kubeproberv1.Alert) {
+	notifiers := make(map[string]Notifier)
+	for i := range alerts {
+		n, err := New(&alerts[i])
+		if err != nil {
+			klog.Errorf("[notifier] skip invalid receiver %s: %+v\n", alerts[i].Name, err)
+			continue
+		}
+		notifiers[n.Name()] = n
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.notifiers = notifiers
+}
+
+// Get returns the notifier of receiver name, nil if not found
+func (r *Registry) Get(name string) Notifier {
+	r.RLock()
+	defer r.RUnlock()
+	return r.notifiers[name]
+}
+
+// List returns all notifiers ordered by name
+func (r *Registry) List() []Notifier {
+	r.RLock()
+	defer r.RUnlock()
+	var l []Notifier
+	for _, n := range r.notifiers {
+		l = append(l, n)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Name() < l[j].Name()
+	})
+	return l
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+// slack truncates messages longer than 40000 characters, keep some room for the title
+const slackMaxChars = 39000
+
+type slack struct {
+	base
+	cfg kubeproberv1.SlackConfig
+}
+
+func newSlack(b base, cfg *kubeproberv1.SlackConfig) (Notifier, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, errors.New("url is empty in slack config")
+	}
+	return &slack{base: b.withType(kubeproberv1.ReceiverTypeSlack), cfg: *cfg}, nil
+}
+
+func (s *slack) Notify(ctx context.Context, msg *Message) error {
+	payload := map[string]string{
+		"text": truncateRunes(joinText(msg), slackMaxChars),
+	}
+	if s.cfg.Channel != "" {
+		payload["channel"] = s.cfg.Channel
+	}
+	if s.cfg.Username != "" {
+		payload["username"] = s.cfg.Username
+	}
+	// slack incoming webhook returns plain text "ok"
+	_, err := postJSON(ctx, s.cfg.URL, nil, payload)
+	return err
+}
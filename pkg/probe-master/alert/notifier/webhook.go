@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+type webhook struct {
+	base
+	cfg kubeproberv1.WebhookConfig
+}
+
+func newWebhook(b base, cfg *kubeproberv1.WebhookConfig) (Notifier, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, errors.New("url is empty in webhook config")
+	}
+	return &webhook{base: b.withType(kubeproberv1.ReceiverTypeWebhook), cfg: *cfg}, nil
+}
+
+func (w *webhook) Notify(ctx context.Context, msg *Message) error {
+	_, err := postJSON(ctx, w.cfg.URL, w.cfg.Headers, &apistructs.AlertWebhookMessage{
+		Receiver: w.name,
+		Title:    msg.Title,
+		Text:     msg.Text,
+		Alerts:   msg.Alerts,
+	})
+	return err
+}
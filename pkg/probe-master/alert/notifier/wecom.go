@@ -0,0 +1,49 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+const wecomMaxBytes = 2048
+
+type wecom struct {
+	base
+	cfg kubeproberv1.WeComConfig
+}
+
+func newWeCom(b base, cfg *kubeproberv1.WeComConfig) (Notifier, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, errors.New("url is empty in wecom config")
+	}
+	return &wecom{base: b.withType(kubeproberv1.ReceiverTypeWeCom), cfg: *cfg}, nil
+}
+
+func (w *wecom) Notify(ctx context.Context, msg *Message) error {
+	text := map[string]interface{}{
+		"content": truncate(joinText(msg), wecomMaxBytes),
+	}
+	if len(w.cfg.MentionedList) > 0 {
+		text["mentioned_list"] = w.cfg.MentionedList
+	}
+	return postRobot(ctx, w.cfg.URL, map[string]interface{}{
+		"msgtype": "text",
+		"text":    text,
+	})
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+var defaultSeverities = []kubeproberv1.CheckerStatus{kubeproberv1.CheckerStatusError}
+
+type compiledRoute struct {
+	name     string
+	spec     kubeproberv1.AlertRouteSpec
+	selector labels.Selector
+	checker  *regexp.Regexp
+}
+
+// Router matches checker results against AlertRoute objects in default namespace
+type Router struct {
+	sync.RWMutex
+	routes        []compiledRoute
+	clusterLabels map[string]labels.Set
+}
+
+var DefaultRouter = NewRouter()
+
+func NewRouter() *Router {
+	return &Router{clusterLabels: make(map[string]labels.Set)}
+}
+
+// Refresh reloads alert routes and labels of clusters from kubernetes,
+// and records spec errors in the status of routes
+func (r *Router) Refresh(ctx context.Context) error {
+	routes := &kubeproberv1.AlertRouteList{}
+	if err := k8sclient.RestClient.List(ctx, routes, client.InNamespace(metav1.NamespaceDefault)); err != nil {
+		return err
+	}
+	clusters, err := k8sclient.GetClusters()
+	if err != nil {
+		return err
+	}
+
+	errs := r.Load(routes.Items, clusters)
+	for _, route := range routes.Items {
+		if route.Status.Error == errs[route.Name] {
+			continue
+		}
+		patch, _ := json.Marshal(kubeproberv1.AlertRoute{Status: kubeproberv1.AlertRouteStatus{Error: errs[route.Name]}})
+		if err := k8sclient.RestClient.Status().Patch(ctx, &kubeproberv1.AlertRoute{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      route.Name,
+				Namespace: metav1.NamespaceDefault,
+			},
+		}, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			klog.Errorf("[route] failed to update status of alert route %s: %+v\n", route.Name, err)
+		}
+	}
+	return nil
+}
+
+// Load replaces routes and cluster labels, invalid routes are skipped and their errors are returned by name
+func (r *Router) Load(routes []kubeproberv1.AlertRoute, clusters []kubeproberv1.Cluster) map[string]string {
+	errs := make(map[string]string)
+	var compiled []compiledRoute
+	for _, route := range routes {
+		c, err := compile(&route)
+		if err != nil {
+			klog.Errorf("[route] skip invalid alert route %s: %+v\n", route.Name, err)
+			errs[route.Name] = err.Error()
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	sort.SliceStable(compiled, func(i, j int) bool {
+		if compiled[i].spec.Priority != compiled[j].spec.Priority {
+			return compiled[i].spec.Priority < compiled[j].spec.Priority
+		}
+		return compiled[i].name < compiled[j].name
+	})
+
+	clusterLabels := make(map[string]labels.Set)
+	for _, c := range clusters {
+		clusterLabels[c.Name] = labels.Set(c.Labels)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.routes = compiled
+	r.clusterLabels = clusterLabels
+	return errs
+}
+
+func compile(route *kubeproberv1.AlertRoute) (compiledRoute, error) {
+	c := compiledRoute{name: route.Name, spec: route.Spec}
+	if len(route.Spec.Receivers) == 0 && len(route.Spec.Tickets) == 0 {
+		return c, fmt.Errorf("no receivers or tickets")
+	}
+	for _, e := range route.Spec.Escalations {
+		if e.After.Duration <= 0 || len(e.Receivers) == 0 {
+			return c, fmt.Errorf("escalation requires a positive duration and receivers")
+		}
+	}
+	if route.Spec.Match.ClusterSelector != nil {
+		s, err := metav1.LabelSelectorAsSelector(route.Spec.Match.ClusterSelector)
+		if err != nil {
+			return c, fmt.Errorf("invalid cluster selector: %v", err)
+		}
+		c.selector = s
+	}
+	if route.Spec.Match.Checker != "" {
+		re, err := regexp.Compile("^(?:" + route.Spec.Match.Checker + ")$")
+		if err != nil {
+			return c, fmt.Errorf("invalid checker regex: %v", err)
+		}
+		c.checker = re
+	}
+	return c, nil
+}
+
+// Match returns the receivers of a checker result
+func (r *Router) Match(result *apistructs.CheckerResult) *apistructs.AlertRouteDecision {
+	r.RLock()
+	defer r.RUnlock()
+
+	d := &apistructs.AlertRouteDecision{Routes: []string{}, Receivers: []string{}, Tickets: []string{}}
+	if len(r.routes) == 0 {
+		// node alerts are sent to the dingding receiver without routes
+		d.All = result.Status == kubeproberv1.CheckerStatusError && result.Probe != apistructs.NodeAlertProbe
+		klog.V(2).Infof("[route] no alert route, %s %s is sent to all receivers: %t\n", result.Key(), result.Status, d.All)
+		return d
+	}
+
+	seen := make(map[string]bool)
+	seenTickets := make(map[kubeproberv1.TicketBackendType]bool)
+	for _, route := range r.routes {
+		if !route.match(result, r.clusterLabels[result.Cluster]) {
+			continue
+		}
+		d.Routes = append(d.Routes, route.name)
+		for _, receiver := range route.spec.Receivers {
+			if !seen[receiver] {
+				seen[receiver] = true
+				d.Receivers = append(d.Receivers, receiver)
+			}
+		}
+		// only error alerts are escalated
+		if result.Status == kubeproberv1.CheckerStatusError {
+			for _, e := range route.spec.Escalations {
+				d.Escalations = append(d.Escalations, apistructs.AlertEscalationTier{
+					After:     e.After.Duration,
+					Receivers: e.Receivers,
+				})
+			}
+		}
+		for _, backend := range route.spec.Tickets {
+			if !seenTickets[backend] {
+				seenTickets[backend] = true
+				d.Tickets = append(d.Tickets, string(backend))
+			}
+		}
+		if !route.spec.Continue {
+			break
+		}
+	}
+	sort.SliceStable(d.Escalations, func(i, j int) bool {
+		return d.Escalations[i].After < d.Escalations[j].After
+	})
+	klog.V(2).Infof("[route] %s %s matched routes %v, receivers %v, tickets %v\n", result.Key(), result.Status, d.Routes,
+		d.Receivers, d.Tickets)
+	return d
+}
+
+func (c *compiledRoute) match(result *apistructs.CheckerResult, clusterLabels labels.Set) bool {
+	m := &c.spec.Match
+	if len(m.Clusters) > 0 && !contains(m.Clusters, result.Cluster) {
+		return false
+	}
+	if c.selector != nil && !c.selector.Matches(clusterLabels) {
+		return false
+	}
+	if len(m.Probes) > 0 && !contains(m.Probes, result.Probe) {
+		return false
+	}
+	// routes without probes match checker results only
+	if result.Probe == apistructs.NodeAlertProbe && len(m.Probes) == 0 {
+		return false
+	}
+	if c.checker != nil && !c.checker.MatchString(result.Checker) {
+		return false
+	}
+	severities := m.Severities
+	if len(severities) == 0 {
+		severities = defaultSeverities
+	}
+	for _, s := range severities {
+		if s == result.Status {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, i := range l {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
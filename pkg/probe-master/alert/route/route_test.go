@@ -0,0 +1,161 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func newRoute(name string, spec kubeproberv1.AlertRouteSpec) kubeproberv1.AlertRoute {
+	return kubeproberv1.AlertRoute{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+}
+
+func TestMatch(t *testing.T) {
+	r := NewRouter()
+
+	// without routes error results go to all receivers
+	assert.True(t, r.Match(&apistructs.CheckerResult{Status: kubeproberv1.CheckerStatusError}).All)
+	assert.False(t, r.Match(&apistructs.CheckerResult{Status: kubeproberv1.CheckerStatusWARN}).All)
+
+	errs := r.Load([]kubeproberv1.AlertRoute{
+		newRoute("catch-all", kubeproberv1.AlertRouteSpec{
+			Priority:  100,
+			Receivers: []string{"sre"},
+		}),
+		newRoute("prod", kubeproberv1.AlertRouteSpec{
+			Priority: 10,
+			Match: kubeproberv1.AlertRouteMatch{
+				ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+				Severities:      []kubeproberv1.CheckerStatus{kubeproberv1.CheckerStatusError, kubeproberv1.CheckerStatusWARN},
+			},
+			Receivers: []string{"oncall"},
+			Tickets:   []kubeproberv1.TicketBackendType{"jira"},
+			Continue:  true,
+			Escalations: []kubeproberv1.AlertEscalation{
+				{After: metav1.Duration{Duration: time.Hour}, Receivers: []string{"director"}},
+				{After: metav1.Duration{Duration: 30 * time.Minute}, Receivers: []string{"manager"}},
+			},
+		}),
+		newRoute("dns", kubeproberv1.AlertRouteSpec{
+			Priority: 20,
+			Match: kubeproberv1.AlertRouteMatch{
+				Probes:  []string{"k8s"},
+				Checker: "dns-.*",
+			},
+			Receivers: []string{"network", "sre"},
+			Tickets:   []kubeproberv1.TicketBackendType{"jira", "github"},
+		}),
+		newRoute("invalid", kubeproberv1.AlertRouteSpec{
+			Match:     kubeproberv1.AlertRouteMatch{Checker: "("},
+			Receivers: []string{"sre"},
+		}),
+		newRoute("invalid-escalation", kubeproberv1.AlertRouteSpec{
+			Receivers:   []string{"sre"},
+			Escalations: []kubeproberv1.AlertEscalation{{Receivers: []string{"manager"}}},
+		}),
+	}, []kubeproberv1.Cluster{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prod-1", Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-1", Labels: map[string]string{"env": "test"}}},
+	})
+	assert.Contains(t, errs["invalid"], "invalid checker regex")
+	assert.Contains(t, errs["invalid-escalation"], "escalation")
+
+	// only error alerts are escalated, tiers are ordered by duration
+	d := r.Match(&apistructs.CheckerResult{Cluster: "prod-1", Probe: "k8s", Checker: "etcd", Status: kubeproberv1.CheckerStatusError})
+	assert.Equal(t, []apistructs.AlertEscalationTier{
+		{After: 30 * time.Minute, Receivers: []string{"manager"}},
+		{After: time.Hour, Receivers: []string{"director"}},
+	}, d.Escalations)
+	d = r.Match(&apistructs.CheckerResult{Cluster: "prod-1", Probe: "k8s", Checker: "etcd", Status: kubeproberv1.CheckerStatusWARN})
+	assert.Empty(t, d.Escalations)
+
+	for _, c := range []struct {
+		result    apistructs.CheckerResult
+		routes    []string
+		receivers []string
+		tickets   []string
+	}{
+		{
+			result:    apistructs.CheckerResult{Cluster: "prod-1", Probe: "k8s", Checker: "dns-resolve", Status: kubeproberv1.CheckerStatusError},
+			routes:    []string{"prod", "dns"},
+			receivers: []string{"oncall", "network", "sre"},
+			tickets:   []string{"jira", "github"},
+		},
+		{
+			result:    apistructs.CheckerResult{Cluster: "prod-1", Probe: "k8s", Checker: "dns-resolve", Status: kubeproberv1.CheckerStatusWARN},
+			routes:    []string{"prod"},
+			receivers: []string{"oncall"},
+			tickets:   []string{"jira"},
+		},
+		{
+			// checker regex is fully matched
+			result:    apistructs.CheckerResult{Cluster: "test-1", Probe: "k8s", Checker: "coredns-pod", Status: kubeproberv1.CheckerStatusError},
+			routes:    []string{"catch-all"},
+			receivers: []string{"sre"},
+			tickets:   []string{},
+		},
+		{
+			result:    apistructs.CheckerResult{Cluster: "test-1", Probe: "k8s", Checker: "coredns-pod", Status: kubeproberv1.CheckerStatusInfo},
+			routes:    []string{},
+			receivers: []string{},
+			tickets:   []string{},
+		},
+	} {
+		d := r.Match(&c.result)
+		assert.False(t, d.All)
+		assert.Equal(t, c.routes, d.Routes, c.result.Key())
+		assert.Equal(t, c.receivers, d.Receivers, c.result.Key())
+		assert.Equal(t, c.tickets, d.Tickets, c.result.Key())
+	}
+}
+
+func TestMatchNodeAlert(t *testing.T) {
+	r := NewRouter()
+	node := apistructs.NodeAlertResult("prod-1", "node-1", "NodeDown", "kubelet", kubeproberv1.CheckerStatusError, "down", time.Now())
+
+	// node alerts are sent to the dingding receiver without routes
+	assert.False(t, r.Match(node).All)
+
+	r.Load([]kubeproberv1.AlertRoute{
+		newRoute("catch-all", kubeproberv1.AlertRouteSpec{
+			Priority:  100,
+			Receivers: []string{"sre"},
+		}),
+		newRoute("node", kubeproberv1.AlertRouteSpec{
+			Priority: 10,
+			Match: kubeproberv1.AlertRouteMatch{
+				Probes:  []string{apistructs.NodeAlertProbe},
+				Checker: ".*/NodeDown/.*",
+			},
+			Receivers:   []string{"oncall"},
+			Escalations: []kubeproberv1.AlertEscalation{{After: metav1.Duration{Duration: time.Hour}, Receivers: []string{"manager"}}},
+		}),
+	}, nil)
+
+	d := r.Match(node)
+	assert.Equal(t, []string{"node"}, d.Routes)
+	assert.Equal(t, []string{"oncall"}, d.Receivers)
+	assert.Len(t, d.Escalations, 1)
+
+	// routes without probes do not match node alerts
+	node.Checker = "node-1/NodeNotReady/"
+	assert.Empty(t, r.Match(node).Routes)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+const (
+	refreshInterval = 60 * time.Second
+	// expired silences are kept for this duration before deleted
+	expiredRetention = 24 * time.Hour
+)
+
+// Silencer keeps the Silence objects in default namespace
+type Silencer struct {
+	sync.RWMutex
+	silences []kubeproberv1.Silence
+}
+
+var DefaultSilencer = &Silencer{}
+
+// Start refreshes silences and cleans up expired ones until ctx is done
+func (s *Silencer) Start(ctx context.Context) {
+	refresh := func() {
+		if err := s.Refresh(ctx); err != nil {
+			klog.Errorf("[silence] failed to load silences: %+v\n", err)
+			return
+		}
+		s.Cleanup(ctx, time.Now())
+	}
+	refresh()
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+// Refresh reloads Silence objects from kubernetes
+func (s *Silencer) Refresh(ctx context.Context) error {
+	silences := &kubeproberv1.SilenceList{}
+	if err := k8sclient.RestClient.List(ctx, silences, client.InNamespace(metav1.NamespaceDefault)); err != nil {
+		return err
+	}
+	s.Load(silences.Items)
+	return nil
+}
+
+func (s *Silencer) Load(silences []kubeproberv1.Silence) {
+	s.Lock()
+	defer s.Unlock()
+	s.silences = silences
+}
+
+// Silenced returns the names of active silences matching the checker and status
+func (s *Silencer) Silenced(cluster, probe, checker string, status kubeproberv1.CheckerStatus, now time.Time) []string {
+	s.RLock()
+	defer s.RUnlock()
+	var names []string
+	for i := range s.silences {
+		silence := &s.silences[i]
+		if silence.State(now) == kubeproberv1.SilenceStateActive && Matches(&silence.Spec.Matchers, cluster, probe, checker, status) {
+			names = append(names, silence.Name)
+		}
+	}
+	return names
+}
+
+// Matches returns whether the checker and status match all matchers
+func Matches(m *kubeproberv1.SilenceMatchers, cluster, probe, checker string, status kubeproberv1.CheckerStatus) bool {
+	if m.Cluster != "" && m.Cluster != cluster {
+		return false
+	}
+	if m.Probe != "" && m.Probe != probe {
+		return false
+	}
+	if m.Checker != "" && m.Checker != checker {
+		return false
+	}
+	if len(m.Severities) == 0 {
+		return true
+	}
+	for _, severity := range m.Severities {
+		if severity == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Cleanup updates the state of silences, and deletes the silences expired for retention
+func (s *Silencer) Cleanup(ctx context.Context, now time.Time) {
+	s.RLock()
+	silences := make([]kubeproberv1.Silence, len(s.silences))
+	copy(silences, s.silences)
+	s.RUnlock()
+
+	for i := range silences {
+		silence := &silences[i]
+		state := silence.State(now)
+		if state == kubeproberv1.SilenceStateExpired && now.Sub(silence.Spec.EndsAt.Time) >= expiredRetention {
+			if err := k8sclient.RestClient.Delete(ctx, silence); client.IgnoreNotFound(err) != nil {
+				klog.Errorf("[silence] failed to delete expired silence %s: %+v\n", silence.Name, err)
+			} else {
+				klog.Infof("[silence] deleted expired silence %s\n", silence.Name)
+			}
+			continue
+		}
+		if silence.Status.State == state {
+			continue
+		}
+		patch := client.MergeFrom(silence.DeepCopy())
+		silence.Status.State = state
+		if err := k8sclient.RestClient.Status().Patch(ctx, silence, patch); err != nil {
+			klog.Errorf("[silence] failed to update state of silence %s: %+v\n", silence.Name, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestSilenced(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	s := &Silencer{}
+	s.Load([]kubeproberv1.Silence{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: kubeproberv1.SilenceSpec{
+				Matchers: kubeproberv1.SilenceMatchers{Cluster: "c1"},
+				StartsAt: metav1.Time{Time: now.Add(-time.Hour)},
+				EndsAt:   metav1.Time{Time: now.Add(time.Hour)},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "warn"},
+			Spec: kubeproberv1.SilenceSpec{
+				Matchers: kubeproberv1.SilenceMatchers{
+					Cluster:    "c2",
+					Probe:      "k8s",
+					Severities: []kubeproberv1.CheckerStatus{kubeproberv1.CheckerStatusWARN},
+				},
+				StartsAt: metav1.Time{Time: now.Add(-time.Hour)},
+				EndsAt:   metav1.Time{Time: now.Add(time.Hour)},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "expired"},
+			Spec: kubeproberv1.SilenceSpec{
+				StartsAt: metav1.Time{Time: now.Add(-2 * time.Hour)},
+				EndsAt:   metav1.Time{Time: now.Add(-time.Hour)},
+			},
+		},
+	})
+
+	assert.Equal(t, []string{"cluster"}, s.Silenced("c1", "k8s", "dns", kubeproberv1.CheckerStatusError, now))
+	assert.Equal(t, []string{"warn"}, s.Silenced("c2", "k8s", "dns", kubeproberv1.CheckerStatusWARN, now))
+	assert.Empty(t, s.Silenced("c2", "k8s", "dns", kubeproberv1.CheckerStatusError, now))
+	assert.Empty(t, s.Silenced("c2", "node", "dns", kubeproberv1.CheckerStatusWARN, now))
+	assert.Empty(t, s.Silenced("c1", "k8s", "dns", kubeproberv1.CheckerStatusError, now.Add(time.Hour)))
+}
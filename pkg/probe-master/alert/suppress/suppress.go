@@ -0,0 +1,297 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suppress
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+const (
+	refreshInterval = 60 * time.Second
+	// name of the matcher of deprecated blackList, alerts suppressed by it are counted under it
+	BlackListMatcher = "blackList"
+)
+
+// DefaultSuppressor suppresses node alerts sent to dingding
+var DefaultSuppressor = New("dingding")
+
+// Fields are the parsed fields of a node alert
+type Fields struct {
+	Cluster   string
+	Node      string
+	Component string
+	Level     string
+	Type      string
+	// text of the alert, matched by the deprecated blackList
+	Text string
+}
+
+func (f *Fields) get(field string) string {
+	switch field {
+	case "cluster":
+		return f.Cluster
+	case "node":
+		return f.Node
+	case "component":
+		return f.Component
+	case "level":
+		return f.Level
+	case "type":
+		return f.Type
+	}
+	return ""
+}
+
+type condition struct {
+	field  string
+	negate bool
+	value  string
+	re     *regexp.Regexp
+}
+
+type matcher struct {
+	name       string
+	conditions []condition
+	expiresAt  *time.Time
+}
+
+// Suppressor suppresses node alerts by the matchers of an Alert, and counts
+// the suppressed alerts of every matcher in status of the Alert
+type Suppressor struct {
+	sync.Mutex
+	alert     string
+	matchers  []matcher
+	blackList []string
+	// suppressed counts not yet added to status
+	counts map[string]int64
+}
+
+func New(alert string) *Suppressor {
+	return &Suppressor{alert: alert, counts: make(map[string]int64)}
+}
+
+// Start refreshes matchers and saves suppressed counts until ctx is done
+func (s *Suppressor) Start(ctx context.Context) {
+	refresh := func() {
+		if err := s.Refresh(ctx); err != nil {
+			klog.Errorf("[suppress] failed to load suppress matchers of alert %s: %+v\n", s.alert, err)
+		}
+		if err := s.Save(ctx); err != nil {
+			klog.Errorf("[suppress] failed to save suppressed counts of alert %s: %+v\n", s.alert, err)
+		}
+	}
+	refresh()
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+// Refresh reloads the matchers of Alert from kubernetes
+func (s *Suppressor) Refresh(ctx context.Context) error {
+	alert := &kubeproberv1.Alert{}
+	err := k8sclient.RestClient.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: s.alert}, alert)
+	if apierrors.IsNotFound(err) {
+		s.Load(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(alert.Spec.BlackList) > 0 {
+		klog.Warningf("[suppress] blackList of alert %s is deprecated, use suppress instead\n", s.alert)
+	}
+	s.Load(alert.Spec.Suppress)
+	s.LoadBlackList(alert.Spec.BlackList)
+	return nil
+}
+
+// LoadBlackList sets the substrings of the deprecated blackList, empty ones are ignored
+func (s *Suppressor) LoadBlackList(words []string) {
+	var blackList []string
+	for _, w := range words {
+		if w != "" {
+			blackList = append(blackList, w)
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.blackList = blackList
+}
+
+// Load compiles matchers, matchers with invalid regexes are ignored
+func (s *Suppressor) Load(matchers []kubeproberv1.AlertSuppressMatcher) {
+	var compiled []matcher
+	for _, m := range matchers {
+		c, err := compile(&m)
+		if err != nil {
+			klog.Errorf("[suppress] ignore invalid matcher %s of alert %s: %+v\n", m.Name, s.alert, err)
+			continue
+		}
+		compiled = append(compiled, *c)
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.matchers = compiled
+}
+
+func compile(m *kubeproberv1.AlertSuppressMatcher) (*matcher, error) {
+	c := &matcher{name: m.Name}
+	if m.ExpiresAt != nil {
+		t := m.ExpiresAt.Time
+		c.expiresAt = &t
+	}
+	for _, cond := range m.Conditions {
+		cc := condition{field: cond.Field, value: cond.Value}
+		switch cond.Operator {
+		case kubeproberv1.SuppressOperatorNotEqual:
+			cc.negate = true
+		case kubeproberv1.SuppressOperatorRegex, kubeproberv1.SuppressOperatorNotRegex:
+			re, err := regexp.Compile("^(?:" + cond.Value + ")$")
+			if err != nil {
+				return nil, err
+			}
+			cc.re = re
+			cc.negate = cond.Operator == kubeproberv1.SuppressOperatorNotRegex
+		}
+		c.conditions = append(c.conditions, cc)
+	}
+	return c, nil
+}
+
+func (m *matcher) matches(f *Fields, now time.Time) bool {
+	if m.expiresAt != nil && !now.Before(*m.expiresAt) {
+		return false
+	}
+	for _, c := range m.conditions {
+		v := f.get(c.field)
+		matched := v == c.value
+		if c.re != nil {
+			matched = c.re.MatchString(v)
+		}
+		if matched == c.negate {
+			return false
+		}
+	}
+	return len(m.conditions) > 0
+}
+
+// Suppressed returns the name of the first unexpired matcher matching the alert, or
+// BlackListMatcher if the text contains words of blackList, empty if not suppressed
+func (s *Suppressor) Suppressed(f *Fields, now time.Time) string {
+	s.Lock()
+	defer s.Unlock()
+	for i := range s.matchers {
+		if s.matchers[i].matches(f, now) {
+			s.counts[s.matchers[i].name]++
+			return s.matchers[i].name
+		}
+	}
+	return s.blackListed(f.Text)
+}
+
+// BlackListed returns BlackListMatcher if text contains words of blackList, for alerts
+// which are not parsed into fields, empty if not suppressed
+func (s *Suppressor) BlackListed(text string) string {
+	s.Lock()
+	defer s.Unlock()
+	return s.blackListed(text)
+}
+
+func (s *Suppressor) blackListed(text string) string {
+	for _, w := range s.blackList {
+		if strings.Contains(text, w) {
+			s.counts[BlackListMatcher]++
+			return BlackListMatcher
+		}
+	}
+	return ""
+}
+
+// Save adds the suppressed counts since last save to status of Alert
+func (s *Suppressor) Save(ctx context.Context) error {
+	s.Lock()
+	counts := s.counts
+	s.counts = make(map[string]int64)
+	s.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	err := s.save(ctx, counts)
+	if err != nil {
+		// retry on next save
+		s.Lock()
+		for name, n := range counts {
+			s.counts[name] += n
+		}
+		s.Unlock()
+	}
+	return err
+}
+
+func (s *Suppressor) save(ctx context.Context, counts map[string]int64) error {
+	alert := &kubeproberv1.Alert{}
+	if err := k8sclient.RestClient.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: s.alert}, alert); err != nil {
+		return err
+	}
+	suppressed := alert.Status.Suppressed
+	if suppressed == nil {
+		suppressed = make(map[string]int64)
+	}
+	for name, n := range counts {
+		suppressed[name] += n
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"suppressed": suppressed},
+	})
+	if err != nil {
+		return err
+	}
+	return k8sclient.RestClient.Status().Patch(ctx, alert, client.RawPatch(types.MergePatchType, patch))
+}
+
+// Counts returns the suppressed counts not yet saved
+func (s *Suppressor) Counts() map[string]int64 {
+	s.Lock()
+	defer s.Unlock()
+	counts := make(map[string]int64, len(s.counts))
+	for name, n := range s.counts {
+		counts[name] = n
+	}
+	return counts
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suppress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+)
+
+func TestSuppressed(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := metav1.NewTime(now.Add(time.Hour))
+	s := New("dingding")
+	s.Load([]kubeproberv1.AlertSuppressMatcher{
+		{
+			Name: "test-clusters",
+			Conditions: []kubeproberv1.AlertSuppressCondition{
+				{Field: "cluster", Operator: kubeproberv1.SuppressOperatorRegex, Value: "test-.*"},
+				{Field: "level", Operator: kubeproberv1.SuppressOperatorNotEqual, Value: "critical"},
+			},
+		},
+		{
+			Name:      "upgrade",
+			ExpiresAt: &expiresAt,
+			Conditions: []kubeproberv1.AlertSuppressCondition{
+				{Field: "node", Value: "node-1"},
+			},
+		},
+		{
+			Name: "invalid",
+			Conditions: []kubeproberv1.AlertSuppressCondition{
+				{Field: "type", Operator: kubeproberv1.SuppressOperatorRegex, Value: "("},
+			},
+		},
+	})
+
+	assert.Equal(t, "test-clusters", s.Suppressed(&Fields{Cluster: "test-1", Level: "warning"}, now))
+	assert.Equal(t, "", s.Suppressed(&Fields{Cluster: "test-1", Level: "critical"}, now))
+	// regexes match the whole value
+	assert.Equal(t, "", s.Suppressed(&Fields{Cluster: "prod-test-1", Level: "warning"}, now))
+	assert.Equal(t, "upgrade", s.Suppressed(&Fields{Cluster: "prod", Node: "node-1"}, now))
+	assert.Equal(t, "", s.Suppressed(&Fields{Cluster: "prod", Node: "node-1"}, now.Add(time.Hour)))
+	assert.Equal(t, "", s.Suppressed(&Fields{Cluster: "prod", Type: "("}, now))
+
+	assert.Equal(t, map[string]int64{"test-clusters": 1, "upgrade": 1}, s.Counts())
+}
+
+func TestBlackList(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	s := New("dingding")
+	s.Load([]kubeproberv1.AlertSuppressMatcher{{
+		Name:       "test-clusters",
+		Conditions: []kubeproberv1.AlertSuppressCondition{{Field: "cluster", Value: "test"}},
+	}})
+	s.LoadBlackList([]string{"", "disk pressure"})
+
+	assert.Equal(t, "test-clusters", s.Suppressed(&Fields{Cluster: "test", Text: "node-1 disk pressure"}, now))
+	assert.Equal(t, BlackListMatcher, s.Suppressed(&Fields{Cluster: "prod", Text: "node-1 disk pressure"}, now))
+	assert.Equal(t, "", s.Suppressed(&Fields{Cluster: "prod", Text: "node-1 not ready"}, now))
+	assert.Equal(t, BlackListMatcher, s.BlackListed("unknown alert of disk pressure"))
+	assert.Equal(t, "", s.BlackListed("unknown alert"))
+
+	assert.Equal(t, map[string]int64{"test-clusters": 1, BlackListMatcher: 2}, s.Counts())
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// names of ticket backends
+const (
+	BackendErda   = "erda"
+	BackendJira   = "jira"
+	BackendGitHub = "github"
+)
+
+// Issue is a ticket found in or created by a ticket backend
+type Issue struct {
+	ID     string
+	Title  string
+	URL    string
+	Closed bool
+	// closed as won't fix, not reopened on recurrence
+	Ignored bool
+	// issue object of the backend
+	raw interface{}
+}
+
+// TicketBackend creates and updates tickets in an issue tracker, issues are
+// recorded by ticket fingerprints instead of titles, and labeled with the
+// fingerprint so that they are found again when records are lost
+type TicketBackend interface {
+	Name() string
+	// Get returns the issue of id, nil if it is deleted
+	Get(ctx context.Context, id string) (*Issue, error)
+	// Find returns the latest issue labeled with the fingerprint of ticket, nil if not found
+	Find(ctx context.Context, t *Ticket) (*Issue, error)
+	Create(ctx context.Context, t *Ticket) (*Issue, error)
+	// Comment adds the content of ticket to issue
+	Comment(ctx context.Context, issue *Issue, t *Ticket) error
+	Close(ctx context.Context, issue *Issue) error
+	Reopen(ctx context.Context, issue *Issue) error
+}
+
+var backends = struct {
+	sync.RWMutex
+	m map[string]TicketBackend
+}{m: make(map[string]TicketBackend)}
+
+// Register adds or replaces the backend of its name
+func Register(b TicketBackend) {
+	backends.Lock()
+	defer backends.Unlock()
+	backends.m[b.Name()] = b
+}
+
+// Get returns the backend of name, nil if not registered
+func Get(name string) TicketBackend {
+	backends.RLock()
+	defer backends.RUnlock()
+	return backends.m[name]
+}
+
+// Backends returns the names of registered backends
+func Backends() []string {
+	backends.RLock()
+	defer backends.RUnlock()
+	var names []string
+	for name := range backends.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fingerprintLabel is the label of issues, used to find issues of lost records
+func fingerprintLabel(t *Ticket) string {
+	return "kubeprober-" + t.Fingerprint
+}
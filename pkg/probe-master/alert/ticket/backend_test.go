@@ -0,0 +1,353 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   map[string]interface{}
+}
+
+type recorder struct {
+	requests []request
+}
+
+// last returns the last request of method and path
+func (r *recorder) last(method, path string) *request {
+	for i := len(r.requests) - 1; i >= 0; i-- {
+		if r.requests[i].method == method && r.requests[i].path == path {
+			return &r.requests[i]
+		}
+	}
+	return nil
+}
+
+type response struct {
+	code int
+	body string
+}
+
+// newTestServer serves responses keyed by "METHOD path", others are not found
+func newTestServer(t *testing.T, responses map[string]response) (*httptest.Server, *recorder) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		r := request{method: req.Method, path: req.URL.Path, query: req.URL.Query(), header: req.Header}
+		if b, _ := ioutil.ReadAll(req.Body); len(b) > 0 {
+			assert.NoError(t, json.Unmarshal(b, &r.body))
+		}
+		rec.requests = append(rec.requests, r)
+
+		resp, ok := responses[req.Method+" "+req.URL.Path]
+		if !ok {
+			resp = response{code: http.StatusNotFound, body: "{}"}
+		}
+		if resp.code == 0 {
+			resp.code = http.StatusOK
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(resp.code)
+		rw.Write([]byte(resp.body))
+	}))
+	return srv, rec
+}
+
+func newTicket() *Ticket {
+	return &Ticket{
+		Kind:        ErrorTicket,
+		Labels:      []string{"dns", "with space"},
+		Fingerprint: "0123456789abcdef",
+		Title:       "dns failed",
+		Content:     "content of dns",
+	}
+}
+
+func TestJira(t *testing.T) {
+	ctx := context.Background()
+	srv, rec := newTestServer(t, map[string]response{
+		"GET /rest/api/2/issue/OPS-1": {body: `{"id":"10001","key":"OPS-1","fields":{"summary":"dns failed",
+			"status":{"statusCategory":{"key":"done"}},"resolution":{"name":"Won't Do"}}}`},
+		"GET /rest/api/2/issue/OPS-2": {body: `{"id":"10002","key":"OPS-2","fields":{"summary":"dns failed",
+			"status":{"statusCategory":{"key":"indeterminate"}},"resolution":null}}`},
+		"GET /rest/api/2/issue/OPS-3": {body: `{"id":"10003","key":"OPS-3","fields":{"summary":"dns failed",
+			"status":{"statusCategory":{"key":"done"}},"resolution":{"name":"Done"}}}`},
+		"GET /rest/api/2/search": {body: `{"issues":[{"id":"10002","key":"OPS-2","fields":{"summary":"dns failed",
+			"status":{"statusCategory":{"key":"done"}}}}]}`},
+		"POST /rest/api/2/issue":                   {code: http.StatusCreated, body: `{"id":"10004","key":"OPS-4"}`},
+		"POST /rest/api/2/issue/OPS-2/comment":     {code: http.StatusCreated, body: `{}`},
+		"GET /rest/api/2/issue/OPS-2/transitions":  {body: `{"transitions":[{"id":"11","name":"To Do"},{"id":"31","name":"done"}]}`},
+		"POST /rest/api/2/issue/OPS-2/transitions": {code: http.StatusNoContent},
+	})
+	defer srv.Close()
+
+	j, err := NewJira(apistructs.JiraConfig{URL: srv.URL + "/", Project: "OPS", Username: "bot", Token: "token"})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		id      string
+		closed  bool
+		ignored bool
+	}{
+		{"OPS-1", true, true},
+		{"OPS-2", false, false},
+		{"OPS-3", true, false},
+	} {
+		issue, err := j.Get(ctx, tt.id)
+		require.NoError(t, err)
+		assert.Equal(t, tt.id, issue.ID)
+		assert.Equal(t, "dns failed", issue.Title)
+		assert.Equal(t, srv.URL+"/browse/"+tt.id, issue.URL)
+		assert.Equal(t, tt.closed, issue.Closed, tt.id)
+		assert.Equal(t, tt.ignored, issue.Ignored, tt.id)
+	}
+	r := rec.last(http.MethodGet, "/rest/api/2/issue/OPS-1")
+	assert.Equal(t, "summary,labels,status,resolution", r.query.Get("fields"))
+	user, token, ok := (&http.Request{Header: r.header}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "bot", user)
+	assert.Equal(t, "token", token)
+
+	// deleted issues are not found
+	issue, err := j.Get(ctx, "OPS-404")
+	assert.NoError(t, err)
+	assert.Nil(t, issue)
+
+	// issues are found by the fingerprint label
+	issue, err = j.Find(ctx, newTicket())
+	require.NoError(t, err)
+	assert.Equal(t, "OPS-2", issue.ID)
+	assert.True(t, issue.Closed)
+	r = rec.last(http.MethodGet, "/rest/api/2/search")
+	assert.Equal(t, `project = "OPS" AND labels = "kubeprober-0123456789abcdef" ORDER BY created DESC`, r.query.Get("jql"))
+	assert.Equal(t, "1", r.query.Get("maxResults"))
+
+	issue, err = j.Create(ctx, newTicket())
+	require.NoError(t, err)
+	assert.Equal(t, "OPS-4", issue.ID)
+	assert.Equal(t, "dns failed", issue.Title)
+	fields := rec.last(http.MethodPost, "/rest/api/2/issue").body["fields"].(map[string]interface{})
+	assert.Equal(t, "OPS", fields["project"].(map[string]interface{})["key"])
+	assert.Equal(t, "Task", fields["issuetype"].(map[string]interface{})["name"])
+	assert.Equal(t, "dns failed", fields["summary"])
+	assert.Equal(t, "content of dns", fields["description"])
+	assert.Equal(t, []interface{}{"kubeprober-0123456789abcdef", "dns"}, fields["labels"])
+
+	issue = &Issue{ID: "OPS-2"}
+	assert.NoError(t, j.Comment(ctx, issue, newTicket()))
+	assert.Equal(t, "content of dns", rec.last(http.MethodPost, "/rest/api/2/issue/OPS-2/comment").body["body"])
+
+	// transitions are looked up by name
+	assert.NoError(t, j.Close(ctx, issue))
+	transition := rec.last(http.MethodPost, "/rest/api/2/issue/OPS-2/transitions").body["transition"]
+	assert.Equal(t, "31", transition.(map[string]interface{})["id"])
+	assert.NoError(t, j.Reopen(ctx, issue))
+	transition = rec.last(http.MethodPost, "/rest/api/2/issue/OPS-2/transitions").body["transition"]
+	assert.Equal(t, "11", transition.(map[string]interface{})["id"])
+
+	j.cfg.CloseTransition = "Resolve"
+	assert.Error(t, j.Close(ctx, issue))
+}
+
+func TestGitHub(t *testing.T) {
+	ctx := context.Background()
+	srv, rec := newTestServer(t, map[string]response{
+		"GET /repos/erda/ops/issues/1": {body: `{"number":1,"title":"dns failed","html_url":"https://github.com/erda/ops/issues/1",
+			"state":"closed","labels":[{"name":"dns"},{"name":"wontfix"}]}`},
+		"GET /repos/erda/ops/issues/2":           {body: `{"number":2,"title":"dns failed","state":"open","labels":[{"name":"wontfix"}]}`},
+		"GET /repos/erda/ops/issues/3":           {body: `{"number":3,"title":"dns failed","state":"closed","labels":[]}`},
+		"GET /repos/erda/ops/issues/4":           {code: http.StatusGone, body: `{"message":"This issue was deleted"}`},
+		"GET /repos/erda/ops/issues":             {body: `[{"number":3,"title":"dns failed","state":"closed","labels":[]}]`},
+		"POST /repos/erda/ops/issues":            {code: http.StatusCreated, body: `{"number":5,"title":"dns failed","state":"open"}`},
+		"POST /repos/erda/ops/issues/2/comments": {code: http.StatusCreated, body: `{}`},
+		"PATCH /repos/erda/ops/issues/2":         {body: `{}`},
+	})
+	defer srv.Close()
+
+	_, err := NewGitHub(apistructs.GitHubConfig{Repo: "ops"})
+	assert.Error(t, err)
+	g, err := NewGitHub(apistructs.GitHubConfig{URL: srv.URL, Repo: "erda/ops", Token: "token"})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		id      string
+		closed  bool
+		ignored bool
+	}{
+		{"1", true, true},
+		{"2", false, false},
+		{"3", true, false},
+	} {
+		issue, err := g.Get(ctx, tt.id)
+		require.NoError(t, err)
+		assert.Equal(t, tt.id, issue.ID)
+		assert.Equal(t, tt.closed, issue.Closed, tt.id)
+		assert.Equal(t, tt.ignored, issue.Ignored, tt.id)
+	}
+	assert.Equal(t, "token token", rec.last(http.MethodGet, "/repos/erda/ops/issues/1").header.Get("Authorization"))
+
+	// deleted or transferred issues are not found
+	for _, id := range []string{"4", "404"} {
+		issue, err := g.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Nil(t, issue)
+	}
+
+	issue, err := g.Find(ctx, newTicket())
+	require.NoError(t, err)
+	assert.Equal(t, "3", issue.ID)
+	query := rec.last(http.MethodGet, "/repos/erda/ops/issues").query
+	assert.Equal(t, "kubeprober-0123456789abcdef", query.Get("labels"))
+	assert.Equal(t, "all", query.Get("state"))
+	assert.Equal(t, "1", query.Get("per_page"))
+
+	issue, err = g.Create(ctx, newTicket())
+	require.NoError(t, err)
+	assert.Equal(t, "5", issue.ID)
+	body := rec.last(http.MethodPost, "/repos/erda/ops/issues").body
+	assert.Equal(t, "dns failed", body["title"])
+	assert.Equal(t, "content of dns", body["body"])
+	assert.Equal(t, []interface{}{"kubeprober-0123456789abcdef", "dns"}, body["labels"])
+
+	issue = &Issue{ID: "2"}
+	assert.NoError(t, g.Comment(ctx, issue, newTicket()))
+	assert.Equal(t, "content of dns", rec.last(http.MethodPost, "/repos/erda/ops/issues/2/comments").body["body"])
+	assert.NoError(t, g.Close(ctx, issue))
+	assert.Equal(t, "closed", rec.last(http.MethodPatch, "/repos/erda/ops/issues/2").body["state"])
+	assert.NoError(t, g.Reopen(ctx, issue))
+	assert.Equal(t, "open", rec.last(http.MethodPatch, "/repos/erda/ops/issues/2").body["state"])
+}
+
+func TestErda(t *testing.T) {
+	ctx := context.Background()
+	responses := map[string]response{
+		"GET /api/labels":   {body: `{"success":true,"data":{"total":1,"list":[{"id":8,"name":"kubeprober-0123456789abcdef0"}]}}`},
+		"POST /api/labels":  {body: `{"success":true,"data":9}`},
+		"GET /api/issues":   {body: `{"success":true,"data":{"total":1,"list":[{"id":4,"title":"dns failed","state":3}]}}`},
+		"GET /api/issues/1": {body: `{"success":true,"data":{"id":1,"title":"dns failed","state":3,"labels":["dns","暂不修复"]}}`},
+		"GET /api/issues/2": {body: `{"success":true,"data":{"id":2,"title":"dns failed","state":1,"labels":["dns","暂不修复"]}}`},
+		"GET /api/issues/3": {body: `{"success":true,"data":{"id":3,"title":"dns failed","state":4,"labels":["暂不修复"]}}`},
+		"GET /api/issues/4": {body: `{"success":true,"data":{"id":4,"title":"dns failed","state":3,"labels":[]}}`},
+		"POST /api/issues":  {body: `{"success":true,"data":5}`},
+		"POST /api/issues/actions/batch-create-comment-stream": {body: `{"success":true}`},
+		"PUT /api/issues/2": {body: `{"success":true,"data":2}`},
+	}
+	srv, rec := newTestServer(t, responses)
+	defer srv.Close()
+
+	u := &ErdaIdentity{
+		OpenapiUrl:       srv.URL,
+		ProjectId:        10,
+		StateIds:         []int64{1, 2, 3, 4},
+		UserID:           "1001",
+		OrgID:            1,
+		SessionID:        "session",
+		Assignee:         "1002",
+		TodoStateId:      1,
+		ReopenStateId:    2,
+		NoprocessStateId: 3,
+		SolvedStateId:    4,
+		Labels:           map[string]interface{}{"dns": struct{}{}},
+		client:           resty.New(),
+	}
+
+	for _, tt := range []struct {
+		id      string
+		closed  bool
+		ignored bool
+	}{
+		{"1", true, true},
+		{"2", false, false},
+		{"3", true, false},
+		{"4", true, false},
+	} {
+		issue, err := u.Get(ctx, tt.id)
+		require.NoError(t, err)
+		assert.Equal(t, tt.id, issue.ID)
+		assert.Equal(t, tt.closed, issue.Closed, tt.id)
+		assert.Equal(t, tt.ignored, issue.Ignored, tt.id)
+	}
+	assert.Equal(t, "1001", rec.last(http.MethodGet, "/api/issues/1").header.Get("USER-ID"))
+
+	issue, err := u.Get(ctx, "404")
+	assert.NoError(t, err)
+	assert.Nil(t, issue)
+	_, err = u.Get(ctx, "OPS-1")
+	assert.Error(t, err)
+
+	// labels are matched fuzzily, issues are not found without the fingerprint label
+	issue, err = u.Find(ctx, newTicket())
+	assert.NoError(t, err)
+	assert.Nil(t, issue)
+	assert.Equal(t, "kubeprober-0123456789abcdef", rec.last(http.MethodGet, "/api/labels").query.Get("key"))
+
+	// the fingerprint label is created with the issue
+	issue, err = u.Create(ctx, newTicket())
+	require.NoError(t, err)
+	assert.Equal(t, "5", issue.ID)
+	label := rec.last(http.MethodPost, "/api/labels").body
+	assert.Equal(t, "kubeprober-0123456789abcdef", label["name"])
+	assert.Equal(t, "issue", label["type"])
+	assert.Equal(t, float64(10), label["projectID"])
+	body := rec.last(http.MethodPost, "/api/issues").body
+	assert.Equal(t, "dns failed", body["title"])
+	assert.Equal(t, "1002", body["assignee"])
+	// labels not existing in the project are dropped
+	assert.Equal(t, []interface{}{"dns", "kubeprober-0123456789abcdef"}, body["labels"])
+
+	responses["GET /api/labels"] = response{body: `{"success":true,"data":{"total":2,"list":[
+		{"id":8,"name":"kubeprober-0123456789abcdef0"},{"id":9,"name":"kubeprober-0123456789abcdef"}]}}`}
+	issue, err = u.Find(ctx, newTicket())
+	require.NoError(t, err)
+	assert.Equal(t, "4", issue.ID)
+	assert.True(t, issue.Closed)
+	query := rec.last(http.MethodGet, "/api/issues").query
+	assert.Equal(t, []string{"9"}, query["label"])
+	assert.Equal(t, []string{"1", "2", "3", "4"}, query["state"])
+	assert.Equal(t, "1", query.Get("pageSize"))
+
+	issue, err = u.Get(ctx, "2")
+	require.NoError(t, err)
+	ticket := newTicket()
+	ticket.Labels = []string{"dns", "etcd"}
+	u.Labels["etcd"] = struct{}{}
+	assert.NoError(t, u.Comment(ctx, issue, ticket))
+	stream := rec.last(http.MethodPost, "/api/issues/actions/batch-create-comment-stream").body["issueStreams"].([]interface{})[0]
+	assert.Equal(t, "content of dns", stream.(map[string]interface{})["content"])
+	assert.Equal(t, float64(2), stream.(map[string]interface{})["issueID"])
+	update := rec.last(http.MethodPut, "/api/issues/2").body
+	assert.Equal(t, float64(1), update["state"])
+	assert.Equal(t, []interface{}{"dns", "暂不修复", "etcd"}, update["labels"])
+
+	assert.NoError(t, u.Close(ctx, issue))
+	assert.Equal(t, float64(3), rec.last(http.MethodPut, "/api/issues/2").body["state"])
+	assert.NoError(t, u.Reopen(ctx, issue))
+	assert.Equal(t, float64(2), rec.last(http.MethodPut, "/api/issues/2").body["state"])
+}
@@ -14,6 +14,7 @@
 package ticket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -29,8 +30,12 @@ import (
 
 	erda_api "github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
 )
 
+// color of fingerprint labels created in erda project
+const erdaLabelColor = "gray"
+
 type ErdaIdentity struct {
 	UserName   string
 	Password   string
@@ -70,6 +75,7 @@ func Init(loginUser, loginPassword, openapiURL, orgName string, projectId uint64
 		return err
 	} else {
 		sender = erdaIdentity
+		Register(erdaIdentity)
 
 		err = sender.GetTicketStates()
 		if err != nil {
@@ -232,7 +238,7 @@ func (u *ErdaIdentity) GetTicketStates() error {
 }
 
 func (u *ErdaIdentity) GetAssignee() error {
-	today := time.Now().Format("2006-01-02")
+	today := timezone.Day(time.Now())
 	resp, err := u.client.R().Get(fmt.Sprintf("https://onduty.app.terminus.io/sre?date=%s", today))
 	if err != nil {
 		return err
@@ -289,7 +295,7 @@ func (u *ErdaIdentity) SearchUser(username string) (*erda_api.UserInfo, error) {
 	return &r.Data.Users[0], nil
 }
 
-func (u *ErdaIdentity) CreateIssue(req *erda_api.IssueCreateRequest) error {
+func (u *ErdaIdentity) CreateIssue(req *erda_api.IssueCreateRequest) (uint64, error) {
 	klog.Errorf("start send ticket to cloud address: %s\n", u.OpenapiUrl)
 	resp, err := u.client.R().SetBody(req).
 		SetCookie(&http.Cookie{Name: "OPENAPISESSION", Value: u.SessionID}).
@@ -297,21 +303,21 @@ func (u *ErdaIdentity) CreateIssue(req *erda_api.IssueCreateRequest) error {
 		SetHeader("Org-ID", strconv.FormatUint(u.OrgID, 10)).
 		Post(strings.Join([]string{u.OpenapiUrl, "/api/issues"}, ""))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	r := erda_api.IssueCreateResponse{}
 	err = unmarshalResponse(resp, &r)
 	if err != nil {
 		logrus.Errorf("unmarshal response failed, error: %v", err)
-		return err
+		return 0, err
 	}
 	if !r.Success || r.Error.Msg != "" {
 		err = fmt.Errorf("%v", r.Error)
-		return err
+		return 0, err
 	}
 
-	return nil
+	return r.Data, nil
 }
 
 func (u *ErdaIdentity) UpdateIssue(req *erda_api.IssueUpdateRequest) error {
@@ -339,10 +345,13 @@ func (u *ErdaIdentity) UpdateIssue(req *erda_api.IssueUpdateRequest) error {
 }
 
 func (u *ErdaIdentity) PagingIssue(req *erda_api.IssuePagingRequest) ([]erda_api.Issue, error) {
-	reqStates := url.Values{"state": []string{}}
+	reqStates := url.Values{"state": []string{}, "label": []string{}}
 	for _, s := range req.State {
 		reqStates["state"] = append(reqStates["state"], strconv.FormatInt(s, 10))
 	}
+	for _, l := range req.Label {
+		reqStates["label"] = append(reqStates["label"], strconv.FormatUint(l, 10))
+	}
 
 	resp, err := u.client.R().
 		SetCookie(&http.Cookie{Name: "OPENAPISESSION", Value: u.SessionID}).
@@ -428,6 +437,73 @@ func (u *ErdaIdentity) GetLabels() error {
 	return nil
 }
 
+// GetLabelID returns the id of issue label of name, 0 if not found
+func (u *ErdaIdentity) GetLabelID(name string) (int64, error) {
+	resp, err := u.client.R().
+		SetCookie(&http.Cookie{Name: "OPENAPISESSION", Value: u.SessionID}).
+		SetHeader("USER-ID", u.UserID).
+		SetQueryParam("type", "issue").
+		SetQueryParam("projectID", strconv.FormatUint(u.ProjectId, 10)).
+		SetQueryParam("key", name).
+		SetQueryParam("pageSize", "100").
+		Get(strings.Join([]string{u.OpenapiUrl, "/api/labels"}, ""))
+	if err != nil {
+		return 0, err
+	}
+
+	r := &erda_api.ProjectLabelListResponse{}
+	err = unmarshalResponse(resp, &r)
+	if err != nil {
+		logrus.Errorf("unmarshal response failed, error: %v", err)
+		return 0, err
+	}
+	if !r.Success || r.Error.Msg != "" {
+		err = fmt.Errorf("%v", r.Error)
+		return 0, err
+	}
+
+	// key is matched fuzzily
+	if r.Data != nil {
+		for _, l := range r.Data.List {
+			if l.Name == name {
+				return l.ID, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// CreateLabel creates the issue label of name in the project
+func (u *ErdaIdentity) CreateLabel(name string) (int64, error) {
+	req := &erda_api.ProjectLabelCreateRequest{
+		Name:      name,
+		Type:      erda_api.LabelTypeIssue,
+		Color:     erdaLabelColor,
+		ProjectID: u.ProjectId,
+	}
+	resp, err := u.client.R().SetBody(req).
+		SetCookie(&http.Cookie{Name: "OPENAPISESSION", Value: u.SessionID}).
+		SetHeader("USER-ID", u.UserID).
+		SetHeader("Org-ID", strconv.FormatUint(u.OrgID, 10)).
+		Post(strings.Join([]string{u.OpenapiUrl, "/api/labels"}, ""))
+	if err != nil {
+		return 0, err
+	}
+
+	r := erda_api.ProjectLabelCreateResponse{}
+	err = unmarshalResponse(resp, &r)
+	if err != nil {
+		logrus.Errorf("unmarshal response failed, error: %v", err)
+		return 0, err
+	}
+	if !r.Success || r.Error.Msg != "" {
+		err = fmt.Errorf("%v", r.Error)
+		return 0, err
+	}
+
+	return r.Data, nil
+}
+
 func (u *ErdaIdentity) CreateIssueComment(req *apistructs.CommentIssueStreamBatchCreateRequest) error {
 	resp, err := u.client.R().SetBody(req).
 		SetCookie(&http.Cookie{Name: "OPENAPISESSION", Value: u.SessionID}).
@@ -451,3 +527,194 @@ func (u *ErdaIdentity) CreateIssueComment(req *apistructs.CommentIssueStreamBatc
 
 	return nil
 }
+
+func (u *ErdaIdentity) GetIssue(id uint64) (*erda_api.Issue, error) {
+	resp, err := u.client.R().
+		SetCookie(&http.Cookie{Name: "OPENAPISESSION", Value: u.SessionID}).
+		SetHeader("USER-ID", u.UserID).
+		SetHeader("Org-ID", strconv.FormatUint(u.OrgID, 10)).
+		Get(fmt.Sprintf("%s/api/issues/%d", u.OpenapiUrl, id))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+
+	r := erda_api.IssueGetResponse{}
+	err = unmarshalResponse(resp, &r)
+	if err != nil {
+		logrus.Errorf("unmarshal response failed for issue %d, error: %v", id, err)
+		return nil, err
+	}
+	if !r.Success || r.Error.Msg != "" {
+		err = fmt.Errorf("%v", r.Error)
+		return nil, err
+	}
+
+	return r.Data, nil
+}
+
+func (u *ErdaIdentity) Name() string {
+	return BackendErda
+}
+
+func (u *ErdaIdentity) Get(ctx context.Context, id string) (*Issue, error) {
+	issueID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("invalid erda issue id %s", id)
+	}
+	issue, err := u.GetIssue(issueID)
+	if err != nil || issue == nil {
+		return nil, err
+	}
+	return u.toIssue(issue), nil
+}
+
+// Find pages issues by the fingerprint label
+func (u *ErdaIdentity) Find(ctx context.Context, t *Ticket) (*Issue, error) {
+	labelID, err := u.GetLabelID(fingerprintLabel(t))
+	if err != nil || labelID == 0 {
+		return nil, err
+	}
+
+	req := &erda_api.IssuePagingRequest{}
+	req.ProjectID = u.ProjectId
+	req.Label = []uint64{uint64(labelID)}
+	req.State = u.StateIds
+	req.PageSize = 1
+
+	issues, err := u.PagingIssue(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return u.toIssue(&issues[0]), nil
+}
+
+// toIssue converts erda issue, issues of noprocess state labeled 暂不修复 are ignored
+func (u *ErdaIdentity) toIssue(issue *erda_api.Issue) *Issue {
+	ignored := false
+	for _, l := range issue.Labels {
+		if l == "暂不修复" {
+			ignored = true
+		}
+	}
+	closed := issue.State == u.NoprocessStateId || issue.State == u.SolvedStateId
+	return &Issue{
+		ID:      strconv.FormatInt(issue.ID, 10),
+		Title:   issue.Title,
+		Closed:  closed,
+		Ignored: ignored && issue.State == u.NoprocessStateId,
+		raw:     issue,
+	}
+}
+
+func (u *ErdaIdentity) Create(ctx context.Context, t *Ticket) (*Issue, error) {
+	now := time.Now()
+
+	req := &erda_api.IssueCreateRequest{}
+	req.Title = t.Title
+	req.Content = t.Content
+	req.Priority = t.Priority
+	req.Type = t.Type
+	req.PlanStartedAt = &now
+	req.Assignee = u.Assignee
+	req.IterationID = -1
+
+	req.UserID = u.UserID
+	req.ProjectID = u.ProjectId
+
+	logrus.Infof("server label %+v", u.Labels)
+	logrus.Infof("ticket label %+v", t.Labels)
+
+	// erda only accepts labels existing in the project
+	label := fingerprintLabel(t)
+	labelID, err := u.GetLabelID(label)
+	if err != nil {
+		return nil, err
+	}
+	if labelID == 0 {
+		if _, err = u.CreateLabel(label); err != nil {
+			return nil, err
+		}
+	}
+	req.Labels = append(u.newLabels(t), label)
+
+	id, err := u.CreateIssue(req)
+	if err != nil {
+		return nil, err
+	}
+	return &Issue{ID: strconv.FormatUint(id, 10), Title: req.Title}, nil
+}
+
+// Comment adds the content as comment, and assigns the issue to the person on duty
+func (u *ErdaIdentity) Comment(ctx context.Context, issue *Issue, t *Ticket) error {
+	raw := issue.raw.(*erda_api.Issue)
+	comment := &apistructs.CommentIssueStreamCreateRequest{
+		IssueID: raw.ID,
+		Type:    string(erda_api.ISTComment),
+		UserID:  u.UserID,
+		Content: t.Content,
+	}
+	if err := u.CreateIssueComment(&apistructs.CommentIssueStreamBatchCreateRequest{
+		IssueStreams: []*apistructs.CommentIssueStreamCreateRequest{comment},
+	}); err != nil {
+		return err
+	}
+
+	raw.Labels = mergeLabels(raw.Labels, u.newLabels(t))
+	return u.updateState(raw, raw.State)
+}
+
+func (u *ErdaIdentity) Close(ctx context.Context, issue *Issue) error {
+	return u.updateState(issue.raw.(*erda_api.Issue), u.NoprocessStateId)
+}
+
+func (u *ErdaIdentity) Reopen(ctx context.Context, issue *Issue) error {
+	return u.updateState(issue.raw.(*erda_api.Issue), u.ReopenStateId)
+}
+
+func (u *ErdaIdentity) updateState(issue *erda_api.Issue, state int64) error {
+	issue.State = state
+	reqU := &erda_api.IssueUpdateRequest{}
+	reqU.ID = uint64(issue.ID)
+	reqU.Title = &issue.Title
+	reqU.Priority = &issue.Priority
+	reqU.State = &issue.State
+	reqU.Assignee = &u.Assignee
+	reqU.UserID = u.UserID
+	reqU.Labels = issue.Labels
+	return u.UpdateIssue(reqU)
+}
+
+// newLabels returns the labels of ticket existing in erda project
+func (u *ErdaIdentity) newLabels(t *Ticket) []string {
+	var labels []string
+	for _, l := range t.Labels {
+		if _, ok := u.Labels[l]; ok {
+			labels = append(labels, l)
+		}
+	}
+
+	return labels
+}
+
+func mergeLabels(oldL, newL []string) []string {
+	newLabels := []string{}
+	oldMap := map[string]interface{}{}
+	for _, l := range oldL {
+		oldMap[l] = struct{}{}
+		newLabels = append(newLabels, l)
+	}
+
+	for _, nl := range newL {
+		if _, ok := oldMap[nl]; !ok {
+			newLabels = append(newLabels, nl)
+		}
+	}
+
+	return newLabels
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const defaultGitHubURL = "https://api.github.com"
+
+// GitHub creates tickets as github issues, the fingerprint of ticket is an issue label
+type GitHub struct {
+	repo   string
+	client *resty.Client
+}
+
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	State   string `json:"state"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+func NewGitHub(cfg apistructs.GitHubConfig) (*GitHub, error) {
+	if cfg.Repo == "" || len(strings.Split(cfg.Repo, "/")) != 2 {
+		return nil, errors.Errorf("invalid github repo %q, owner/repo is required", cfg.Repo)
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultGitHubURL
+	}
+	client := resty.New().
+		SetHostURL(strings.TrimSuffix(cfg.URL, "/")).
+		SetHeader("Accept", "application/vnd.github.v3+json").
+		SetTimeout(10 * time.Second).
+		SetRetryCount(3).SetRetryWaitTime(3 * time.Second)
+	if cfg.Token != "" {
+		client.SetHeader("Authorization", "token "+cfg.Token)
+	}
+	return &GitHub{repo: cfg.Repo, client: client}, nil
+}
+
+func (g *GitHub) Name() string {
+	return BackendGitHub
+}
+
+func (g *GitHub) Get(ctx context.Context, id string) (*Issue, error) {
+	result := &githubIssue{}
+	resp, err := g.client.R().SetContext(ctx).
+		SetResult(result).
+		Get(fmt.Sprintf("/repos/%s/issues/%s", g.repo, id))
+	if err == nil && (resp.StatusCode() == http.StatusNotFound || resp.StatusCode() == http.StatusGone) {
+		return nil, nil
+	}
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+	return githubToIssue(result), nil
+}
+
+func (g *GitHub) Find(ctx context.Context, t *Ticket) (*Issue, error) {
+	var issues []githubIssue
+	resp, err := g.client.R().SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"labels":    fingerprintLabel(t),
+			"state":     "all",
+			"sort":      "created",
+			"direction": "desc",
+			"per_page":  "1",
+		}).
+		SetResult(&issues).
+		Get(fmt.Sprintf("/repos/%s/issues", g.repo))
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return githubToIssue(&issues[0]), nil
+}
+
+func (g *GitHub) Create(ctx context.Context, t *Ticket) (*Issue, error) {
+	created := &githubIssue{}
+	resp, err := g.client.R().SetContext(ctx).
+		SetBody(map[string]interface{}{
+			"title":  t.Title,
+			"body":   t.Content,
+			"labels": issueLabels(t),
+		}).
+		SetResult(created).
+		Post(fmt.Sprintf("/repos/%s/issues", g.repo))
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+	return githubToIssue(created), nil
+}
+
+func (g *GitHub) Comment(ctx context.Context, issue *Issue, t *Ticket) error {
+	resp, err := g.client.R().SetContext(ctx).
+		SetBody(map[string]string{"body": t.Content}).
+		Post(fmt.Sprintf("/repos/%s/issues/%s/comments", g.repo, issue.ID))
+	return checkResponse(resp, err)
+}
+
+func (g *GitHub) Close(ctx context.Context, issue *Issue) error {
+	return g.setState(ctx, issue, "closed")
+}
+
+func (g *GitHub) Reopen(ctx context.Context, issue *Issue) error {
+	return g.setState(ctx, issue, "open")
+}
+
+func (g *GitHub) setState(ctx context.Context, issue *Issue, state string) error {
+	resp, err := g.client.R().SetContext(ctx).
+		SetBody(map[string]string{"state": state}).
+		Patch(fmt.Sprintf("/repos/%s/issues/%s", g.repo, issue.ID))
+	return checkResponse(resp, err)
+}
+
+// githubToIssue converts github issue, closed issues labeled wontfix are ignored
+func githubToIssue(i *githubIssue) *Issue {
+	issue := &Issue{
+		ID:     strconv.Itoa(i.Number),
+		Title:  i.Title,
+		URL:    i.HTMLURL,
+		Closed: i.State == "closed",
+		raw:    i,
+	}
+	for _, l := range i.Labels {
+		if l.Name == "wontfix" {
+			issue.Ignored = issue.Closed
+		}
+	}
+	return issue
+}
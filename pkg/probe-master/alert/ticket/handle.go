@@ -14,62 +14,35 @@
 package ticket
 
 import (
-	"fmt"
+	"context"
+	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"k8s.io/klog"
 
 	erda_api "github.com/erda-project/erda/apistructs"
-	"github.com/erda-project/kubeprober/apistructs"
 )
 
-var (
-	sendIssueCh = make(chan *Ticket, 100)
-	sender      *ErdaIdentity
+// DefaultBackend receives tickets of node alerts
+const DefaultBackend = BackendErda
+
+const (
+	// DefaultCommentInterval is the minimum interval between comments of error tickets on an issue
+	DefaultCommentInterval = 1 * time.Hour
+	// records of closed issues are kept for reopening on recurrence
+	closedRetention = 30 * 24 * time.Hour
+	flushInterval   = 5 * time.Second
 )
 
-func init() {
-	initWorker()
-}
+var (
+	sender *ErdaIdentity
 
-func initWorker() {
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		for {
-			select {
-			case t := <-sendIssueCh:
-				t.Title = fmt.Sprintf("%s-{%s}", t.Title, GetWeek())
-				err := sendIssue(t)
-				if err != nil {
-					klog.Errorf("send ticket failed, %v", err)
-				}
-			case <-ticker.C:
-				if sender != nil {
-					err := sender.GetUserID()
-					if err != nil {
-						klog.Errorf("user login failed, %v", err)
-					}
-
-					err = sender.GetTicketStates()
-					if err != nil {
-						klog.Errorf("get ticket states failed, %v", err)
-					}
-
-					err = sender.GetAssignee()
-					if err != nil {
-						klog.Errorf("get assingee failed, %v", err)
-					}
-
-					err = sender.GetLabels()
-					if err != nil {
-						klog.Errorf("get labels failed, %v", err)
-					}
-				}
-			}
-		}
-	}()
-}
+	tracker = &ticketTracker{
+		records:         make(map[string]*Record),
+		commentInterval: DefaultCommentInterval,
+		notify:          make(chan struct{}, 1),
+	}
+)
 
 type TicketKind string
 
@@ -79,182 +52,319 @@ const (
 )
 
 type Ticket struct {
-	Kind   TicketKind
-	Labels []string
-
-	Title    string
-	Content  string
-	Priority erda_api.IssuePriority
-	Type     erda_api.IssueType
+	Kind   TicketKind `json:"kind"`
+	Labels []string   `json:"labels,omitempty"`
+	// stable identity of the ticket, issues are recorded by it
+	// instead of title, so that titles can be changed by templates
+	Fingerprint string `json:"fingerprint"`
+
+	Title    string                 `json:"title"`
+	Content  string                 `json:"content"`
+	Priority erda_api.IssuePriority `json:"priority,omitempty"`
+	Type     erda_api.IssueType     `json:"type,omitempty"`
 }
 
-func GetWeek() string {
-	timeLayout := "20060102"
-	now := time.Now().Unix()
-	datetime := time.Unix(now+8*3600, 0).Format(timeLayout)
-
-	loc, _ := time.LoadLocation("Asia/Shanghai")
-	tmp, _ := time.ParseInLocation(timeLayout, datetime, loc)
-	y, w := tmp.ISOWeek()
-	return fmt.Sprintf("%d-%d", y, w)
+// ticketTracker records issues of ticket fingerprints, and keeps the latest unsent ticket of each
+type ticketTracker struct {
+	sync.Mutex
+	records         map[string]*Record
+	commentInterval time.Duration
+	dirty           bool
+	notify          chan struct{}
 }
 
-func sendIssue(t *Ticket) error {
-	klog.Errorf("start send ticket to cloud, %+v\n", t)
-	issue, err := existIssue(t)
-	if err != nil {
-		return err
+// Start restores ticket records from store, and sends pending tickets and saves records in background
+func Start(ctx context.Context, store Store, commentInterval time.Duration) {
+	tracker.Lock()
+	if commentInterval > 0 {
+		tracker.commentInterval = commentInterval
 	}
+	tracker.Unlock()
 
-	if issue != nil {
-
-		reqU := &erda_api.IssueUpdateRequest{}
-		reqU.ID = uint64(issue.ID)
-		reqU.Title = &issue.Title
-		reqU.Priority = &issue.Priority
-
-		if t.Kind == PassTicket {
-			// duplicate msg
-			if issue.State != sender.TodoStateId &&
-				issue.State != sender.ReopenStateId {
-				return nil
-			}
-			reqU.State = &sender.NoprocessStateId
-		} else {
-			reqU.State = &issue.State
-			if issue.State == sender.NoprocessStateId ||
-				issue.State == sender.SolvedStateId {
-				reqU.State = &sender.ReopenStateId
-			}
+	records, err := store.Load(ctx)
+	if err != nil {
+		klog.Errorf("failed to load ticket records: %+v\n", err)
+	}
+	tracker.restore(records)
 
-			foundNoHandleLabel := false
-			for _, l := range issue.Labels {
-				if l == "暂不修复" {
-					foundNoHandleLabel = true
-				}
-			}
-			if foundNoHandleLabel && issue.State == sender.NoprocessStateId {
-				return nil
+	go func() {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		refreshTicker := time.NewTicker(1 * time.Hour)
+		defer refreshTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				tracker.save(context.Background(), store)
+				return
+			case <-tracker.notify:
+			case <-ticker.C:
+			case <-refreshTicker.C:
+				refreshErda()
+				continue
 			}
+			tracker.flush(ctx, time.Now())
+			tracker.save(ctx, store)
 		}
+	}()
+}
 
-		err = createIssueComment(issue.ID, t.Content)
-		if err != nil {
-			return err
-		}
-
-		reqU.Assignee = &sender.Assignee
-		reqU.UserID = sender.UserID
+func refreshErda() {
+	if sender == nil {
+		return
+	}
+	err := sender.GetUserID()
+	if err != nil {
+		klog.Errorf("user login failed, %v", err)
+	}
 
-		reqU.Labels = getLabels(issue.Labels, t.newLabels())
+	err = sender.GetTicketStates()
+	if err != nil {
+		klog.Errorf("get ticket states failed, %v", err)
+	}
 
-		return updateIssue(reqU)
+	err = sender.GetAssignee()
+	if err != nil {
+		klog.Errorf("get assingee failed, %v", err)
 	}
 
-	// no need to send issue
-	if t.Kind == PassTicket {
-		return nil
+	err = sender.GetLabels()
+	if err != nil {
+		klog.Errorf("get labels failed, %v", err)
 	}
+}
 
-	return createIssue(t)
+// SendTicket queues the ticket of node alerts to the default backend
+func SendTicket(t *Ticket) {
+	SendTicketTo(DefaultBackend, t)
 }
 
-func createIssueComment(issueID int64, content string) error {
-	comment := &apistructs.CommentIssueStreamCreateRequest{
-		IssueID: issueID,
-		Type:    string(erda_api.ISTComment),
-		UserID:  sender.UserID,
-		Content: content,
+// SendTicketTo queues the ticket to backend, it replaces the unsent ticket of
+// the same fingerprint. pass tickets are only sent for fingerprints with open issues
+func SendTicketTo(backend string, t *Ticket) {
+	if Get(backend) == nil {
+		return
 	}
-	relateReq := apistructs.CommentIssueStreamBatchCreateRequest{
-		IssueStreams: []*apistructs.CommentIssueStreamCreateRequest{comment},
+	if tracker.add(backend, t, time.Now()) {
+		select {
+		case tracker.notify <- struct{}{}:
+		default:
+		}
 	}
-	return sender.CreateIssueComment(&relateReq)
 }
 
-func getLabels(oldL, newL []string) []string {
-	newLabels := []string{}
-	oldMap := map[string]interface{}{}
-	for _, l := range oldL {
-		oldMap[l] = struct{}{}
-		newLabels = append(newLabels, l)
+// Records returns a copy of ticket records
+func Records() []Record {
+	tracker.Lock()
+	defer tracker.Unlock()
+	records := make([]Record, 0, len(tracker.records))
+	for _, r := range tracker.records {
+		records = append(records, *r)
 	}
+	return records
+}
 
-	for _, nl := range newL {
-		if _, ok := oldMap[nl]; !ok {
-			newLabels = append(newLabels, nl)
+// restore adds records loaded from store, records changed since start are kept
+func (tr *ticketTracker) restore(records []*Record) {
+	tr.Lock()
+	defer tr.Unlock()
+	for _, r := range records {
+		if _, ok := tr.records[r.Key()]; !ok {
+			tr.records[r.Key()] = r
 		}
 	}
-
-	return newLabels
 }
 
-func updateIssue(req *erda_api.IssueUpdateRequest) error {
-	return sender.UpdateIssue(req)
+// add sets the ticket as pending of its record, returns false if nothing is to be sent
+func (tr *ticketTracker) add(backend string, t *Ticket, now time.Time) bool {
+	tr.Lock()
+	defer tr.Unlock()
+	key := recordKey(backend, t.Fingerprint)
+	r := tr.records[key]
+	if t.Kind == PassTicket {
+		switch {
+		case r == nil:
+			return false
+		case r.IssueID == "":
+			// recovered before the issue is created, the error ticket may be being sent, so keep
+			// the pass ticket to close the created issue, the record is deleted if none is created
+			if r.Pending == nil || r.Pending.Kind == PassTicket {
+				return false
+			}
+		case r.Closed:
+			if r.Pending != nil {
+				r.Pending = nil
+				tr.dirty = true
+			}
+			return false
+		}
+	}
+	if r == nil {
+		r = &Record{Backend: backend, Fingerprint: t.Fingerprint, CreatedAt: now}
+		tr.records[key] = r
+	}
+	r.Pending = t
+	r.UpdatedAt = now
+	tr.dirty = true
+	return true
 }
 
-func createIssue(t *Ticket) error {
-	now := time.Now()
-
-	req := &erda_api.IssueCreateRequest{}
-	req.Title = t.Title
-	req.Content = t.Content
-	req.Priority = t.Priority
-	req.Type = t.Type
-	req.PlanStartedAt = &now
-	req.Assignee = sender.Assignee
-	req.IterationID = -1
-
-	req.UserID = sender.UserID
-	req.ProjectID = sender.ProjectId
-
-	logrus.Infof("server label %+v", sender.Labels)
-	logrus.Infof("ticket label %+v", t.Labels)
-
-	req.Labels = t.newLabels()
-
-	return sender.CreateIssue(req)
+// due returns copies of records with tickets to send, error tickets on open or
+// ignored issues wait for the comment interval
+func (tr *ticketTracker) due(now time.Time) []*Record {
+	tr.Lock()
+	defer tr.Unlock()
+	var records []*Record
+	for key, r := range tr.records {
+		if r.Pending == nil {
+			if r.Closed && now.Sub(r.UpdatedAt) > closedRetention {
+				delete(tr.records, key)
+				tr.dirty = true
+			}
+			continue
+		}
+		if Get(r.Backend) == nil {
+			continue
+		}
+		if r.Pending.Kind == ErrorTicket && r.IssueID != "" && (!r.Closed || r.Ignored) &&
+			now.Sub(r.CommentedAt) < tr.commentInterval {
+			continue
+		}
+		c := *r
+		records = append(records, &c)
+	}
+	return records
 }
 
-func (t *Ticket) newLabels() []string {
-	var labels []string
-	for _, l := range t.Labels {
-		if _, ok := sender.Labels[l]; ok {
-			labels = append(labels, l)
+func (tr *ticketTracker) flush(ctx context.Context, now time.Time) {
+	for _, r := range tr.due(now) {
+		pending := r.Pending
+		if err := send(ctx, Get(r.Backend), r, now); err != nil {
+			klog.Errorf("send ticket %s to %s failed, %v", r.Fingerprint, r.Backend, err)
+			continue
 		}
+		r.UpdatedAt = now
+
+		tr.Lock()
+		cur, ok := tr.records[r.Key()]
+		switch {
+		case ok && cur.Pending != pending:
+			// a newer ticket arrived while sending, e.g. the pass ticket closing the issue being created
+			r.Pending = cur.Pending
+			tr.records[r.Key()] = r
+		case pending.Kind == PassTicket && r.IssueID == "":
+			// recovered without any issue
+			delete(tr.records, r.Key())
+		default:
+			r.Pending = nil
+			tr.records[r.Key()] = r
+		}
+		tr.dirty = true
+		tr.Unlock()
 	}
+}
 
-	return labels
+func (tr *ticketTracker) save(ctx context.Context, store Store) {
+	tr.Lock()
+	if !tr.dirty {
+		tr.Unlock()
+		return
+	}
+	records := make([]*Record, 0, len(tr.records))
+	for _, r := range tr.records {
+		c := *r
+		records = append(records, &c)
+	}
+	tr.dirty = false
+	tr.Unlock()
+
+	if err := store.Save(ctx, records); err != nil {
+		klog.Errorf("failed to save ticket records: %+v\n", err)
+		tr.Lock()
+		tr.dirty = true
+		tr.Unlock()
+	}
 }
 
-func existIssue(t *Ticket) (*erda_api.Issue, error) {
-	req := &erda_api.IssuePagingRequest{}
-	req.ProjectID = sender.ProjectId
-	req.OrderBy = "plan_started_at"
-	req.Asc = false
-	req.Title = t.Title
-	req.State = sender.StateIds
-	req.PageSize = 1
+// send sends the pending ticket of record: creates the issue of error ticket or comments on
+// the recorded one, reopens closed issues on recurrence and closes open issues on pass
+func send(ctx context.Context, b TicketBackend, r *Record, now time.Time) error {
+	t := r.Pending
+	klog.Infof("start send ticket %s to %s\n", t.Fingerprint, b.Name())
 
-	issues, err := sender.PagingIssue(req)
-	if err != nil {
-		return nil, err
+	var issue *Issue
+	if r.IssueID != "" {
+		var err error
+		if issue, err = b.Get(ctx, r.IssueID); err != nil {
+			return err
+		}
+		if issue == nil {
+			klog.Warningf("issue %s of ticket %s is deleted in %s\n", r.IssueID, t.Fingerprint, b.Name())
+			r.IssueID, r.Title, r.URL, r.Closed, r.Ignored = "", "", "", false, false
+		}
 	}
 
-	var issue erda_api.Issue
-	l := len(issues)
-	if l == 0 {
-		return nil, nil
-	} else if l > 1 {
-		logrus.Warn("more than one issue exist with same title")
+	if issue == nil {
+		// the record is lost, e.g. the state ConfigMap is deleted or the closed issue is pruned
+		var err error
+		if issue, err = b.Find(ctx, t); err != nil {
+			return err
+		}
+		if issue != nil {
+			klog.Infof("found issue %s of ticket %s in %s\n", issue.ID, t.Fingerprint, b.Name())
+			r.IssueID = issue.ID
+		}
 	}
-	issue = issues[0]
 
-	return &issue, nil
-}
+	if issue == nil {
+		// no need to send issue
+		if t.Kind == PassTicket {
+			return nil
+		}
+		issue, err := b.Create(ctx, t)
+		if err != nil {
+			return err
+		}
+		r.IssueID, r.Title, r.URL = issue.ID, issue.Title, issue.URL
+		r.Closed, r.Ignored = false, false
+		r.CommentedAt = now
+		return nil
+	}
 
-func SendTicket(t *Ticket) {
-	if sender != nil {
-		sendIssueCh <- t
+	r.Title = issue.Title
+	if issue.URL != "" {
+		r.URL = issue.URL
+	}
+	r.Closed, r.Ignored = issue.Closed, issue.Ignored
+	if t.Kind == PassTicket {
+		// closed manually
+		if issue.Closed {
+			return nil
+		}
+		if err := b.Comment(ctx, issue, t); err != nil {
+			return err
+		}
+		if err := b.Close(ctx, issue); err != nil {
+			return err
+		}
+		r.Closed = true
+		return nil
+	}
+
+	if issue.Closed {
+		if issue.Ignored {
+			// checked again after the comment interval
+			r.CommentedAt = now
+			return nil
+		}
+		if err := b.Reopen(ctx, issue); err != nil {
+			return err
+		}
+		r.Closed = false
+	}
+	if err := b.Comment(ctx, issue, t); err != nil {
+		return err
 	}
+	r.CommentedAt = now
+	return nil
 }
@@ -0,0 +1,391 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+const fakeBackendName = "fake"
+
+type fakeIssue struct {
+	Issue
+	fingerprint string
+	deleted     bool
+}
+
+// fakeBackend keeps issues in memory and records calls like "create <fingerprint>" and "close <id>"
+type fakeBackend struct {
+	issues []*fakeIssue
+	calls  []string
+	// called while creating the issue
+	onCreate func()
+}
+
+func (b *fakeBackend) Name() string {
+	return fakeBackendName
+}
+
+func (b *fakeBackend) issue(id string) *fakeIssue {
+	for _, i := range b.issues {
+		if i.ID == id && !i.deleted {
+			return i
+		}
+	}
+	return nil
+}
+
+func (b *fakeBackend) Get(ctx context.Context, id string) (*Issue, error) {
+	if i := b.issue(id); i != nil {
+		c := i.Issue
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (b *fakeBackend) Find(ctx context.Context, t *Ticket) (*Issue, error) {
+	b.calls = append(b.calls, "find "+t.Fingerprint)
+	for j := len(b.issues) - 1; j >= 0; j-- {
+		if b.issues[j].fingerprint == t.Fingerprint && !b.issues[j].deleted {
+			c := b.issues[j].Issue
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *fakeBackend) Create(ctx context.Context, t *Ticket) (*Issue, error) {
+	b.calls = append(b.calls, "create "+t.Fingerprint)
+	if b.onCreate != nil {
+		b.onCreate()
+	}
+	i := &fakeIssue{Issue: Issue{ID: strconv.Itoa(len(b.issues) + 1), Title: t.Title}, fingerprint: t.Fingerprint}
+	b.issues = append(b.issues, i)
+	c := i.Issue
+	return &c, nil
+}
+
+func (b *fakeBackend) Comment(ctx context.Context, issue *Issue, t *Ticket) error {
+	b.calls = append(b.calls, "comment "+issue.ID)
+	return nil
+}
+
+func (b *fakeBackend) Close(ctx context.Context, issue *Issue) error {
+	b.calls = append(b.calls, "close "+issue.ID)
+	b.issue(issue.ID).Closed = true
+	return nil
+}
+
+func (b *fakeBackend) Reopen(ctx context.Context, issue *Issue) error {
+	b.calls = append(b.calls, "reopen "+issue.ID)
+	b.issue(issue.ID).Closed = false
+	return nil
+}
+
+type fakeStore struct {
+	records []*Record
+	err     error
+	saves   int
+}
+
+func (s *fakeStore) Load(ctx context.Context) ([]*Record, error) {
+	return s.records, s.err
+}
+
+func (s *fakeStore) Save(ctx context.Context, records []*Record) error {
+	s.saves++
+	if s.err != nil {
+		return s.err
+	}
+	s.records = records
+	return nil
+}
+
+func newTracker() *ticketTracker {
+	return &ticketTracker{
+		records:         make(map[string]*Record),
+		commentInterval: time.Hour,
+		notify:          make(chan struct{}, 1),
+	}
+}
+
+func newTestTicket(kind TicketKind) *Ticket {
+	return &Ticket{Kind: kind, Fingerprint: "fp", Title: "dns failed", Content: "content"}
+}
+
+func TestTicketTracker(t *testing.T) {
+	type step struct {
+		at time.Duration
+		// ticket added before flush, none for flush only
+		kind TicketKind
+		// changes issues before the step, e.g. closes the issue manually
+		edit    func(b *fakeBackend)
+		noFlush bool
+	}
+	closeIssue := func(ignored bool) func(b *fakeBackend) {
+		return func(b *fakeBackend) {
+			b.issues[0].Closed, b.issues[0].Ignored = true, ignored
+		}
+	}
+	tests := []struct {
+		name string
+		// issues existing before the first step
+		issues []*fakeIssue
+		steps  []step
+		calls  []string
+		// expected record, nil if it is deleted
+		record *Record
+	}{
+		{
+			name:   "create",
+			steps:  []step{{kind: ErrorTicket}},
+			calls:  []string{"find fp", "create fp"},
+			record: &Record{IssueID: "1", Title: "dns failed"},
+		},
+		{
+			name:   "comment inside interval",
+			steps:  []step{{kind: ErrorTicket}, {at: 10 * time.Minute, kind: ErrorTicket}, {at: 30 * time.Minute}},
+			calls:  []string{"find fp", "create fp"},
+			record: &Record{IssueID: "1", Title: "dns failed", Pending: newTestTicket(ErrorTicket)},
+		},
+		{
+			name:   "comment outside interval",
+			steps:  []step{{kind: ErrorTicket}, {at: 10 * time.Minute, kind: ErrorTicket}, {at: time.Hour}},
+			calls:  []string{"find fp", "create fp", "comment 1"},
+			record: &Record{IssueID: "1", Title: "dns failed"},
+		},
+		{
+			name:   "pass closes",
+			steps:  []step{{kind: ErrorTicket}, {at: time.Minute, kind: PassTicket}, {at: 2 * time.Minute, kind: PassTicket}},
+			calls:  []string{"find fp", "create fp", "comment 1", "close 1"},
+			record: &Record{IssueID: "1", Title: "dns failed", Closed: true},
+		},
+		{
+			name: "recurrence reopens",
+			steps: []step{
+				{kind: ErrorTicket}, {at: time.Minute, kind: PassTicket}, {at: 2 * time.Minute, kind: ErrorTicket},
+			},
+			calls:  []string{"find fp", "create fp", "comment 1", "close 1", "reopen 1", "comment 1"},
+			record: &Record{IssueID: "1", Title: "dns failed"},
+		},
+		{
+			name:   "manually closed stays closed",
+			steps:  []step{{kind: ErrorTicket}, {at: time.Minute, kind: PassTicket, edit: closeIssue(false)}},
+			calls:  []string{"find fp", "create fp"},
+			record: &Record{IssueID: "1", Title: "dns failed", Closed: true},
+		},
+		{
+			name:   "ignored is not reopened",
+			steps:  []step{{kind: ErrorTicket}, {at: 2 * time.Hour, kind: ErrorTicket, edit: closeIssue(true)}},
+			calls:  []string{"find fp", "create fp"},
+			record: &Record{IssueID: "1", Title: "dns failed", Closed: true, Ignored: true},
+		},
+		{
+			name: "deleted issue is re-created",
+			steps: []step{{kind: ErrorTicket}, {at: 2 * time.Hour, kind: ErrorTicket, edit: func(b *fakeBackend) {
+				b.issues[0].deleted = true
+			}}},
+			calls:  []string{"find fp", "create fp", "find fp", "create fp"},
+			record: &Record{IssueID: "2", Title: "dns failed"},
+		},
+		{
+			name:   "issue of lost record is found",
+			issues: []*fakeIssue{{Issue: Issue{ID: "7", Title: "dns failed", Closed: true}, fingerprint: "fp"}},
+			steps:  []step{{kind: ErrorTicket}},
+			calls:  []string{"find fp", "reopen 7", "comment 7"},
+			record: &Record{IssueID: "7", Title: "dns failed"},
+		},
+		{
+			name:   "recovered before sending",
+			steps:  []step{{kind: ErrorTicket, noFlush: true}, {at: time.Second, kind: PassTicket}},
+			calls:  []string{"find fp"},
+			record: nil,
+		},
+		{
+			name:   "closed records are pruned",
+			steps:  []step{{kind: ErrorTicket}, {at: time.Minute, kind: PassTicket}, {at: 31 * 24 * time.Hour}},
+			calls:  []string{"find fp", "create fp", "comment 1", "close 1"},
+			record: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+			b := &fakeBackend{issues: tt.issues}
+			Register(b)
+			tr := newTracker()
+			for _, s := range tt.steps {
+				if s.edit != nil {
+					s.edit(b)
+				}
+				if s.kind != "" {
+					tr.add(fakeBackendName, newTestTicket(s.kind), start.Add(s.at))
+				}
+				if !s.noFlush {
+					tr.flush(ctx, start.Add(s.at))
+				}
+			}
+
+			assert.Equal(t, tt.calls, b.calls)
+			r := tr.records[recordKey(fakeBackendName, "fp")]
+			if tt.record == nil {
+				assert.Nil(t, r)
+				return
+			}
+			require.NotNil(t, r)
+			assert.Equal(t, tt.record.IssueID, r.IssueID)
+			assert.Equal(t, tt.record.Title, r.Title)
+			assert.Equal(t, tt.record.Closed, r.Closed)
+			assert.Equal(t, tt.record.Ignored, r.Ignored)
+			assert.Equal(t, tt.record.Pending, r.Pending)
+		})
+	}
+}
+
+func TestTicketTrackerPassWhileCreating(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	tr := newTracker()
+	b := &fakeBackend{}
+	b.onCreate = func() {
+		assert.True(t, tr.add(fakeBackendName, newTestTicket(PassTicket), now))
+	}
+	Register(b)
+
+	tr.add(fakeBackendName, newTestTicket(ErrorTicket), now)
+	tr.flush(ctx, now)
+	r := tr.records[recordKey(fakeBackendName, "fp")]
+	require.NotNil(t, r)
+	assert.Equal(t, "1", r.IssueID)
+	assert.Equal(t, PassTicket, r.Pending.Kind)
+
+	// the pass ticket closes the issue created
+	tr.flush(ctx, now.Add(time.Second))
+	assert.Equal(t, []string{"find fp", "create fp", "comment 1", "close 1"}, b.calls)
+	r = tr.records[recordKey(fakeBackendName, "fp")]
+	assert.True(t, r.Closed)
+	assert.Nil(t, r.Pending)
+}
+
+func TestTicketTrackerRestore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	b := &fakeBackend{issues: []*fakeIssue{{Issue: Issue{ID: "1", Title: "dns failed"}, fingerprint: "fp"}}}
+	Register(b)
+	store := &fakeStore{}
+
+	tr := newTracker()
+	tr.add(fakeBackendName, newTestTicket(ErrorTicket), now)
+	tr.records[recordKey(fakeBackendName, "fp")].IssueID = "1"
+	tr.records[recordKey(fakeBackendName, "fp")].CommentedAt = now
+	tr.save(ctx, store)
+	require.Len(t, store.records, 1)
+	// records are only saved when changed
+	tr.save(ctx, store)
+	assert.Equal(t, 1, store.saves)
+
+	// pending tickets are sent after restart
+	tr = newTracker()
+	tr.add(fakeBackendName, &Ticket{Kind: PassTicket, Fingerprint: "other"}, now)
+	records, err := store.Load(ctx)
+	require.NoError(t, err)
+	tr.restore(records)
+	tr.flush(ctx, now.Add(time.Minute))
+	assert.Empty(t, b.calls)
+	tr.flush(ctx, now.Add(time.Hour))
+	assert.Equal(t, []string{"comment 1"}, b.calls)
+	assert.Nil(t, tr.records[recordKey(fakeBackendName, "fp")].Pending)
+
+	// failed saves are retried
+	store.err = errors.New("conflict")
+	tr.save(ctx, store)
+	assert.True(t, tr.dirty)
+	store.err = nil
+	tr.save(ctx, store)
+	assert.False(t, tr.dirty)
+	assert.Nil(t, store.records[0].Pending)
+}
+
+func TestConfigMapStore(t *testing.T) {
+	ctx := context.Background()
+	restClient := k8sclient.RestClient
+	defer func() {
+		k8sclient.RestClient = restClient
+	}()
+	k8sclient.RestClient = fake.NewClientBuilder().Build()
+	s := &ConfigMapStore{}
+
+	records, err := s.Load(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	saved := []*Record{{Backend: BackendJira, Fingerprint: "fp", IssueID: "OPS-1", CreatedAt: now, Pending: newTestTicket(ErrorTicket)}}
+	require.NoError(t, s.Save(ctx, saved))
+	records, err = s.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, saved, records)
+
+	saved[0].Pending = nil
+	saved[0].Closed = true
+	require.NoError(t, s.Save(ctx, saved))
+	records, err = s.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, saved, records)
+
+	saved[0].Pending = newTestTicket(ErrorTicket)
+	require.NoError(t, s.Save(ctx, saved))
+	records, err = s.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, saved[0].Pending.Title, records[0].Pending.Content)
+	assert.NotEqual(t, saved[0].Pending.Title, saved[0].Pending.Content)
+}
+
+func TestBoundRecords(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	var records []*Record
+	for i := 0; i < maxRecords+10; i++ {
+		records = append(records, &Record{
+			Backend:     BackendJira,
+			Fingerprint: fmt.Sprintf("fp%d", i),
+			Closed:      i%2 == 0,
+			UpdatedAt:   now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	bounded := boundRecords(records)
+	assert.Len(t, bounded, maxRecords)
+	open := 0
+	for _, r := range bounded {
+		if !r.Closed {
+			open++
+		}
+	}
+	assert.Equal(t, (maxRecords+10)/2, open)
+	// the oldest closed records are dropped
+	assert.False(t, bounded[maxRecords-1].UpdatedAt.Before(now.Add(20*time.Minute)))
+	assert.Len(t, boundRecords(records[:2]), 2)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+// Jira creates tickets by jira rest api v2, the fingerprint of ticket is an issue label
+type Jira struct {
+	cfg    apistructs.JiraConfig
+	client *resty.Client
+}
+
+type jiraIssue struct {
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	Fields struct {
+		Summary string   `json:"summary"`
+		Labels  []string `json:"labels"`
+		Status  struct {
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Resolution *struct {
+			Name string `json:"name"`
+		} `json:"resolution"`
+	} `json:"fields"`
+}
+
+func NewJira(cfg apistructs.JiraConfig) (*Jira, error) {
+	if cfg.URL == "" || cfg.Project == "" {
+		return nil, errors.New("url and project of jira are required")
+	}
+	if cfg.IssueType == "" {
+		cfg.IssueType = "Task"
+	}
+	if cfg.CloseTransition == "" {
+		cfg.CloseTransition = "Done"
+	}
+	if cfg.ReopenTransition == "" {
+		cfg.ReopenTransition = "To Do"
+	}
+	client := resty.New().
+		SetHostURL(strings.TrimSuffix(cfg.URL, "/")).
+		SetBasicAuth(cfg.Username, cfg.Token).
+		SetHeader("Content-Type", "application/json").
+		SetTimeout(10 * time.Second).
+		SetRetryCount(3).SetRetryWaitTime(3 * time.Second)
+	return &Jira{cfg: cfg, client: client}, nil
+}
+
+func (j *Jira) Name() string {
+	return BackendJira
+}
+
+func (j *Jira) Get(ctx context.Context, id string) (*Issue, error) {
+	result := &jiraIssue{}
+	resp, err := j.client.R().SetContext(ctx).
+		SetQueryParam("fields", "summary,labels,status,resolution").
+		SetResult(result).
+		Get(fmt.Sprintf("/rest/api/2/issue/%s", id))
+	if err == nil && resp.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+	return j.issue(result), nil
+}
+
+func (j *Jira) Find(ctx context.Context, t *Ticket) (*Issue, error) {
+	var result struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s" ORDER BY created DESC`, j.cfg.Project, fingerprintLabel(t))
+	resp, err := j.client.R().SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"jql":        jql,
+			"maxResults": "1",
+			"fields":     "summary,labels,status,resolution",
+		}).
+		SetResult(&result).
+		Get("/rest/api/2/search")
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+	if len(result.Issues) == 0 {
+		return nil, nil
+	}
+	return j.issue(&result.Issues[0]), nil
+}
+
+func (j *Jira) Create(ctx context.Context, t *Ticket) (*Issue, error) {
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": j.cfg.Project},
+			"issuetype":   map[string]string{"name": j.cfg.IssueType},
+			"summary":     t.Title,
+			"description": t.Content,
+			"labels":      issueLabels(t),
+		},
+	}
+	created := &jiraIssue{}
+	resp, err := j.client.R().SetContext(ctx).SetBody(body).SetResult(created).Post("/rest/api/2/issue")
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+	created.Fields.Summary = t.Title
+	return j.issue(created), nil
+}
+
+func (j *Jira) Comment(ctx context.Context, issue *Issue, t *Ticket) error {
+	resp, err := j.client.R().SetContext(ctx).
+		SetBody(map[string]string{"body": t.Content}).
+		Post(fmt.Sprintf("/rest/api/2/issue/%s/comment", issue.ID))
+	return checkResponse(resp, err)
+}
+
+func (j *Jira) Close(ctx context.Context, issue *Issue) error {
+	return j.transition(ctx, issue, j.cfg.CloseTransition)
+}
+
+func (j *Jira) Reopen(ctx context.Context, issue *Issue) error {
+	return j.transition(ctx, issue, j.cfg.ReopenTransition)
+}
+
+// transition moves the issue by the transition of name, transitions differ between workflows
+func (j *Jira) transition(ctx context.Context, issue *Issue, name string) error {
+	var result struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	resp, err := j.client.R().SetContext(ctx).SetResult(&result).
+		Get(fmt.Sprintf("/rest/api/2/issue/%s/transitions", issue.ID))
+	if err := checkResponse(resp, err); err != nil {
+		return err
+	}
+	for _, tr := range result.Transitions {
+		if strings.EqualFold(tr.Name, name) {
+			resp, err = j.client.R().SetContext(ctx).
+				SetBody(map[string]interface{}{"transition": map[string]string{"id": tr.ID}}).
+				Post(fmt.Sprintf("/rest/api/2/issue/%s/transitions", issue.ID))
+			return checkResponse(resp, err)
+		}
+	}
+	return errors.Errorf("transition %q is not available for issue %s", name, issue.ID)
+}
+
+func (j *Jira) issue(i *jiraIssue) *Issue {
+	issue := &Issue{
+		ID:     i.Key,
+		Title:  i.Fields.Summary,
+		URL:    fmt.Sprintf("%s/browse/%s", strings.TrimSuffix(j.cfg.URL, "/"), i.Key),
+		Closed: i.Fields.Status.StatusCategory.Key == "done",
+		raw:    i,
+	}
+	if i.Fields.Resolution != nil {
+		name := strings.ToLower(i.Fields.Resolution.Name)
+		issue.Ignored = issue.Closed && strings.HasPrefix(name, "won't")
+	}
+	return issue
+}
+
+// issueLabels returns the fingerprint label and the labels of ticket without spaces
+func issueLabels(t *Ticket) []string {
+	labels := []string{fingerprintLabel(t)}
+	for _, l := range t.Labels {
+		if l != "" && !strings.ContainsAny(l, " \t") {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+func checkResponse(resp *resty.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return errors.Errorf("unexpected status code %d, body: %s", resp.StatusCode(), resp.Body())
+	}
+	return nil
+}
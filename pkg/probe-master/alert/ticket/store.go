@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+const (
+	StateConfigMap = "kubeprober-ticket-state"
+	stateKey       = "tickets.json"
+	// records saved in the ConfigMap, which is limited to 1MiB
+	maxRecords = 1000
+)
+
+// Record is the issue of a ticket fingerprint in a backend, with the latest
+// ticket waiting to be sent
+type Record struct {
+	Backend     string    `json:"backend"`
+	Fingerprint string    `json:"fingerprint"`
+	IssueID     string    `json:"issueID,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Closed      bool      `json:"closed,omitempty"`
+	Ignored     bool      `json:"ignored,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt,omitempty"`
+	CommentedAt time.Time `json:"commentedAt,omitempty"`
+	Pending     *Ticket   `json:"pending,omitempty"`
+}
+
+func (r *Record) Key() string {
+	return recordKey(r.Backend, r.Fingerprint)
+}
+
+func recordKey(backend, fingerprint string) string {
+	return backend + "/" + fingerprint
+}
+
+// Store saves ticket records, so that issues and pending tickets survive master restarts
+type Store interface {
+	Load(ctx context.Context) ([]*Record, error)
+	Save(ctx context.Context, records []*Record) error
+}
+
+// ConfigMapStore saves ticket records in a ConfigMap of default namespace
+type ConfigMapStore struct{}
+
+func (s *ConfigMapStore) Load(ctx context.Context) ([]*Record, error) {
+	var records []*Record
+	if err := k8sclient.LoadConfigMapJSON(ctx, StateConfigMap, stateKey, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Save keeps at most maxRecords records to bound the size of ConfigMap, open issues and
+// recently updated records are kept first. Contents of tickets pending on closed issues
+// are saved as their titles, which are commented when the issues are reopened after a restart
+func (s *ConfigMapStore) Save(ctx context.Context, records []*Record) error {
+	return k8sclient.SaveConfigMapJSON(ctx, StateConfigMap, stateKey, boundRecords(records))
+}
+
+func boundRecords(records []*Record) []*Record {
+	bounded := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if r.Closed && r.Pending != nil && r.Pending.Content != r.Pending.Title {
+			c, p := *r, *r.Pending
+			p.Content = p.Title
+			c.Pending = &p
+			r = &c
+		}
+		bounded = append(bounded, r)
+	}
+	if len(bounded) <= maxRecords {
+		return bounded
+	}
+	sort.SliceStable(bounded, func(i, j int) bool {
+		if bounded[i].Closed != bounded[j].Closed {
+			return !bounded[i].Closed
+		}
+		return bounded[i].UpdatedAt.After(bounded[j].UpdatedAt)
+	})
+	klog.Warningf("%d ticket records exceed %d, the oldest ones are not saved\n", len(bounded), maxRecords)
+	return bounded[:maxRecords]
+}
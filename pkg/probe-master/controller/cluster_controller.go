@@ -49,6 +49,10 @@ type ClusterReconciler struct {
 //+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=clusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=alerts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=alerts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=alertroutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=alertroutes/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=silences,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=silences/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kubeprober.erda.cloud,resources=clusters/finalizers,verbs=update
 
@@ -0,0 +1,282 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const (
+	DefaultRange  = 24 * time.Hour
+	DefaultPeriod = time.Hour
+	// MinPeriod is the shortest period of pass rates
+	MinPeriod = time.Minute
+	// MaxPeriods limits the number of pass rate periods in one query
+	MaxPeriods = 10000
+)
+
+// Query filters checker results, empty cluster/probe/checker match all
+type Query struct {
+	Cluster string
+	Probe   string
+	Checker string
+	Start   time.Time
+	End     time.Time
+}
+
+// AlertQuery filters alert records and notifications, empty fields match all
+type AlertQuery struct {
+	Cluster string
+	Type    string
+	Level   string
+	// only filters notifications
+	Receiver string
+	Start    time.Time
+	End      time.Time
+}
+
+// keys which alert statistics are grouped by
+const (
+	StatCluster  = "cluster"
+	StatType     = "type"
+	StatChecker  = "checker"
+	StatLevel    = "level"
+	StatReceiver = "receiver"
+)
+
+var StatKeys = []string{StatCluster, StatType, StatChecker, StatLevel, StatReceiver}
+
+// Store keeps checker results, cluster inventories and alert records and reads them back
+type Store interface {
+	WriteResult(r *apistructs.CheckerResult) error
+	QueryResults(ctx context.Context, q *Query) ([]apistructs.CheckerResult, error)
+	// probe and checker of query are ignored by inventories
+	WriteInventory(i *apistructs.ClusterInventory) error
+	QueryInventories(ctx context.Context, q *Query) ([]apistructs.ClusterInventory, error)
+	WriteAlert(a *apistructs.AlertRecord) error
+	QueryAlerts(ctx context.Context, q *AlertQuery) ([]apistructs.AlertRecord, error)
+	WriteNotification(n *apistructs.AlertNotification) error
+	QueryNotifications(ctx context.Context, q *AlertQuery) ([]apistructs.AlertNotification, error)
+	// TagValues returns the distinct values of a tag since start, cluster, probe and checker are
+	// tags of checker results, type and level of alerts, receiver of notifications
+	TagValues(ctx context.Context, tag string, start time.Time) ([]string, error)
+}
+
+// Validate fills the default time range and checks the query
+func (q *Query) Validate() error {
+	return validateRange(&q.Start, &q.End)
+}
+
+// Validate fills the default time range and checks the query
+func (q *AlertQuery) Validate() error {
+	return validateRange(&q.Start, &q.End)
+}
+
+func validateRange(start, end *time.Time) error {
+	if end.IsZero() {
+		*end = time.Now()
+	}
+	if start.IsZero() {
+		*start = end.Add(-DefaultRange)
+	}
+	if !start.Before(*end) {
+		return fmt.Errorf("start time %s is not before end time %s", *start, *end)
+	}
+	return nil
+}
+
+// Timeline collapses consecutive results with the same status of every checker
+// into one status change, ordered by checker and time
+func Timeline(results []apistructs.CheckerResult) []apistructs.StatusChange {
+	var keys []string
+	byChecker := make(map[string][]apistructs.CheckerResult)
+	for _, r := range results {
+		k := r.Key()
+		if _, ok := byChecker[k]; !ok {
+			keys = append(keys, k)
+		}
+		byChecker[k] = append(byChecker[k], r)
+	}
+	sort.Strings(keys)
+
+	var changes []apistructs.StatusChange
+	for _, k := range keys {
+		rs := byChecker[k]
+		sort.SliceStable(rs, func(i, j int) bool {
+			return rs[i].Time.Before(rs[j].Time)
+		})
+		var current *apistructs.StatusChange
+		for _, r := range rs {
+			if current != nil && current.Status == r.Status {
+				current.Until = r.Time
+				current.Message = r.Message
+				current.Count++
+				continue
+			}
+			if current != nil {
+				changes = append(changes, *current)
+			}
+			current = &apistructs.StatusChange{
+				Cluster: r.Cluster,
+				Probe:   r.Probe,
+				Checker: r.Checker,
+				Status:  r.Status,
+				Message: r.Message,
+				Since:   r.Time,
+				Until:   r.Time,
+				Count:   1,
+			}
+		}
+		if current != nil {
+			changes = append(changes, *current)
+		}
+	}
+	return changes
+}
+
+// ValidatePeriod checks that [start, end) split into periods stays within MinPeriod and MaxPeriods
+func ValidatePeriod(start, end time.Time, period time.Duration) error {
+	if period < MinPeriod {
+		return fmt.Errorf("period %s is shorter than %s", period, MinPeriod)
+	}
+	if n := countPeriods(start, end, period); n > MaxPeriods {
+		return fmt.Errorf("%d periods of %s exceed the limit of %d", n, period, MaxPeriods)
+	}
+	return nil
+}
+
+func countPeriods(start, end time.Time, period time.Duration) int64 {
+	if !start.Before(end) {
+		return 0
+	}
+	d := end.Sub(start)
+	return int64((d + period - 1) / period)
+}
+
+// PassRates splits [start, end) into periods and computes the pass rate of each one,
+// periods without any result have a zero total and rate. The period is widened when
+// it is below MinPeriod or would produce more than MaxPeriods periods
+func PassRates(results []apistructs.CheckerResult, start, end time.Time, period time.Duration) []apistructs.PassRate {
+	if period <= 0 {
+		period = DefaultPeriod
+	}
+	if period < MinPeriod {
+		period = MinPeriod
+	}
+	if countPeriods(start, end, period) > MaxPeriods {
+		period = (end.Sub(start) + MaxPeriods - 1) / MaxPeriods
+	}
+
+	var rates []apistructs.PassRate
+	for s := start; s.Before(end); s = s.Add(period) {
+		e := s.Add(period)
+		if e.After(end) {
+			e = end
+		}
+		rates = append(rates, apistructs.PassRate{Start: s, End: e})
+	}
+
+	for _, r := range results {
+		if r.Time.Before(start) || !r.Time.Before(end) {
+			continue
+		}
+		i := int(r.Time.Sub(start) / period)
+		rates[i].Total++
+		if r.Status == kubeproberv1.CheckerStatusPass {
+			rates[i].Pass++
+		}
+	}
+
+	for i := range rates {
+		if rates[i].Total > 0 {
+			rates[i].Rate = float64(rates[i].Pass) / float64(rates[i].Total)
+		}
+	}
+	return rates
+}
+
+// ParseStatKeys splits comma separated keys of alert statistics, empty means by cluster
+func ParseStatKeys(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return []string{StatCluster}, nil
+	}
+	var keys []string
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		valid := false
+		for _, sk := range StatKeys {
+			if k == sk {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid key %q, supported keys: %s", k, strings.Join(StatKeys, ","))
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// AlertStats counts notifications grouped by keys, ordered by count descending,
+// only the first limit groups are returned if limit is positive
+func AlertStats(notifications []apistructs.AlertNotification, keys []string, limit int) []apistructs.AlertStat {
+	index := make(map[apistructs.AlertStat]int)
+	var stats []apistructs.AlertStat
+	for _, n := range notifications {
+		var s apistructs.AlertStat
+		for _, k := range keys {
+			switch k {
+			case StatCluster:
+				s.Cluster = n.Cluster
+			case StatType:
+				s.Type = n.Type
+			case StatChecker:
+				s.Checker = n.Checker
+			case StatLevel:
+				s.Level = n.Level
+			case StatReceiver:
+				s.Receiver = n.Receiver
+			}
+		}
+		i, ok := index[s]
+		if !ok {
+			i = len(stats)
+			index[s] = i
+			stats = append(stats, s)
+		}
+		stats[i].Count++
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return statKey(stats[i]) < statKey(stats[j])
+	})
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
+
+func statKey(s apistructs.AlertStat) string {
+	return strings.Join([]string{s.Cluster, s.Type, s.Checker, s.Level, s.Receiver}, "/")
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+func newResult(checker string, status kubeproberv1.CheckerStatus, t time.Time) apistructs.CheckerResult {
+	return apistructs.CheckerResult{
+		Cluster: "cluster-test",
+		Probe:   "probe-test",
+		Checker: checker,
+		Status:  status,
+		Time:    t,
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	now := time.Now()
+	results := []apistructs.CheckerResult{
+		newResult("checker1", kubeproberv1.CheckerStatusPass, now),
+		newResult("checker1", kubeproberv1.CheckerStatusPass, now.Add(time.Minute)),
+		newResult("checker2", kubeproberv1.CheckerStatusError, now.Add(time.Minute)),
+		newResult("checker1", kubeproberv1.CheckerStatusError, now.Add(2*time.Minute)),
+		newResult("checker1", kubeproberv1.CheckerStatusPass, now.Add(3*time.Minute)),
+	}
+
+	changes := Timeline(results)
+	assert.Equal(t, 4, len(changes))
+	assert.Equal(t, kubeproberv1.CheckerStatusPass, changes[0].Status)
+	assert.Equal(t, 2, changes[0].Count)
+	assert.Equal(t, now, changes[0].Since)
+	assert.Equal(t, now.Add(time.Minute), changes[0].Until)
+	assert.Equal(t, kubeproberv1.CheckerStatusError, changes[1].Status)
+	assert.Equal(t, kubeproberv1.CheckerStatusPass, changes[2].Status)
+	assert.Equal(t, "checker2", changes[3].Checker)
+}
+
+func TestPassRates(t *testing.T) {
+	start := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(150 * time.Minute)
+	results := []apistructs.CheckerResult{
+		newResult("checker1", kubeproberv1.CheckerStatusPass, start),
+		newResult("checker1", kubeproberv1.CheckerStatusError, start.Add(10*time.Minute)),
+		newResult("checker1", kubeproberv1.CheckerStatusPass, start.Add(70*time.Minute)),
+		newResult("checker1", kubeproberv1.CheckerStatusPass, end),
+	}
+
+	rates := PassRates(results, start, end, time.Hour)
+	assert.Equal(t, 3, len(rates))
+	assert.Equal(t, 2, rates[0].Total)
+	assert.Equal(t, 0.5, rates[0].Rate)
+	assert.Equal(t, 1, rates[1].Total)
+	assert.Equal(t, 1.0, rates[1].Rate)
+	assert.Equal(t, 0, rates[2].Total)
+	assert.Equal(t, end, rates[2].End)
+}
+
+func TestPassRatesBounded(t *testing.T) {
+	start := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(720 * time.Hour)
+
+	assert.Len(t, PassRates(nil, start, start.Add(time.Hour), time.Nanosecond), 60)
+	assert.Len(t, PassRates(nil, start, end, time.Minute), MaxPeriods)
+}
+
+func TestValidatePeriod(t *testing.T) {
+	start := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Error(t, ValidatePeriod(start, start.Add(time.Hour), time.Nanosecond))
+	assert.Error(t, ValidatePeriod(start, start.Add(720*time.Hour), time.Minute))
+	assert.NoError(t, ValidatePeriod(start, start.Add(720*time.Hour), time.Hour))
+	assert.NoError(t, ValidatePeriod(start, start.Add(MaxPeriods*time.Minute), time.Minute))
+}
+
+func TestAlertStats(t *testing.T) {
+	notifications := []apistructs.AlertNotification{
+		{Cluster: "c1", Type: "k8s", Checker: "dns", Level: "ERROR", Receiver: "dingding"},
+		{Cluster: "c1", Type: "k8s", Checker: "dns", Level: "ERROR", Receiver: "wecom"},
+		{Cluster: "c2", Type: "k8s", Checker: "dns", Level: "WARN", Receiver: "dingding"},
+		{Cluster: "c2", Type: "NodeDown", Level: "critical", Receiver: "dingding"},
+		{Cluster: "c2", Type: "NodeDown", Level: "critical", Receiver: "dingding"},
+	}
+
+	stats := AlertStats(notifications, []string{StatCluster}, 0)
+	assert.Equal(t, []apistructs.AlertStat{{Cluster: "c2", Count: 3}, {Cluster: "c1", Count: 2}}, stats)
+
+	stats = AlertStats(notifications, []string{StatType, StatReceiver}, 2)
+	assert.Equal(t, []apistructs.AlertStat{
+		{Type: "NodeDown", Receiver: "dingding", Count: 2},
+		{Type: "k8s", Receiver: "dingding", Count: 2},
+	}, stats)
+
+	keys, err := ParseStatKeys("cluster, level")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{StatCluster, StatLevel}, keys)
+	_, err = ParseStatKeys("node")
+	assert.Error(t, err)
+}
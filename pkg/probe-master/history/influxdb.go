@@ -0,0 +1,381 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	influxdb2api "github.com/influxdata/influxdb-client-go/v2/api"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const (
+	checkerMeasurement = "checker"
+	alertMeasurement   = "alert"
+	// fields of inventory are the items of cluster inventory
+	inventoryMeasurement = "inventory"
+	// alerts sent to receivers
+	notificationMeasurement = "alert_notification"
+
+	fieldStatus  = "status"
+	fieldMessage = "message"
+	// legacy field, value is "STATUS###message"
+	fieldResult     = "result"
+	resultSeparator = "###"
+	fieldAlertMsg   = "msg"
+	fieldCount      = "count"
+)
+
+type influxStore struct {
+	bucket        string
+	alertBucket   string
+	writeAPI      influxdb2api.WriteAPI
+	alertWriteAPI influxdb2api.WriteAPI
+	queryAPI      influxdb2api.QueryAPI
+}
+
+// NewInfluxStore returns a Store which keeps checker results in bucket and alert records in alertBucket of influxdb
+func NewInfluxStore(client influxdb2.Client, org string, bucket string, alertBucket string) Store {
+	return &influxStore{
+		bucket:        bucket,
+		alertBucket:   alertBucket,
+		writeAPI:      client.WriteAPI(org, bucket),
+		alertWriteAPI: client.WriteAPI(org, alertBucket),
+		queryAPI:      client.QueryAPI(org),
+	}
+}
+
+func (s *influxStore) WriteResult(r *apistructs.CheckerResult) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	p := influxdb2.NewPointWithMeasurement(checkerMeasurement).
+		AddTag("cluster", r.Cluster).
+		AddTag("checker", r.Checker).
+		AddTag("probe", r.Probe).
+		AddField(fieldStatus, string(r.Status)).
+		AddField(fieldMessage, r.Message).
+		SetTime(r.Time)
+	// Flush writes
+	s.writeAPI.WritePoint(p)
+	s.writeAPI.Flush()
+	return nil
+}
+
+func (s *influxStore) QueryResults(ctx context.Context, q *Query) ([]apistructs.CheckerResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	result, err := s.queryAPI.Query(ctx, s.fluxQuery(q))
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var results []apistructs.CheckerResult
+	for result.Next() {
+		values := result.Record().Values()
+		r := apistructs.CheckerResult{
+			Cluster: stringValue(values, "cluster"),
+			Probe:   stringValue(values, "probe"),
+			Checker: stringValue(values, "checker"),
+			Status:  kubeproberv1.CheckerStatus(stringValue(values, fieldStatus)),
+			Message: stringValue(values, fieldMessage),
+			Time:    result.Record().Time(),
+		}
+		if r.Status == "" {
+			r.Status, r.Message = parseLegacyResult(stringValue(values, fieldResult))
+		}
+		results = append(results, r)
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return results, nil
+}
+
+func (s *influxStore) fluxQuery(q *Query) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "from(bucket: %s)\n", fluxString(s.bucket))
+	fmt.Fprintf(&b, "  |> range(start: %s, stop: %s)\n", q.Start.UTC().Format(time.RFC3339Nano), q.End.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => r._measurement == %s)\n", fluxString(checkerMeasurement))
+	for _, f := range []struct{ tag, value string }{
+		{"cluster", q.Cluster},
+		{"probe", q.Probe},
+		{"checker", q.Checker},
+	} {
+		if f.value != "" {
+			fmt.Fprintf(&b, "  |> filter(fn: (r) => r.%s == %s)\n", f.tag, fluxString(f.value))
+		}
+	}
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => r._field == %s or r._field == %s or r._field == %s)\n",
+		fluxString(fieldStatus), fluxString(fieldMessage), fluxString(fieldResult))
+	b.WriteString("  |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")\n")
+	b.WriteString("  |> group()\n")
+	b.WriteString("  |> sort(columns: [\"_time\"])\n")
+	return b.String()
+}
+
+func (s *influxStore) WriteInventory(i *apistructs.ClusterInventory) error {
+	if i.Time.IsZero() {
+		i.Time = time.Now()
+	}
+	p := influxdb2.NewPointWithMeasurement(inventoryMeasurement).
+		AddTag("cluster", i.Cluster).
+		SetTime(i.Time)
+	for k, v := range i.Items {
+		p.AddField(k, v)
+	}
+	s.writeAPI.WritePoint(p)
+	s.writeAPI.Flush()
+	return nil
+}
+
+func (s *influxStore) QueryInventories(ctx context.Context, q *Query) ([]apistructs.ClusterInventory, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "from(bucket: %s)\n", fluxString(s.bucket))
+	fmt.Fprintf(&b, "  |> range(start: %s, stop: %s)\n", q.Start.UTC().Format(time.RFC3339Nano), q.End.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => r._measurement == %s)\n", fluxString(inventoryMeasurement))
+	if q.Cluster != "" {
+		fmt.Fprintf(&b, "  |> filter(fn: (r) => r.cluster == %s)\n", fluxString(q.Cluster))
+	}
+	b.WriteString("  |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")\n")
+	b.WriteString("  |> group()\n")
+	b.WriteString("  |> sort(columns: [\"_time\"])\n")
+
+	result, err := s.queryAPI.Query(ctx, b.String())
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var inventories []apistructs.ClusterInventory
+	for result.Next() {
+		inventories = append(inventories, apistructs.ClusterInventory{
+			Cluster: stringValue(result.Record().Values(), "cluster"),
+			Items:   inventoryItems(result.Record().Values()),
+			Time:    result.Record().Time(),
+		})
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return inventories, nil
+}
+
+// inventoryItems picks the pivoted fields from values of a record
+func inventoryItems(values map[string]interface{}) map[string]string {
+	items := make(map[string]string)
+	for k, v := range values {
+		if strings.HasPrefix(k, "_") || k == "cluster" || k == "result" || k == "table" {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			items[k] = s
+		}
+	}
+	return items
+}
+
+func (s *influxStore) WriteAlert(a *apistructs.AlertRecord) error {
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+	p := influxdb2.NewPointWithMeasurement(alertMeasurement).
+		AddTag("cluster", a.Cluster).
+		AddTag("node", a.Node).
+		AddTag("type", a.Type).
+		AddTag("component", a.Component).
+		AddTag("level", a.Level).
+		AddField(fieldAlertMsg, a.Message).
+		SetTime(a.Time)
+	// Flush writes
+	s.alertWriteAPI.WritePoint(p)
+	s.alertWriteAPI.Flush()
+	return nil
+}
+
+func (s *influxStore) QueryAlerts(ctx context.Context, q *AlertQuery) ([]apistructs.AlertRecord, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "from(bucket: %s)\n", fluxString(s.alertBucket))
+	fmt.Fprintf(&b, "  |> range(start: %s, stop: %s)\n", q.Start.UTC().Format(time.RFC3339Nano), q.End.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => r._measurement == %s and r._field == %s)\n", fluxString(alertMeasurement), fluxString(fieldAlertMsg))
+	for _, f := range []struct{ tag, value string }{
+		{"cluster", q.Cluster},
+		{"type", q.Type},
+		{"level", q.Level},
+	} {
+		if f.value != "" {
+			fmt.Fprintf(&b, "  |> filter(fn: (r) => r.%s == %s)\n", f.tag, fluxString(f.value))
+		}
+	}
+	b.WriteString("  |> group()\n")
+	b.WriteString("  |> sort(columns: [\"_time\"])\n")
+
+	result, err := s.queryAPI.Query(ctx, b.String())
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var alerts []apistructs.AlertRecord
+	for result.Next() {
+		values := result.Record().Values()
+		alerts = append(alerts, apistructs.AlertRecord{
+			Cluster:   stringValue(values, "cluster"),
+			Node:      stringValue(values, "node"),
+			Type:      stringValue(values, "type"),
+			Component: stringValue(values, "component"),
+			Level:     stringValue(values, "level"),
+			Message:   stringValue(values, "_value"),
+			Time:      result.Record().Time(),
+		})
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return alerts, nil
+}
+
+func (s *influxStore) WriteNotification(n *apistructs.AlertNotification) error {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	p := influxdb2.NewPointWithMeasurement(notificationMeasurement).
+		AddTag("cluster", n.Cluster).
+		AddTag("type", n.Type).
+		AddTag("checker", n.Checker).
+		AddTag("level", n.Level).
+		AddTag("receiver", n.Receiver).
+		AddField(fieldCount, 1).
+		SetTime(n.Time)
+	s.alertWriteAPI.WritePoint(p)
+	return nil
+}
+
+func (s *influxStore) QueryNotifications(ctx context.Context, q *AlertQuery) ([]apistructs.AlertNotification, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "from(bucket: %s)\n", fluxString(s.alertBucket))
+	fmt.Fprintf(&b, "  |> range(start: %s, stop: %s)\n", q.Start.UTC().Format(time.RFC3339Nano), q.End.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => r._measurement == %s and r._field == %s)\n", fluxString(notificationMeasurement), fluxString(fieldCount))
+	for _, f := range []struct{ tag, value string }{
+		{"cluster", q.Cluster},
+		{"type", q.Type},
+		{"level", q.Level},
+		{"receiver", q.Receiver},
+	} {
+		if f.value != "" {
+			fmt.Fprintf(&b, "  |> filter(fn: (r) => r.%s == %s)\n", f.tag, fluxString(f.value))
+		}
+	}
+	b.WriteString("  |> group()\n")
+	b.WriteString("  |> sort(columns: [\"_time\"])\n")
+
+	result, err := s.queryAPI.Query(ctx, b.String())
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var notifications []apistructs.AlertNotification
+	for result.Next() {
+		values := result.Record().Values()
+		notifications = append(notifications, apistructs.AlertNotification{
+			Cluster:  stringValue(values, "cluster"),
+			Type:     stringValue(values, "type"),
+			Checker:  stringValue(values, "checker"),
+			Level:    stringValue(values, "level"),
+			Receiver: stringValue(values, "receiver"),
+			Time:     result.Record().Time(),
+		})
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return notifications, nil
+}
+
+func (s *influxStore) TagValues(ctx context.Context, tag string, start time.Time) ([]string, error) {
+	bucket, measurement := s.bucket, checkerMeasurement
+	switch tag {
+	case "cluster", "probe", "checker":
+	case "type", "level":
+		bucket, measurement = s.alertBucket, alertMeasurement
+	case "receiver":
+		bucket, measurement = s.alertBucket, notificationMeasurement
+	default:
+		return nil, fmt.Errorf("unknown tag %q", tag)
+	}
+
+	var b strings.Builder
+	b.WriteString("import \"influxdata/influxdb/schema\"\n")
+	fmt.Fprintf(&b, "schema.tagValues(bucket: %s, tag: %s, predicate: (r) => r._measurement == %s, start: %s)\n",
+		fluxString(bucket), fluxString(tag), fluxString(measurement), start.UTC().Format(time.RFC3339Nano))
+
+	result, err := s.queryAPI.Query(ctx, b.String())
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var values []string
+	for result.Next() {
+		if v := stringValue(result.Record().Values(), "_value"); v != "" {
+			values = append(values, v)
+		}
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return values, nil
+}
+
+func fluxString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func stringValue(values map[string]interface{}, key string) string {
+	if v, ok := values[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func parseLegacyResult(result string) (kubeproberv1.CheckerStatus, string) {
+	parts := strings.SplitN(result, resultSeparator, 2)
+	if len(parts) != 2 {
+		return kubeproberv1.CheckerStatusUNKNOWN, result
+	}
+	return kubeproberv1.CheckerStatus(parts[0]), parts[1]
+}
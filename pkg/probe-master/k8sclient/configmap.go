@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LoadConfigMapJSON unmarshals the key of ConfigMap in default namespace into v,
+// v is left untouched if the ConfigMap or the key does not exist
+func LoadConfigMapJSON(ctx context.Context, name, key string, v interface{}) error {
+	cm := &corev1.ConfigMap{}
+	err := RestClient.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: name}, cm)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if data := cm.Data[key]; data != "" {
+		return json.Unmarshal([]byte(data), v)
+	}
+	return nil
+}
+
+// SaveConfigMapJSON marshals v into the key of ConfigMap in default namespace,
+// the ConfigMap is created if it does not exist
+func SaveConfigMapJSON(ctx context.Context, name, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{}
+	err = RestClient.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: name}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: name},
+			Data:       map[string]string{key: string(data)},
+		}
+		return RestClient.Create(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[key] = string(data)
+	return RestClient.Update(ctx, cm)
+}
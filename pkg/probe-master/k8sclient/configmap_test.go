@@ -0,0 +1,43 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMapJSON(t *testing.T) {
+	ctx := context.Background()
+	restClient := RestClient
+	defer func() {
+		RestClient = restClient
+	}()
+	RestClient = fake.NewClientBuilder().Build()
+
+	var v []string
+	require.NoError(t, LoadConfigMapJSON(ctx, "state", "a.json", &v))
+	assert.Nil(t, v)
+
+	require.NoError(t, SaveConfigMapJSON(ctx, "state", "a.json", []string{"a"}))
+	require.NoError(t, SaveConfigMapJSON(ctx, "state", "b.json", []string{"b"}))
+	require.NoError(t, LoadConfigMapJSON(ctx, "state", "a.json", &v))
+	assert.Equal(t, []string{"a"}, v)
+	require.NoError(t, LoadConfigMapJSON(ctx, "state", "b.json", &v))
+	assert.Equal(t, []string{"b"}, v)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timezone
+
+import (
+	"fmt"
+	"time"
+	// keep timezones available in images without zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	// DefaultTimezone keeps daily buckets of existing deployments unchanged
+	DefaultTimezone = "Asia/Shanghai"
+	DayLayout       = "2006-01-02"
+)
+
+var location = time.UTC
+
+func init() {
+	if err := Set(DefaultTimezone); err != nil {
+		panic(err)
+	}
+}
+
+// Set changes the timezone of probe-master, it should be called before serving
+func Set(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", name, err)
+	}
+	location = loc
+	return nil
+}
+
+// Location returns the timezone of probe-master
+func Location() *time.Location {
+	return location
+}
+
+// Day returns the day of t in the timezone, e.g. "2021-08-01"
+func Day(t time.Time) string {
+	return t.In(location).Format(DayLayout)
+}
+
+// ParseDay returns the start of day in the timezone
+func ParseDay(day string) (time.Time, error) {
+	return time.ParseInLocation(DayLayout, day, location)
+}
+
+// Week returns the ISO year and week of t in the timezone, e.g. "2021-31"
+func Week(t time.Time) string {
+	y, w := t.In(location).ISOWeek()
+	return fmt.Sprintf("%d-%d", y, w)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timezone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDayAndWeek(t *testing.T) {
+	defer Set(DefaultTimezone)
+
+	// 2021-08-01 20:00 UTC is sunday in UTC and monday in Asia/Shanghai
+	now := time.Date(2021, 8, 1, 20, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, Set("UTC"))
+	assert.Equal(t, "2021-08-01", Day(now))
+	assert.Equal(t, "2021-30", Week(now))
+
+	assert.NoError(t, Set("Asia/Shanghai"))
+	assert.Equal(t, "2021-08-02", Day(now))
+	assert.Equal(t, "2021-31", Week(now))
+
+	start, err := ParseDay("2021-08-02")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 8, 1, 16, 0, 0, 0, time.UTC), start.UTC())
+
+	assert.Error(t, Set("Mars/Olympus"))
+	assert.Equal(t, "Asia/Shanghai", Location().String())
+}
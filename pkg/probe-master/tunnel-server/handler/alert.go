@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"k8s.io/klog"
+
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/manager"
+)
+
+// GetAlerts returns alert states kept by alert manager, filtered by cluster and state
+func GetAlerts(rw http.ResponseWriter, req *http.Request, m *manager.Manager) {
+	v := req.URL.Query()
+	states := []apistructs.AlertState{}
+	for _, s := range m.States() {
+		if c := v.Get("cluster"); c != "" && c != s.Cluster {
+			continue
+		}
+		if st := v.Get("state"); st != "" && st != string(s.State) {
+			continue
+		}
+		states = append(states, s)
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(states); err != nil {
+		klog.Errorf("json encode for alerts error: %+v\n", err)
+	}
+}
+
+// AckAlert acknowledges the firing alert of fingerprint, which stops its escalations and repeats
+func AckAlert(rw http.ResponseWriter, req *http.Request, m *manager.Manager) {
+	id := mux.Vars(req)["id"]
+	ack := apistructs.AlertAck{}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&ack); err != nil {
+			errMsg := fmt.Sprintf("[alert ack] invalid request: %+v\n", err)
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(errMsg))
+			return
+		}
+	}
+	ack.Time = time.Now()
+
+	s, err := m.Ack(id, ack)
+	if err != nil {
+		errMsg := fmt.Sprintf("[alert ack] failed to acknowledge alert %s: %+v\n", id, err)
+		klog.Errorf(errMsg)
+		if err == manager.ErrNotFound {
+			rw.WriteHeader(http.StatusNotFound)
+		} else {
+			rw.WriteHeader(http.StatusConflict)
+		}
+		rw.Write([]byte(errMsg))
+		return
+	}
+	klog.Infof("[alert ack] alert %s is acknowledged by %s\n", s.Key(), ack.By)
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(s); err != nil {
+		klog.Errorf("json encode for alert error: %+v\n", err)
+	}
+}
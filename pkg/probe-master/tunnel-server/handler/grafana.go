@@ -0,0 +1,704 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/history"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+)
+
+// targets served by the grafana json datasource
+const (
+	// table of clusters
+	TargetClusters = "clusters"
+	// node count of every cluster
+	TargetClusterNodeCount = "cluster_nodecount"
+	// table of checker results
+	TargetCheckerResults = "checker_results"
+	// status priority of every checker, PASS is 0 and ERROR is 4
+	TargetCheckerStatus = "checker_status"
+	// pass rate of checker results in percent
+	TargetCheckerPassRate = "checker_passrate"
+	// count of error checker results of every cluster
+	TargetCheckerErrors = "checker_errors"
+	// daily alert count, both table and time series
+	TargetAlertCount = "alert_count"
+	// table of alerts received by probe-master
+	TargetAlerts = "alerts"
+	// table of sent alert counts grouped by keys in target data "by", e.g. "cluster,type", and "limit"
+	TargetAlertStats = "alert_stats"
+)
+
+var grafanaTargets = []string{
+	TargetClusters,
+	TargetClusterNodeCount,
+	TargetCheckerResults,
+	TargetCheckerStatus,
+	TargetCheckerPassRate,
+	TargetCheckerErrors,
+	TargetAlertCount,
+	TargetAlerts,
+	TargetAlertStats,
+}
+
+// filter keys supported by adhoc filters, target data and annotation queries
+var grafanaTagKeys = []string{"cluster", "probe", "checker", "type", "level", "receiver"}
+
+var errStoreDisabled = errors.New("result store is not enabled")
+
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type GrafanaTarget struct {
+	Target string                 `json:"target"`
+	RefID  string                 `json:"refId"`
+	Type   string                 `json:"type"`
+	Hide   bool                   `json:"hide"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+type GrafanaAdhocFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+type GrafanaQueryRequest struct {
+	Range         GrafanaRange         `json:"range"`
+	IntervalMs    int64                `json:"intervalMs"`
+	MaxDataPoints int64                `json:"maxDataPoints"`
+	Targets       []GrafanaTarget      `json:"targets"`
+	AdhocFilters  []GrafanaAdhocFilter `json:"adhocFilters"`
+}
+
+type GrafanaAnnotationQuery struct {
+	Name       string `json:"name"`
+	Datasource string `json:"datasource"`
+	Enable     bool   `json:"enable"`
+	IconColor  string `json:"iconColor,omitempty"`
+	// filters like "cluster=foo,checker=bar", "target=alerts" annotates alerts instead of checker status changes
+	Query string `json:"query"`
+}
+
+type GrafanaAnnotationRequest struct {
+	Range      GrafanaRange           `json:"range"`
+	Annotation GrafanaAnnotationQuery `json:"annotation"`
+}
+
+type GrafanaAnnotation struct {
+	Annotation GrafanaAnnotationQuery `json:"annotation"`
+	Time       int64                  `json:"time"`
+	TimeEnd    int64                  `json:"timeEnd,omitempty"`
+	Title      string                 `json:"title"`
+	Text       string                 `json:"text"`
+	Tags       []string               `json:"tags"`
+}
+
+type GrafanaTagKey struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type GrafanaTagValue struct {
+	Text string `json:"text"`
+}
+
+// grafanaFilter is the merged filter of adhoc filters and target data
+type grafanaFilter map[string]string
+
+// GrafanaDatasource implements the grafana json datasource protocol over clusters, checker results and alerts
+type GrafanaDatasource struct {
+	store history.Store
+	// target used when the request does not specify one, keeps compatible with old dashboards
+	defaultTarget string
+}
+
+func NewGrafanaDatasource(store history.Store, defaultTarget string) *GrafanaDatasource {
+	return &GrafanaDatasource{store: store, defaultTarget: defaultTarget}
+}
+
+// Register adds the routes of datasource under prefix
+func (d *GrafanaDatasource) Register(router *mux.Router, prefix string) {
+	router.HandleFunc(prefix, func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	router.HandleFunc(prefix+"/search", d.Search)
+	router.HandleFunc(prefix+"/query", d.Query)
+	router.HandleFunc(prefix+"/annotations", d.Annotations)
+	router.HandleFunc(prefix+"/tag-keys", d.TagKeys)
+	router.HandleFunc(prefix+"/tag-values", d.TagValues)
+}
+
+// Search returns all targets, the default one first
+func (d *GrafanaDatasource) Search(rw http.ResponseWriter, req *http.Request) {
+	targets := []string{d.defaultTarget}
+	for _, t := range grafanaTargets {
+		if t != d.defaultTarget {
+			targets = append(targets, t)
+		}
+	}
+	writeGrafanaResponse(rw, targets)
+}
+
+func (d *GrafanaDatasource) Query(rw http.ResponseWriter, req *http.Request) {
+	q := &GrafanaQueryRequest{}
+	if err := decodeGrafanaRequest(req, q); err != nil {
+		errMsg := fmt.Sprintf("[grafana query] invalid request: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	fillGrafanaRange(&q.Range)
+	if len(q.Targets) == 0 {
+		q.Targets = []GrafanaTarget{{}}
+	}
+
+	resp := []interface{}{}
+	for _, t := range q.Targets {
+		if t.Hide {
+			continue
+		}
+		if t.Target == "" {
+			t.Target = d.defaultTarget
+		}
+		r, err := d.queryTarget(req.Context(), q, &t)
+		if err != nil {
+			errMsg := fmt.Sprintf("[grafana query] failed to query target %s: %+v\n", t.Target, err)
+			klog.Errorf(errMsg)
+			if err == errStoreDisabled {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+			rw.Write([]byte(errMsg))
+			return
+		}
+		resp = append(resp, r...)
+	}
+	writeGrafanaResponse(rw, resp)
+}
+
+func (d *GrafanaDatasource) queryTarget(ctx context.Context, q *GrafanaQueryRequest, t *GrafanaTarget) ([]interface{}, error) {
+	f := newGrafanaFilter(q.AdhocFilters, t.Data)
+	from, to := q.Range.From, q.Range.To
+
+	switch t.Target {
+	case TargetClusters, TargetClusterNodeCount:
+		clusters, err := k8sclient.GetClusters()
+		if err != nil {
+			return nil, err
+		}
+		var filtered []kubeproberv1.Cluster
+		for _, c := range clusters {
+			if f.match("cluster", c.Name) {
+				filtered = append(filtered, c)
+			}
+		}
+		if t.Target == TargetClusters {
+			return []interface{}{clusterTable(filtered)}, nil
+		}
+		var series []interface{}
+		for _, c := range filtered {
+			series = append(series, TimeSerieResponse{
+				Tatget:     c.Name,
+				Datapoints: [][]float64{{float64(c.Status.NodeCount), timeMs(to)}},
+			})
+		}
+		return series, nil
+
+	case TargetCheckerResults, TargetCheckerStatus, TargetCheckerPassRate, TargetCheckerErrors:
+		results, err := d.queryResults(ctx, f, from, to)
+		if err != nil {
+			return nil, err
+		}
+		interval := grafanaInterval(q)
+		switch t.Target {
+		case TargetCheckerResults:
+			return []interface{}{checkerResultTable(results)}, nil
+		case TargetCheckerStatus:
+			return checkerStatusSeries(results), nil
+		case TargetCheckerPassRate:
+			return []interface{}{passRateSeries(results, from, to, interval)}, nil
+		default:
+			return checkerErrorSeries(results, from, interval), nil
+		}
+
+	case TargetAlertCount:
+		points, err := alertCountPoints(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if t.Type == "table" {
+			table := TableResponse{
+				Columns: []Column{{Text: "TIME", Type: "time"}, {Text: "COUNT", Type: "number"}},
+				Rows:    [][]interface{}{},
+				Type:    "table",
+			}
+			for _, p := range points {
+				table.Rows = append(table.Rows, []interface{}{p[1], p[0]})
+			}
+			return []interface{}{table}, nil
+		}
+		return []interface{}{TimeSerieResponse{Tatget: "count", Datapoints: points}}, nil
+
+	case TargetAlerts:
+		if d.store == nil {
+			return nil, errStoreDisabled
+		}
+		alerts, err := d.store.QueryAlerts(ctx, &history.AlertQuery{
+			Cluster: f["cluster"],
+			Type:    f["type"],
+			Level:   f["level"],
+			Start:   from,
+			End:     to,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{alertTable(alerts)}, nil
+
+	case TargetAlertStats:
+		if d.store == nil {
+			return nil, errStoreDisabled
+		}
+		keys, err := history.ParseStatKeys(f["by"])
+		if err != nil {
+			return nil, err
+		}
+		notifications, err := d.store.QueryNotifications(ctx, &history.AlertQuery{
+			Cluster:  f["cluster"],
+			Type:     f["type"],
+			Level:    f["level"],
+			Receiver: f["receiver"],
+			Start:    from,
+			End:      to,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{alertStatTable(history.AlertStats(notifications, keys, grafanaLimit(t.Data)), keys)}, nil
+	}
+	return nil, errors.Errorf("unknown target %q", t.Target)
+}
+
+// Annotations returns the non-pass status changes of checkers, or alerts when the query has "target=alerts"
+func (d *GrafanaDatasource) Annotations(rw http.ResponseWriter, req *http.Request) {
+	q := &GrafanaAnnotationRequest{}
+	if err := decodeGrafanaRequest(req, q); err != nil {
+		errMsg := fmt.Sprintf("[grafana annotations] invalid request: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	fillGrafanaRange(&q.Range)
+	f := parseGrafanaFilter(q.Annotation.Query)
+
+	var err error
+	annotations := []GrafanaAnnotation{}
+	if f["target"] == TargetAlerts {
+		var alerts []apistructs.AlertRecord
+		if d.store == nil {
+			err = errStoreDisabled
+		} else {
+			alerts, err = d.store.QueryAlerts(req.Context(), &history.AlertQuery{
+				Cluster: f["cluster"],
+				Type:    f["type"],
+				Level:   f["level"],
+				Start:   q.Range.From,
+				End:     q.Range.To,
+			})
+		}
+		for _, a := range alerts {
+			annotations = append(annotations, GrafanaAnnotation{
+				Annotation: q.Annotation,
+				Time:       a.Time.UnixNano() / int64(time.Millisecond),
+				Title:      fmt.Sprintf("%s %s %s", a.Cluster, a.Type, a.Level),
+				Text:       a.Message,
+				Tags:       nonEmpty(a.Cluster, a.Node, a.Type, a.Component, a.Level),
+			})
+		}
+	} else {
+		var results []apistructs.CheckerResult
+		results, err = d.queryResults(req.Context(), f, q.Range.From, q.Range.To)
+		for _, c := range history.Timeline(results) {
+			if c.Status == kubeproberv1.CheckerStatusPass {
+				continue
+			}
+			annotations = append(annotations, GrafanaAnnotation{
+				Annotation: q.Annotation,
+				Time:       c.Since.UnixNano() / int64(time.Millisecond),
+				TimeEnd:    c.Until.UnixNano() / int64(time.Millisecond),
+				Title:      fmt.Sprintf("%s/%s/%s %s", c.Cluster, c.Probe, c.Checker, c.Status),
+				Text:       c.Message,
+				Tags:       nonEmpty(c.Cluster, c.Probe, c.Checker, string(c.Status)),
+			})
+		}
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("[grafana annotations] failed to query annotations: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	writeGrafanaResponse(rw, annotations)
+}
+
+func (d *GrafanaDatasource) TagKeys(rw http.ResponseWriter, req *http.Request) {
+	var keys []GrafanaTagKey
+	for _, k := range grafanaTagKeys {
+		keys = append(keys, GrafanaTagKey{Type: "string", Text: k})
+	}
+	writeGrafanaResponse(rw, keys)
+}
+
+func (d *GrafanaDatasource) TagValues(rw http.ResponseWriter, req *http.Request) {
+	var r struct {
+		Key string `json:"key"`
+	}
+	if err := decodeGrafanaRequest(req, &r); err != nil {
+		errMsg := fmt.Sprintf("[grafana tag values] invalid request: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+
+	values, err := d.tagValues(req.Context(), r.Key)
+	if err != nil {
+		errMsg := fmt.Sprintf("[grafana tag values] failed to get values of %s: %+v\n", r.Key, err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	resp := []GrafanaTagValue{}
+	for _, v := range values {
+		resp = append(resp, GrafanaTagValue{Text: v})
+	}
+	writeGrafanaResponse(rw, resp)
+}
+
+func (d *GrafanaDatasource) tagValues(ctx context.Context, key string) ([]string, error) {
+	set := make(map[string]bool)
+	switch key {
+	case "cluster":
+		clusters, err := k8sclient.GetClusters()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range clusters {
+			set[c.Name] = true
+		}
+	case "probe":
+		probes := &kubeproberv1.ProbeList{}
+		if err := k8sclient.RestClient.List(ctx, probes, client.InNamespace(metav1.NamespaceDefault)); err != nil {
+			return nil, err
+		}
+		for _, p := range probes.Items {
+			set[p.Name] = true
+		}
+	case "checker", "type", "level", "receiver":
+		if d.store == nil {
+			break
+		}
+		values, err := d.store.TagValues(ctx, key, time.Now().Add(-history.DefaultRange))
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			set[v] = true
+		}
+	default:
+		return nil, errors.Errorf("unknown tag key %q", key)
+	}
+
+	var values []string
+	for v := range set {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+func (d *GrafanaDatasource) queryResults(ctx context.Context, f grafanaFilter, from, to time.Time) ([]apistructs.CheckerResult, error) {
+	if d.store == nil {
+		return nil, errStoreDisabled
+	}
+	return d.store.QueryResults(ctx, &history.Query{
+		Cluster: f["cluster"],
+		Probe:   f["probe"],
+		Checker: f["checker"],
+		Start:   from,
+		End:     to,
+	})
+}
+
+func checkerResultTable(results []apistructs.CheckerResult) TableResponse {
+	table := TableResponse{
+		Columns: []Column{
+			{Text: "TIME", Type: "time"},
+			{Text: "CLUSTER", Type: "string"},
+			{Text: "PROBE", Type: "string"},
+			{Text: "CHECKER", Type: "string"},
+			{Text: "STATUS", Type: "string"},
+			{Text: "MESSAGE", Type: "string"},
+		},
+		Rows: [][]interface{}{},
+		Type: "table",
+	}
+	for _, r := range results {
+		table.Rows = append(table.Rows, []interface{}{timeMs(r.Time), r.Cluster, r.Probe, r.Checker, r.Status, r.Message})
+	}
+	return table
+}
+
+func alertTable(alerts []apistructs.AlertRecord) TableResponse {
+	table := TableResponse{
+		Columns: []Column{
+			{Text: "TIME", Type: "time"},
+			{Text: "CLUSTER", Type: "string"},
+			{Text: "NODE", Type: "string"},
+			{Text: "TYPE", Type: "string"},
+			{Text: "COMPONENT", Type: "string"},
+			{Text: "LEVEL", Type: "string"},
+			{Text: "MESSAGE", Type: "string"},
+		},
+		Rows: [][]interface{}{},
+		Type: "table",
+	}
+	for _, a := range alerts {
+		table.Rows = append(table.Rows, []interface{}{timeMs(a.Time), a.Cluster, a.Node, a.Type, a.Component, a.Level, a.Message})
+	}
+	return table
+}
+
+func alertStatTable(stats []apistructs.AlertStat, keys []string) TableResponse {
+	table := TableResponse{Rows: [][]interface{}{}, Type: "table"}
+	for _, k := range keys {
+		table.Columns = append(table.Columns, Column{Text: strings.ToUpper(k), Type: "string"})
+	}
+	table.Columns = append(table.Columns, Column{Text: "COUNT", Type: "number"})
+	for _, s := range stats {
+		var row []interface{}
+		for _, k := range keys {
+			row = append(row, statValue(s, k))
+		}
+		table.Rows = append(table.Rows, append(row, s.Count))
+	}
+	return table
+}
+
+func statValue(s apistructs.AlertStat, key string) string {
+	switch key {
+	case history.StatCluster:
+		return s.Cluster
+	case history.StatType:
+		return s.Type
+	case history.StatChecker:
+		return s.Checker
+	case history.StatLevel:
+		return s.Level
+	case history.StatReceiver:
+		return s.Receiver
+	}
+	return ""
+}
+
+// grafanaLimit returns "limit" of target data, either a number or a string
+func grafanaLimit(data map[string]interface{}) int {
+	switch v := data["limit"].(type) {
+	case float64:
+		return int(v)
+	case string:
+		limit, _ := strconv.Atoi(v)
+		return limit
+	}
+	return 0
+}
+
+// checkerStatusSeries returns one series of status priority for every checker
+func checkerStatusSeries(results []apistructs.CheckerResult) []interface{} {
+	var keys []string
+	points := make(map[string][][]float64)
+	for _, r := range results {
+		k := r.Key()
+		if _, ok := points[k]; !ok {
+			keys = append(keys, k)
+		}
+		points[k] = append(points[k], []float64{float64(r.Status.Priority()), timeMs(r.Time)})
+	}
+	sort.Strings(keys)
+
+	series := []interface{}{}
+	for _, k := range keys {
+		series = append(series, TimeSerieResponse{Tatget: k, Datapoints: points[k]})
+	}
+	return series
+}
+
+// passRateSeries returns the pass rate in percent of every interval, intervals without results are skipped
+func passRateSeries(results []apistructs.CheckerResult, from, to time.Time, interval time.Duration) TimeSerieResponse {
+	s := TimeSerieResponse{Tatget: "pass rate", Datapoints: [][]float64{}}
+	for _, r := range history.PassRates(results, from, to, interval) {
+		if r.Total == 0 {
+			continue
+		}
+		s.Datapoints = append(s.Datapoints, []float64{r.Rate * 100, timeMs(r.Start)})
+	}
+	return s
+}
+
+// checkerErrorSeries returns the count of error results in every interval for every cluster
+func checkerErrorSeries(results []apistructs.CheckerResult, from time.Time, interval time.Duration) []interface{} {
+	var clusters []string
+	counts := make(map[string]map[int64]float64)
+	for _, r := range results {
+		if r.Status != kubeproberv1.CheckerStatusError {
+			continue
+		}
+		if _, ok := counts[r.Cluster]; !ok {
+			clusters = append(clusters, r.Cluster)
+			counts[r.Cluster] = make(map[int64]float64)
+		}
+		bucket := from.Add(r.Time.Sub(from) / interval * interval)
+		counts[r.Cluster][bucket.UnixNano()/int64(time.Millisecond)]++
+	}
+	sort.Strings(clusters)
+
+	series := []interface{}{}
+	for _, c := range clusters {
+		s := TimeSerieResponse{Tatget: c}
+		for ts, v := range counts[c] {
+			s.Datapoints = append(s.Datapoints, []float64{v, float64(ts)})
+		}
+		sort.Slice(s.Datapoints, func(i, j int) bool {
+			return s.Datapoints[i][1] < s.Datapoints[j][1]
+		})
+		series = append(series, s)
+	}
+	return series
+}
+
+func newGrafanaFilter(adhocFilters []GrafanaAdhocFilter, data map[string]interface{}) grafanaFilter {
+	f := grafanaFilter{}
+	for _, a := range adhocFilters {
+		if a.Operator != "" && a.Operator != "=" {
+			klog.Warningf("[grafana query] unsupported operator %s of adhoc filter %s, ignore it", a.Operator, a.Key)
+			continue
+		}
+		f[a.Key] = a.Value
+	}
+	// filters in target data take precedence over adhoc filters
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			f[k] = s
+		}
+	}
+	return f
+}
+
+// parseGrafanaFilter parses filters like "cluster=foo,checker=bar"
+func parseGrafanaFilter(s string) grafanaFilter {
+	f := grafanaFilter{}
+	for _, item := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) == 2 {
+			f[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return f
+}
+
+func (f grafanaFilter) match(key, value string) bool {
+	v, ok := f[key]
+	return !ok || v == "" || v == value
+}
+
+// grafanaInterval returns the interval of datapoints, which keeps the count of datapoints under maxDataPoints
+func grafanaInterval(q *GrafanaQueryRequest) time.Duration {
+	interval := time.Duration(q.IntervalMs) * time.Millisecond
+	if q.MaxDataPoints > 0 {
+		if min := q.Range.To.Sub(q.Range.From) / time.Duration(q.MaxDataPoints); interval < min {
+			interval = min
+		}
+	}
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	return interval
+}
+
+// fillGrafanaRange defaults the range to the last day
+func fillGrafanaRange(r *GrafanaRange) {
+	if r.To.IsZero() {
+		r.To = time.Now()
+	}
+	if r.From.IsZero() || !r.From.Before(r.To) {
+		r.From = r.To.Add(-history.DefaultRange)
+	}
+}
+
+// decodeGrafanaRequest decodes the json body, an empty body is allowed
+func decodeGrafanaRequest(req *http.Request, v interface{}) error {
+	if req.Body == nil || req.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func writeGrafanaResponse(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		klog.Errorf("json encode for grafana datasource error: %+v\n", err)
+	}
+}
+
+func timeMs(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
+
+func nonEmpty(values ...string) []string {
+	var r []string
+	for _, v := range values {
+		if v != "" {
+			r = append(r, v)
+		}
+	}
+	return r
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/history"
+)
+
+// fakeStore serves results from memory and records the last query
+type fakeStore struct {
+	results []apistructs.CheckerResult
+	query   *history.Query
+	tag     string
+}
+
+func (s *fakeStore) WriteResult(r *apistructs.CheckerResult) error { return nil }
+
+func (s *fakeStore) QueryResults(ctx context.Context, q *history.Query) ([]apistructs.CheckerResult, error) {
+	s.query = q
+	return s.results, nil
+}
+
+func (s *fakeStore) WriteInventory(i *apistructs.ClusterInventory) error { return nil }
+
+func (s *fakeStore) QueryInventories(ctx context.Context, q *history.Query) ([]apistructs.ClusterInventory, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) WriteAlert(a *apistructs.AlertRecord) error { return nil }
+
+func (s *fakeStore) QueryAlerts(ctx context.Context, q *history.AlertQuery) ([]apistructs.AlertRecord, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) WriteNotification(n *apistructs.AlertNotification) error { return nil }
+
+func (s *fakeStore) QueryNotifications(ctx context.Context, q *history.AlertQuery) ([]apistructs.AlertNotification, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) TagValues(ctx context.Context, tag string, start time.Time) ([]string, error) {
+	s.tag = tag
+	return []string{"dns", "", "etcd", "dns"}, nil
+}
+
+func TestParseGrafanaFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  grafanaFilter
+	}{
+		{"empty", "", grafanaFilter{}},
+		{"single", "cluster=foo", grafanaFilter{"cluster": "foo"}},
+		{"spaces", " cluster = foo , checker=bar ", grafanaFilter{"cluster": "foo", "checker": "bar"}},
+		{"value with equal sign", "target=a=b", grafanaFilter{"target": "a=b"}},
+		{"invalid item", "cluster,checker=bar", grafanaFilter{"checker": "bar"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseGrafanaFilter(tt.query))
+		})
+	}
+}
+
+func TestNewGrafanaFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		adhoc []GrafanaAdhocFilter
+		data  map[string]interface{}
+		want  grafanaFilter
+	}{
+		{
+			name:  "adhoc filters",
+			adhoc: []GrafanaAdhocFilter{{Key: "cluster", Operator: "=", Value: "foo"}, {Key: "checker", Value: "dns"}},
+			want:  grafanaFilter{"cluster": "foo", "checker": "dns"},
+		},
+		{
+			name:  "unsupported operator",
+			adhoc: []GrafanaAdhocFilter{{Key: "cluster", Operator: "!=", Value: "foo"}, {Key: "level", Operator: "=~", Value: "e.*"}},
+			want:  grafanaFilter{},
+		},
+		{
+			name:  "target data takes precedence",
+			adhoc: []GrafanaAdhocFilter{{Key: "cluster", Operator: "=", Value: "foo"}},
+			data:  map[string]interface{}{"cluster": "bar", "by": "cluster,type", "limit": 10},
+			want:  grafanaFilter{"cluster": "bar", "by": "cluster,type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newGrafanaFilter(tt.adhoc, tt.data))
+		})
+	}
+}
+
+func TestGrafanaInterval(t *testing.T) {
+	from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		rangeDuration time.Duration
+		intervalMs    int64
+		maxDataPoints int64
+		want          time.Duration
+	}{
+		{"interval of request", 24 * time.Hour, int64(5 * time.Minute / time.Millisecond), 1000, 5 * time.Minute},
+		{"limited by max data points", 24 * time.Hour, int64(time.Minute / time.Millisecond), 144, 10 * time.Minute},
+		{"at least one minute", time.Hour, 1000, 0, time.Minute},
+		{"no interval", time.Hour, 0, 0, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &GrafanaQueryRequest{
+				Range:         GrafanaRange{From: from, To: from.Add(tt.rangeDuration)},
+				IntervalMs:    tt.intervalMs,
+				MaxDataPoints: tt.maxDataPoints,
+			}
+			assert.Equal(t, tt.want, grafanaInterval(q))
+		})
+	}
+}
+
+func newCheckerResult(cluster, checker string, status kubeproberv1.CheckerStatus, t time.Time) apistructs.CheckerResult {
+	return apistructs.CheckerResult{
+		Cluster: cluster,
+		Probe:   "probe-test",
+		Checker: checker,
+		Status:  status,
+		Message: "message of " + checker,
+		Time:    t,
+	}
+}
+
+func TestCheckerErrorSeries(t *testing.T) {
+	from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	results := []apistructs.CheckerResult{
+		newCheckerResult("cluster-b", "dns", kubeproberv1.CheckerStatusError, from.Add(11*time.Minute)),
+		newCheckerResult("cluster-a", "dns", kubeproberv1.CheckerStatusError, from.Add(time.Minute)),
+		newCheckerResult("cluster-a", "etcd", kubeproberv1.CheckerStatusError, from.Add(9*time.Minute)),
+		newCheckerResult("cluster-a", "dns", kubeproberv1.CheckerStatusPass, from.Add(2*time.Minute)),
+		newCheckerResult("cluster-a", "dns", kubeproberv1.CheckerStatusWARN, from.Add(3*time.Minute)),
+		newCheckerResult("cluster-b", "dns", kubeproberv1.CheckerStatusError, from.Add(time.Minute)),
+		newCheckerResult("cluster-b", "dns", kubeproberv1.CheckerStatusError, from.Add(12*time.Minute)),
+	}
+
+	series := checkerErrorSeries(results, from, 10*time.Minute)
+	require.Equal(t, 2, len(series))
+	assert.Equal(t, TimeSerieResponse{
+		Tatget:     "cluster-a",
+		Datapoints: [][]float64{{2, timeMs(from)}},
+	}, series[0])
+	assert.Equal(t, TimeSerieResponse{
+		Tatget:     "cluster-b",
+		Datapoints: [][]float64{{1, timeMs(from)}, {2, timeMs(from.Add(10 * time.Minute))}},
+	}, series[1])
+
+	assert.Equal(t, []interface{}{}, checkerErrorSeries(results[3:5], from, 10*time.Minute))
+}
+
+func doGrafanaQuery(t *testing.T, d *GrafanaDatasource, q *GrafanaQueryRequest) *httptest.ResponseRecorder {
+	body, err := json.Marshal(q)
+	require.NoError(t, err)
+	rw := httptest.NewRecorder()
+	d.Query(rw, httptest.NewRequest(http.MethodPost, "/grafana/query", bytes.NewReader(body)))
+	return rw
+}
+
+func TestGrafanaQuery(t *testing.T) {
+	from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	store := &fakeStore{results: []apistructs.CheckerResult{
+		newCheckerResult("cluster-a", "dns", kubeproberv1.CheckerStatusPass, from.Add(time.Minute)),
+		newCheckerResult("cluster-a", "dns", kubeproberv1.CheckerStatusError, from.Add(2*time.Minute)),
+	}}
+	d := NewGrafanaDatasource(store, TargetCheckerResults)
+	rangeOfDay := GrafanaRange{From: from, To: from.Add(24 * time.Hour)}
+
+	t.Run("table", func(t *testing.T) {
+		rw := doGrafanaQuery(t, d, &GrafanaQueryRequest{
+			Range:        rangeOfDay,
+			Targets:      []GrafanaTarget{{Target: TargetCheckerResults, Type: "table"}},
+			AdhocFilters: []GrafanaAdhocFilter{{Key: "cluster", Operator: "=", Value: "cluster-a"}},
+		})
+		require.Equal(t, http.StatusOK, rw.Code)
+		var resp []TableResponse
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, 1, len(resp))
+		assert.Equal(t, "table", resp[0].Type)
+		assert.Equal(t, "STATUS", resp[0].Columns[4].Text)
+		require.Equal(t, 2, len(resp[0].Rows))
+		assert.Equal(t, []interface{}{timeMs(from.Add(2 * time.Minute)), "cluster-a", "probe-test", "dns", "ERROR", "message of dns"}, resp[0].Rows[1])
+		assert.Equal(t, "cluster-a", store.query.Cluster)
+		assert.Equal(t, from, store.query.Start)
+	})
+
+	t.Run("default target", func(t *testing.T) {
+		rw := doGrafanaQuery(t, d, &GrafanaQueryRequest{Range: rangeOfDay})
+		require.Equal(t, http.StatusOK, rw.Code)
+		var resp []TableResponse
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, 1, len(resp))
+		assert.Equal(t, 2, len(resp[0].Rows))
+	})
+
+	t.Run("timeseries", func(t *testing.T) {
+		rw := doGrafanaQuery(t, d, &GrafanaQueryRequest{
+			Range:      rangeOfDay,
+			IntervalMs: int64(time.Hour / time.Millisecond),
+			Targets: []GrafanaTarget{
+				{Target: TargetCheckerStatus, Type: "timeserie"},
+				{Target: TargetCheckerErrors, Type: "timeserie"},
+				{Target: TargetCheckerPassRate, Type: "timeserie", Hide: true},
+			},
+		})
+		require.Equal(t, http.StatusOK, rw.Code)
+		var resp []TimeSerieResponse
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		assert.Equal(t, []TimeSerieResponse{
+			{
+				Tatget:     "cluster-a/probe-test/dns",
+				Datapoints: [][]float64{{0, timeMs(from.Add(time.Minute))}, {4, timeMs(from.Add(2 * time.Minute))}},
+			},
+			{
+				Tatget:     "cluster-a",
+				Datapoints: [][]float64{{1, timeMs(from)}},
+			},
+		}, resp)
+	})
+
+	t.Run("store disabled", func(t *testing.T) {
+		rw := doGrafanaQuery(t, NewGrafanaDatasource(nil, TargetCheckerResults), &GrafanaQueryRequest{Range: rangeOfDay})
+		assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	})
+}
+
+func TestGrafanaTagValues(t *testing.T) {
+	store := &fakeStore{}
+	d := NewGrafanaDatasource(store, TargetCheckerResults)
+
+	values, err := d.tagValues(context.Background(), "checker")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dns", "etcd"}, values)
+	assert.Equal(t, "checker", store.tag)
+
+	_, err = d.tagValues(context.Background(), "unknown")
+	assert.Error(t, err)
+}
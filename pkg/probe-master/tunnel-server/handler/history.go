@@ -0,0 +1,229 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/history"
+)
+
+// GetCheckerHistory returns raw checker results
+func GetCheckerHistory(rw http.ResponseWriter, req *http.Request, store history.Store) {
+	q, results, ok := queryHistory(rw, req, store, nil)
+	if !ok {
+		return
+	}
+	writeHistoryResponse(rw, q, results)
+}
+
+// GetCheckerTimeline returns the status changes of every checker
+func GetCheckerTimeline(rw http.ResponseWriter, req *http.Request, store history.Store) {
+	q, results, ok := queryHistory(rw, req, store, nil)
+	if !ok {
+		return
+	}
+	writeHistoryResponse(rw, q, history.Timeline(results))
+}
+
+// GetCheckerPassRate returns the pass rate of every period
+func GetCheckerPassRate(rw http.ResponseWriter, req *http.Request, store history.Store) {
+	period := history.DefaultPeriod
+	if p := req.URL.Query().Get("period"); p != "" {
+		d, err := time.ParseDuration(p)
+		if err != nil || d <= 0 {
+			errMsg := fmt.Sprintf("[history] invalid period %q\n", p)
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(errMsg))
+			return
+		}
+		period = d
+	}
+
+	q, results, ok := queryHistory(rw, req, store, func(q *history.Query) error {
+		return history.ValidatePeriod(q.Start, q.End, period)
+	})
+	if !ok {
+		return
+	}
+	writeHistoryResponse(rw, q, history.PassRates(results, q.Start, q.End, period))
+}
+
+// GetInventoryHistory returns the cluster inventories reported by heartbeats
+func GetInventoryHistory(rw http.ResponseWriter, req *http.Request, store history.Store) {
+	if store == nil {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		rw.Write([]byte("[history] result store is not enabled\n"))
+		return
+	}
+
+	q, err := parseHistoryQuery(req)
+	if err != nil {
+		errMsg := fmt.Sprintf("[history] invalid query: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+
+	inventories, err := store.QueryInventories(req.Context(), q)
+	if err != nil {
+		errMsg := fmt.Sprintf("[history] failed to query cluster inventories: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	writeHistoryResponse(rw, q, inventories)
+}
+
+// GetAlertStats returns the counts of sent alerts grouped by keys of "by", e.g. "cluster,type"
+func GetAlertStats(rw http.ResponseWriter, req *http.Request, store history.Store) {
+	if store == nil {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		rw.Write([]byte("[alert stats] result store is not enabled\n"))
+		return
+	}
+
+	v := req.URL.Query()
+	keys, err := history.ParseStatKeys(v.Get("by"))
+	if err != nil {
+		errMsg := fmt.Sprintf("[alert stats] invalid query: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	limit := 0
+	if l := v.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			errMsg := fmt.Sprintf("[alert stats] invalid limit %q\n", l)
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(errMsg))
+			return
+		}
+	}
+	q := &history.AlertQuery{
+		Cluster:  v.Get("cluster"),
+		Type:     v.Get("type"),
+		Level:    v.Get("level"),
+		Receiver: v.Get("receiver"),
+	}
+	now := time.Now()
+	if q.Start, err = parseHistoryTime(v.Get("start"), now); err == nil {
+		if q.End, err = parseHistoryTime(v.Get("end"), now); err == nil {
+			err = q.Validate()
+		}
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("[alert stats] invalid query: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+
+	notifications, err := store.QueryNotifications(req.Context(), q)
+	if err != nil {
+		errMsg := fmt.Sprintf("[alert stats] failed to query alert notifications: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	writeHistoryResponse(rw, &history.Query{Start: q.Start, End: q.End}, history.AlertStats(notifications, keys, limit))
+}
+
+// queryHistory parses the query, runs the optional check on it and then queries the store
+func queryHistory(rw http.ResponseWriter, req *http.Request, store history.Store, check func(*history.Query) error) (*history.Query, []apistructs.CheckerResult, bool) {
+	if store == nil {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		rw.Write([]byte("[history] result store is not enabled\n"))
+		return nil, nil, false
+	}
+
+	q, err := parseHistoryQuery(req)
+	if err == nil && check != nil {
+		err = check(q)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("[history] invalid query: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return nil, nil, false
+	}
+
+	results, err := store.QueryResults(req.Context(), q)
+	if err != nil {
+		errMsg := fmt.Sprintf("[history] failed to query checker results: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return nil, nil, false
+	}
+	return q, results, true
+}
+
+func parseHistoryQuery(req *http.Request) (*history.Query, error) {
+	var err error
+	v := req.URL.Query()
+	now := time.Now()
+	q := &history.Query{
+		Cluster: v.Get("cluster"),
+		Probe:   v.Get("probe"),
+		Checker: v.Get("checker"),
+	}
+	if q.Start, err = parseHistoryTime(v.Get("start"), now); err != nil {
+		return nil, err
+	}
+	if q.End, err = parseHistoryTime(v.Get("end"), now); err != nil {
+		return nil, err
+	}
+	if err = q.Validate(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parseHistoryTime accepts RFC3339 timestamps, unix seconds or a duration relative to now, e.g. "-24h"
+func parseHistoryTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if !strings.HasPrefix(s, "-") {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func writeHistoryResponse(rw http.ResponseWriter, q *history.Query, data interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(apistructs.HistoryResponse{Start: q.Start, End: q.End, Data: data}); err != nil {
+		klog.Errorf("json encode for checker history error: %+v\n", err)
+	}
+}
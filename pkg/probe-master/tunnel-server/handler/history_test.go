@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCheckerPassRatePeriod(t *testing.T) {
+	cases := []struct {
+		query string
+		code  int
+	}{
+		{"period=1ns&start=-720h", http.StatusBadRequest},
+		{"period=30s", http.StatusBadRequest},
+		{"period=1m&start=-720h", http.StatusBadRequest},
+		{"period=1h&start=-720h", http.StatusOK},
+		{"period=1m", http.StatusOK},
+	}
+	for _, c := range cases {
+		store := &fakeStore{}
+		rw := httptest.NewRecorder()
+		GetCheckerPassRate(rw, httptest.NewRequest(http.MethodGet, "/api/history/passrate?"+c.query, nil), store)
+		assert.Equal(t, c.code, rw.Code, c.query)
+		if c.code != http.StatusOK {
+			assert.Nil(t, store.query, c.query)
+		}
+	}
+}
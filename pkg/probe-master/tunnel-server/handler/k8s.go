@@ -15,16 +15,13 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
 	"sort"
 	"time"
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
 	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -42,21 +39,13 @@ type TableResponse struct {
 }
 
 type TimeSerieResponse struct {
-	Tatget     string    `json:"target"`
-	Datapoints [][]int64 `json:"datapoints"`
+	Tatget     string      `json:"target"`
+	Datapoints [][]float64 `json:"datapoints"`
 }
 
-func GetClusterList(rw http.ResponseWriter, req *http.Request) {
-	var err error
+// clusterTable builds the grafana table of clusters
+func clusterTable(clusters []kubeproberv1.Cluster) TableResponse {
 	var listRow [][]interface{}
-	clusters, err := k8sclient.GetClusters()
-	if err != nil {
-		errMsg := fmt.Sprintf("[cluster query] failed to get cluster list: %+v\n", err)
-		rw.Write([]byte(errMsg))
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
 	for _, i := range clusters {
 		var list []interface{}
 		list = append(list, i.Name)
@@ -88,10 +77,14 @@ func GetClusterList(rw http.ResponseWriter, req *http.Request) {
 		list = append(list, i.Status.ExtraStatus["jobNum"])
 		list = append(list, i.Status.ExtraStatus["cronjobNum"])
 		list = append(list, i.Status.ExtraStatus["deploymentNum"])
-		list = append(list, i.Status.HeartBeatTimeStamp)
+		if i.Status.HeartBeatTime != nil {
+			list = append(list, timeMs(i.Status.HeartBeatTime.Time))
+		} else {
+			list = append(list, nil)
+		}
 		listRow = append(listRow, list)
 	}
-	resp := TableResponse{
+	return TableResponse{
 		Columns: []Column{
 			{Text: "NAME", Type: "string"},
 			{Text: "VERSION", Type: "string"},
@@ -122,49 +115,39 @@ func GetClusterList(rw http.ResponseWriter, req *http.Request) {
 			{Text: "JOBNUM", Type: "string"},
 			{Text: "CRONJOBNUM", Type: "string"},
 			{Text: "DEPLOYMENTNUM", Type: "string"},
-			{Text: "HEARTBEATTIME", Type: "string"},
+			{Text: "HEARTBEATTIME", Type: "time"},
 		},
 		Rows: listRow,
 		Type: "table",
 	}
-
-	if err := json.NewEncoder(rw).Encode([]TableResponse{resp}); err != nil {
-		klog.Errorf("json encode for cluster list error: %+v\n", err)
-	}
 }
 
-func GetAlertStatistic(rw http.ResponseWriter, req *http.Request) {
-	var err error
-	var listRow [][]int64
+// alertCountPoints returns the daily alert count of dingding alert in [from, to],
+// every point is at the end of the day
+func alertCountPoints(ctx context.Context, from, to time.Time) ([][]float64, error) {
 	alert := &kubeproberv1.Alert{}
-
-	if err = k8sclient.RestClient.Get(context.Background(), client.ObjectKey{
+	if err := k8sclient.RestClient.Get(ctx, client.ObjectKey{
 		Namespace: metav1.NamespaceDefault,
 		Name:      DINGDING_ALERT_NAME,
 	}, alert); err != nil {
-		errMsg := fmt.Sprintf("[alert statistic query] failed to get dingding alert statistic: %+v\n", err)
-		rw.Write([]byte(errMsg))
-		rw.WriteHeader(http.StatusBadRequest)
-		return
+		return nil, err
 	}
-	l, _ := time.LoadLocation("Asia/Shanghai")
-	for k, v := range alert.Status.AlertCount {
-		var list []int64
 
-		ts, _ := time.ParseInLocation("2006-01-02 15:04:05", fmt.Sprintf("%s 23:59:59", k), l)
-		list = append(list, int64(v))
-		list = append(list, ts.Unix()*1000)
-		listRow = append(listRow, list)
+	var points [][]float64
+	for k, v := range alert.Status.AlertCount {
+		day, err := timezone.ParseDay(k)
+		if err != nil {
+			continue
+		}
+		ts := day.Add(24*time.Hour - time.Second)
+		// keep the day which overlaps with the range
+		if ts.Before(from) || ts.Add(-24*time.Hour).After(to) {
+			continue
+		}
+		points = append(points, []float64{float64(v), float64(ts.Unix() * 1000)})
 	}
-	sort.Slice(listRow, func(i, j int) bool {
-		return listRow[i][1] < listRow[j][1]
+	sort.Slice(points, func(i, j int) bool {
+		return points[i][1] < points[j][1]
 	})
-	resp := TimeSerieResponse{
-		Tatget:     "count",
-		Datapoints: listRow,
-	}
-
-	if err := json.NewEncoder(rw).Encode([]TimeSerieResponse{resp}); err != nil {
-		klog.Errorf("json encode for cluster list error: %+v\n", err)
-	}
+	return points, nil
 }
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/pkg/probe-master/history"
+	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
+	"github.com/erda-project/kubeprober/pkg/probe-master/timezone"
+	"github.com/erda-project/kubeprober/pkg/report"
+)
+
+// GetReport renders the diagnostics report of clusters in markdown or html, checker results
+// and trends are read from the result store, query parameters:
+// cluster (comma separated), selector, probe, start, end, format and title
+func GetReport(rw http.ResponseWriter, req *http.Request, store history.Store) {
+	if store == nil {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		rw.Write([]byte("[report] result store is not enabled\n"))
+		return
+	}
+
+	v := req.URL.Query()
+	format := v.Get("format")
+	if format == "" {
+		format = report.FormatHTML
+	}
+	q, err := parseHistoryQuery(req)
+	if err == nil {
+		err = report.ValidateFormat(format)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("[report] invalid query: %+v\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+
+	clusters, err := reportClusters(req.Context(), v.Get("cluster"), v.Get("selector"))
+	if err != nil {
+		errMsg := fmt.Sprintf("[report] failed to get clusters: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	// one query for all clusters, results of other clusters are ignored by cluster reports
+	q.Cluster = ""
+	if len(clusters) == 1 {
+		q.Cluster = clusters[0].Name
+	}
+	results, err := store.QueryResults(req.Context(), q)
+	if err != nil {
+		errMsg := fmt.Sprintf("[report] failed to query checker results: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(errMsg))
+		return
+	}
+
+	latest := report.LatestResults(results)
+	timeline := history.Timeline(results)
+	clusterReports := make([]report.ClusterReport, 0, len(clusters))
+	for i := range clusters {
+		clusterReports = append(clusterReports, report.NewClusterReport(&clusters[i], latest, timeline))
+	}
+	title := v.Get("title")
+	if title == "" {
+		title = fmt.Sprintf("KubeProber Diagnostics Report %s", timezone.Day(q.End))
+	}
+	r := report.New(title, q.Start, q.End, clusterReports)
+	r.Location = timezone.Location()
+
+	rw.Header().Set("Content-Type", report.ContentType(format))
+	if err = report.Render(rw, r, format); err != nil {
+		klog.Errorf("[report] render report error: %+v\n", err)
+	}
+}
+
+// reportClusters gets clusters by comma separated names, or lists clusters matching selector
+func reportClusters(ctx context.Context, names string, selector string) ([]kubeproberv1.Cluster, error) {
+	if names != "" {
+		var clusters []kubeproberv1.Cluster
+		for _, name := range strings.Split(names, ",") {
+			c := kubeproberv1.Cluster{}
+			if err := k8sclient.RestClient.Get(ctx, client.ObjectKey{
+				Namespace: metav1.NamespaceDefault,
+				Name:      strings.TrimSpace(name),
+			}, &c); err != nil {
+				return nil, err
+			}
+			clusters = append(clusters, c)
+		}
+		return clusters, nil
+	}
+
+	opts := []client.ListOption{client.InNamespace(metav1.NamespaceDefault)}
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: s})
+	}
+	clusterList := &kubeproberv1.ClusterList{}
+	if err := k8sclient.RestClient.List(ctx, clusterList, opts...); err != nil {
+		return nil, err
+	}
+	return clusterList.Items, nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/klog"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/route"
+)
+
+// GetAlertRoute returns the routing decision of a checker result described by query parameters,
+// status is ERROR if not specified
+func GetAlertRoute(rw http.ResponseWriter, req *http.Request, router *route.Router) {
+	v := req.URL.Query()
+	r := &apistructs.CheckerResult{
+		Cluster: v.Get("cluster"),
+		Probe:   v.Get("probe"),
+		Checker: v.Get("checker"),
+		Status:  kubeproberv1.CheckerStatus(v.Get("status")),
+	}
+	if r.Status == "" {
+		r.Status = kubeproberv1.CheckerStatusError
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(router.Match(r)); err != nil {
+		klog.Errorf("json encode for alert route error: %+v\n", err)
+	}
+}
@@ -32,7 +32,6 @@ import (
 	erda_api "github.com/erda-project/erda/apistructs"
 	"github.com/gorilla/mux"
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
-	influxdb2api "github.com/influxdata/influxdb-client-go/v2/api"
 	"github.com/pkg/errors"
 	"github.com/rancher/remotedialer"
 	"github.com/sirupsen/logrus"
@@ -46,8 +45,16 @@ import (
 
 	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
 	"github.com/erda-project/kubeprober/apistructs"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/alertmanager"
 	"github.com/erda-project/kubeprober/pkg/probe-master/alert/dingding"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/manager"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/message"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/notifier"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/route"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/silence"
+	"github.com/erda-project/kubeprober/pkg/probe-master/alert/suppress"
 	"github.com/erda-project/kubeprober/pkg/probe-master/alert/ticket"
+	"github.com/erda-project/kubeprober/pkg/probe-master/history"
 	"github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
 	_ "github.com/erda-project/kubeprober/pkg/probe-master/k8sclient"
 	httphandler "github.com/erda-project/kubeprober/pkg/probe-master/tunnel-server/handler"
@@ -63,7 +70,7 @@ func clusterRegister(server *remotedialer.Server, rw http.ResponseWriter, req *h
 	server.ServeHTTP(rw, req)
 }
 
-func heartbeat(rw http.ResponseWriter, req *http.Request) {
+func heartbeat(rw http.ResponseWriter, req *http.Request, resultStore history.Store) {
 	hbData := apistructs.HeartBeatReq{}
 	cluster := &kubeproberv1.Cluster{}
 	var err error
@@ -121,13 +128,13 @@ func heartbeat(rw http.ResponseWriter, req *http.Request) {
 			return
 		}
 	}
-	loc, _ := time.LoadLocation("Asia/Shanghai")
 	statusPatchBody := kubeproberv1.Cluster{
 		Status: kubeproberv1.ClusterStatus{
-			HeartBeatTimeStamp: time.Now().In(loc).Format("2006-01-02 15:04:05"),
-			NodeCount:          hbData.NodeCount,
-			Checkers:           hbData.Checkers,
-			ExtraStatus:        hbData.ExtraStatus,
+			HeartBeatTime: &metav1.Time{Time: time.Now()},
+			NodeCount:     hbData.NodeCount,
+			Checkers:      hbData.Checkers,
+			ExtraStatus:   hbData.ExtraStatus,
+			AgentVersion:  hbData.AgentVersion,
 		},
 	}
 	statusPatch, _ := json.Marshal(statusPatchBody)
@@ -145,6 +152,18 @@ func heartbeat(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// keep inventory snapshots for comparing clusters with their history
+	if resultStore != nil {
+		inventory := apistructs.NewClusterInventory(&kubeproberv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: hbData.Name},
+			Spec:       clusterSpec.Spec,
+			Status:     statusPatchBody.Status,
+		}, statusPatchBody.Status.HeartBeatTime.Time)
+		if err = resultStore.WriteInventory(inventory); err != nil {
+			klog.Errorf("[heartbeat] write inventory of cluster[%s] error: %+v\n", hbData.Name, err)
+		}
+	}
+
 	err = updateExternalPrometheusConfigMap(hbData.Name)
 	if err != nil {
 		errMsg := fmt.Sprintf("[heartbeat] update configmap error for cluster[%s]: %+v\n", hbData.Name, err)
@@ -274,16 +293,16 @@ func updateExternalPrometheusConfigMap(clusterName string) error {
 	return nil
 }
 
-func Start(ctx context.Context, cfg *Config, influxdbConfig *apistructs.InfluxdbConf, erdaConfig *apistructs.ErdaConfig) error {
+func Start(ctx context.Context, cfg *Config, influxdbConfig *apistructs.InfluxdbConf, erdaConfig *apistructs.ErdaConfig,
+	alertConfig *apistructs.AlertConfig, ticketConfig *apistructs.TicketConfig) error {
 	var err error
 	var client influxdb2.Client
-	var writeAPI influxdb2api.WriteAPI
-	var alertDataWriteAPI influxdb2api.WriteAPI
+	var resultStore history.Store
 
 	if influxdbConfig.InfluxdbEnable {
 		client = influxdb2.NewClient(influxdbConfig.InfluxdbHost, influxdbConfig.InfluxdbToken)
-		writeAPI = client.WriteAPI(influxdbConfig.InfluxdbOrg, influxdbConfig.InfluxdbBucket)
-		alertDataWriteAPI = client.WriteAPI(influxdbConfig.InfluxdbOrg, influxdbConfig.AlertDataBucket)
+		resultStore = history.NewInfluxStore(client, influxdbConfig.InfluxdbOrg, influxdbConfig.InfluxdbBucket,
+			influxdbConfig.AlertDataBucket)
 		defer client.Close()
 	}
 
@@ -294,6 +313,38 @@ func Start(ctx context.Context, cfg *Config, influxdbConfig *apistructs.Influxdb
 			klog.Errorf("failed to connect erda: %+v\n", err)
 		}
 	}
+	if ticketConfig.Jira.URL != "" {
+		if jira, err := ticket.NewJira(ticketConfig.Jira); err != nil {
+			klog.Errorf("invalid jira config: %+v\n", err)
+		} else {
+			ticket.Register(jira)
+		}
+	}
+	if ticketConfig.GitHub.Repo != "" {
+		if github, err := ticket.NewGitHub(ticketConfig.GitHub); err != nil {
+			klog.Errorf("invalid github config: %+v\n", err)
+		} else {
+			ticket.Register(github)
+		}
+	}
+	// restore issues of ticket fingerprints and unsent tickets
+	ticket.Start(ctx, &ticket.ConfigMapStore{}, ticketConfig.CommentInterval)
+
+	// load silences, suppress matchers, alert receivers, routes and persisted alert states
+	silence.DefaultSilencer.Start(ctx)
+	suppress.DefaultSuppressor.Start(ctx)
+	alertManager := manager.New(*alertConfig, route.DefaultRouter, notifier.DefaultRegistry, silence.DefaultSilencer,
+		&manager.ConfigMapPersister{})
+	if resultStore != nil {
+		alertManager.SetRecorder(resultStore)
+	}
+	alertManager.Start(ctx)
+
+	var alertSink *alertmanager.Sink
+	if alertConfig.AlertmanagerURL != "" {
+		alertSink = alertmanager.NewSink(alertConfig.AlertmanagerURL, alertConfig.ResolveTimeout)
+		alertSink.Start(ctx)
+	}
 
 	handler := remotedialer.New(Authorizer, remotedialer.DefaultErrorWriter)
 	handler.ClientConnectAuthorizer = func(proto, address string) bool {
@@ -311,7 +362,10 @@ func Start(ctx context.Context, cfg *Config, influxdbConfig *apistructs.Influxdb
 	// TODO: support handler.AddPeer
 	router := mux.NewRouter()
 	router.Handle("/clusterdialer", handler)
-	router.Path("/heartbeat").Methods(http.MethodPost).HandlerFunc(heartbeat)
+	router.Path("/heartbeat").Methods(http.MethodPost).HandlerFunc(func(rw http.ResponseWriter,
+		req *http.Request) {
+		heartbeat(rw, req, resultStore)
+	})
 	router.HandleFunc("/clusteragent/connect", func(rw http.ResponseWriter,
 		req *http.Request) {
 		clusterRegister(handler, rw, req)
@@ -319,48 +373,72 @@ func Start(ctx context.Context, cfg *Config, influxdbConfig *apistructs.Influxdb
 
 	router.HandleFunc("/robot/send", func(rw http.ResponseWriter,
 		req *http.Request) {
-		proxyDingdingAlert(rw, req, alertDataWriteAPI)
+		proxyDingdingAlert(rw, req, resultStore, alertManager)
+	})
+
+	router.Path("/api/alertmanager/webhook").Methods(http.MethodPost).HandlerFunc(func(rw http.ResponseWriter,
+		req *http.Request) {
+		receiveAlertmanagerAlert(rw, req, resultStore, alertManager)
 	})
 
 	router.HandleFunc("/collect", func(rw http.ResponseWriter,
 		req *http.Request) {
-		collectProbeStatus(rw, req, writeAPI)
+		collectProbeStatus(rw, req, resultStore, alertManager, alertSink)
 	})
 
-	router.HandleFunc("/cluster", func(rw http.ResponseWriter,
+	router.Path("/api/history/results").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
 		req *http.Request) {
-		rw.WriteHeader(http.StatusOK)
+		httphandler.GetCheckerHistory(rw, req, resultStore)
 	})
 
-	router.HandleFunc("/cluster/query", func(rw http.ResponseWriter,
+	router.Path("/api/history/timeline").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
 		req *http.Request) {
-		httphandler.GetClusterList(rw, req)
+		httphandler.GetCheckerTimeline(rw, req, resultStore)
 	})
 
-	router.HandleFunc("/cluster/search", func(rw http.ResponseWriter,
+	router.Path("/api/report").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
 		req *http.Request) {
-		json.NewEncoder(rw).Encode([]string{"NODECOUNT"})
+		httphandler.GetReport(rw, req, resultStore)
 	})
 
-	httphandler.NewAggregator(ctx)
-	router.HandleFunc("/api/k8s/clusters/{clusterName}", func(rw http.ResponseWriter,
+	router.Path("/api/history/inventory").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
 		req *http.Request) {
-		httphandler.ClusterConsole(rw, req)
+		httphandler.GetInventoryHistory(rw, req, resultStore)
 	})
 
-	router.HandleFunc("/alertstatistic", func(rw http.ResponseWriter,
+	router.Path("/api/alert/route").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
 		req *http.Request) {
-		rw.WriteHeader(http.StatusOK)
+		httphandler.GetAlertRoute(rw, req, route.DefaultRouter)
+	})
+
+	router.Path("/api/alerts").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
+		req *http.Request) {
+		httphandler.GetAlerts(rw, req, alertManager)
+	})
+
+	router.Path("/api/alerts/{id}/ack").Methods(http.MethodPost).HandlerFunc(func(rw http.ResponseWriter,
+		req *http.Request) {
+		httphandler.AckAlert(rw, req, alertManager)
+	})
+
+	router.Path("/api/alert/stats").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
+		req *http.Request) {
+		httphandler.GetAlertStats(rw, req, resultStore)
 	})
 
-	router.HandleFunc("/alertstatistic/query", func(rw http.ResponseWriter,
+	router.Path("/api/history/passrate").Methods(http.MethodGet).HandlerFunc(func(rw http.ResponseWriter,
 		req *http.Request) {
-		httphandler.GetAlertStatistic(rw, req)
+		httphandler.GetCheckerPassRate(rw, req, resultStore)
 	})
 
-	router.HandleFunc("/alertstatistic/search", func(rw http.ResponseWriter,
+	// grafana json datasource, /cluster and /alertstatistic keep the default target of old dashboards
+	httphandler.NewGrafanaDatasource(resultStore, httphandler.TargetClusters).Register(router, "/cluster")
+	httphandler.NewGrafanaDatasource(resultStore, httphandler.TargetAlertCount).Register(router, "/alertstatistic")
+
+	httphandler.NewAggregator(ctx)
+	router.HandleFunc("/api/k8s/clusters/{clusterName}", func(rw http.ResponseWriter,
 		req *http.Request) {
-		json.NewEncoder(rw).Encode([]string{})
+		httphandler.ClusterConsole(rw, req)
 	})
 
 	router.HandleFunc("/tunnel/{cluster}/{path:.*}", handlePrometheusBypass(cfg, handler))
@@ -530,131 +608,325 @@ func getClient(remoteServer *remotedialer.Server, clientKey string, timeout stri
 	return client
 }
 
-func proxyDingdingAlert(rw http.ResponseWriter, req *http.Request, influxdb2api influxdb2api.WriteAPI) {
-	var (
-		ignore   bool
-		alertStr string
-	)
-	//
-	//// if enable black list, check the copy of request body
-	//if alert != nil && len(alert.Spec.BlackList) > 0 {
+func proxyDingdingAlert(rw http.ResponseWriter, req *http.Request, resultStore history.Store, alertManager *manager.Manager) {
 	// get buffer
 	buf, _ := ioutil.ReadAll(req.Body)
 	// copy buffer & re-assign to request body
 	newBd := ioutil.NopCloser(bytes.NewBuffer(buf))
 	req.Body = newBd
-	alertStr = string(buf)
-	//}
-
-	ignore = dingding.CheckBlacklist(alertStr)
-	// return if ignore
-	if ignore {
-		return
-	}
+	alertStr := string(buf)
 
 	klog.Infof("alert string: %+v\n", alertStr)
 	asItem, err := dingding.ParseAlert(alertStr)
-	if err == nil {
-		if influxdb2api != nil && asItem.Status == dingding.AlertEmit {
-			p := influxdb2.NewPointWithMeasurement("alert").
-				AddTag("cluster", asItem.Cluster).
-				AddTag("node", asItem.Node).
-				AddTag("type", asItem.Type).
-				AddTag("component", asItem.Component).
-				AddTag("level", asItem.Level).
-				AddField("msg", asItem.Msg).
-				SetTime(time.Now())
-			// Flush writes
-			influxdb2api.WritePoint(p)
-			influxdb2api.Flush()
-		}
-
-		level := strings.ToLower(asItem.Level)
-		if level == "fatal" || level == "critical" || level == "warning" ||
-			asItem.Status == dingding.AlertRecover {
-			t := &ticket.Ticket{
-				Labels: []string{asItem.Cluster, asItem.Node, asItem.Type, "告警"},
-			}
-			if asItem.Component != "" {
-				t.Labels = append(t.Labels, asItem.Component)
-			}
-			if asItem.Status == dingding.AlertRecover {
-				t.Kind = ticket.PassTicket
-			} else { // asItem.Status == dingding.AlertRecover
-				t.Kind = ticket.ErrorTicket
-			}
-			t.Title = fmt.Sprintf("(请勿改标题) 异常告警-[集群]: %s,[节点]: %s,[类别]: %s,[组件]: %s",
-				asItem.Cluster, asItem.Node, asItem.Type, asItem.Component)
-			t.Content = asItem.Msg
-			t.Type = erda_api.IssueTypeTicket
-			if level == "fatal" {
-				t.Priority = erda_api.IssuePriorityUrgent
-			} else if level == "critical" {
-				t.Priority = erda_api.IssuePriorityHigh
-			} else if level == "warning" {
-				t.Priority = erda_api.IssuePriorityNormal
-			}
-
-			ticket.SendTicket(t)
+	if err != nil {
+		// alerts failed to parse are only matched by the deprecated blackList
+		if name := suppress.DefaultSuppressor.BlackListed(alertStr); name != "" {
+			klog.Infof("alert is suppressed by %s\n", name)
+			rw.WriteHeader(http.StatusOK)
+			return
 		}
 	}
+	if err == nil && suppressed(asItem, alertStr) {
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+	if err == nil && asItem.Status == dingding.AlertEmit {
+		dingding.CountAlert()
+	}
+	if err == nil && !handleNodeAlert(asItem, resultStore, alertManager) {
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+	if err == nil {
+		recordNodeAlert(asItem, resultStore)
+	}
 	klog.Info("alert start send to dingding")
 	dingding.ProxyAlert(rw, req)
 }
 
-func collectProbeStatus(rw http.ResponseWriter, req *http.Request, influxdb2api influxdb2api.WriteAPI) {
-	ps := apistructs.CollectProbeStatusReq{}
-	var err error
-	if err = json.NewDecoder(req.Body).Decode(&ps); err != nil {
-		errMsg := fmt.Sprintf("receive probe status err: %+v\n", err)
+// receiveAlertmanagerAlert accepts the webhook payload of Prometheus Alertmanager, the alerts are
+// recorded and ticketed like dingding alerts, and sent to the dingding receiver
+func receiveAlertmanagerAlert(rw http.ResponseWriter, req *http.Request, resultStore history.Store,
+	alertManager *manager.Manager) {
+	msg := &apistructs.AlertmanagerWebhookMessage{}
+	if err := json.NewDecoder(req.Body).Decode(msg); err != nil {
+		errMsg := fmt.Sprintf("[alertmanager] failed to decode webhook message: %+v\n", err)
 		klog.Errorf(errMsg)
 		rw.WriteHeader(http.StatusBadRequest)
 		rw.Write([]byte(errMsg))
 		return
 	}
-	if influxdb2api != nil {
-		//influxdb2api.WriteRecord(fmt.Sprintf("checker,cluster=%s,checker=%s,probe=%s result=\"%s###%s\"", ps.ClusterName, ps.CheckerName, ps.ProbeName, ps.Status, ps.Message))
-		p := influxdb2.NewPointWithMeasurement("checker").
-			AddTag("cluster", ps.ClusterName).
-			AddTag("checker", ps.CheckerName).
-			AddTag("probe", ps.ProbeName).
-			AddField("result", fmt.Sprintf("%s###%s", ps.Status, ps.Message)).
-			SetTime(time.Now())
-		// Flush writes
-		influxdb2api.WritePoint(p)
-		influxdb2api.Flush()
+
+	var text strings.Builder
+	count := 0
+	for _, a := range alertmanager.ParseWebhook(msg) {
+		asItem := &dingding.AlertItemStuct{
+			Status:    dingding.AlertEmit,
+			Cluster:   a.Cluster,
+			Node:      a.Node,
+			Component: a.Component,
+			Level:     a.Level,
+			Type:      a.Type,
+			Msg:       a.Message,
+		}
+		if a.Resolved {
+			asItem.Status = dingding.AlertRecover
+		}
+		if suppressed(asItem, a.Message) {
+			continue
+		}
+		if !a.Resolved {
+			dingding.CountAlert()
+		}
+		if !handleNodeAlert(asItem, resultStore, alertManager) {
+			continue
+		}
+		recordNodeAlert(asItem, resultStore)
+
+		title, content, err := ticketTemplate(dingding.DINGDING_ALERT_NAME).Ticket(&message.TicketData{
+			Cluster:   a.Cluster,
+			Node:      a.Node,
+			Type:      a.Type,
+			Component: a.Component,
+			Level:     a.Level,
+			Message:   a.Message,
+		})
+		if err != nil {
+			klog.Errorf("[alertmanager] failed to render alert of %s: %+v\n", a.Cluster, err)
+			continue
+		}
+		status := "FIRING"
+		if a.Resolved {
+			status = "RESOLVED"
+		}
+		text.WriteString(fmt.Sprintf("[%s] %s\n%s\n\n", status, title, content))
+		count++
+	}
+
+	if n := notifier.DefaultRegistry.Get(dingding.DINGDING_ALERT_NAME); n != nil && count > 0 {
+		m := &notifier.Message{
+			Title: fmt.Sprintf("[KubeProber] Alertmanager %s: %d", msg.Status, count),
+			Text:  text.String(),
+		}
+		go func() {
+			if err := n.Notify(context.Background(), m); err != nil {
+				klog.Errorf("[alertmanager] failed to send alerts to %s: %+v\n", n.Name(), err)
+			}
+		}()
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// suppressed returns whether the node alert of text matches suppress matchers or blackList of dingding Alert
+func suppressed(asItem *dingding.AlertItemStuct, text string) bool {
+	name := suppress.DefaultSuppressor.Suppressed(&suppress.Fields{
+		Cluster:   asItem.Cluster,
+		Node:      asItem.Node,
+		Component: asItem.Component,
+		Level:     asItem.Level,
+		Type:      asItem.Type,
+		Text:      text,
+	}, time.Now())
+	if name != "" {
+		klog.Infof("alert of cluster %s node %s is suppressed by %s\n", asItem.Cluster, asItem.Node, name)
+		return true
+	}
+	return false
+}
+
+// recordNodeAlert counts the firing node alert sent to dingding in alert statistics
+func recordNodeAlert(asItem *dingding.AlertItemStuct, resultStore history.Store) {
+	if resultStore == nil || asItem.Status != dingding.AlertEmit {
+		return
+	}
+	if err := resultStore.WriteNotification(&apistructs.AlertNotification{
+		Cluster:  asItem.Cluster,
+		Type:     asItem.Type,
+		Checker:  asItem.Component,
+		Level:    strings.ToLower(asItem.Level),
+		Receiver: dingding.DINGDING_ALERT_NAME,
+		Time:     time.Now(),
+	}); err != nil {
+		klog.Errorf("failed to write alert notification: %+v\n", err)
+	}
+}
+
+// handleNodeAlert records the node alert, passes it to the alert manager and sends its ticket,
+// returns false if the alert is silenced
+func handleNodeAlert(asItem *dingding.AlertItemStuct, resultStore history.Store, alertManager *manager.Manager) bool {
+	if resultStore != nil && asItem.Status == dingding.AlertEmit {
+		if err := resultStore.WriteAlert(&apistructs.AlertRecord{
+			Cluster:   asItem.Cluster,
+			Node:      asItem.Node,
+			Type:      asItem.Type,
+			Component: asItem.Component,
+			Level:     asItem.Level,
+			Message:   asItem.Msg,
+			Time:      time.Now(),
+		}); err != nil {
+			klog.Errorf("failed to write alert record: %+v\n", err)
+		}
 	}
 
-	if ps.Status == kubeproberv1.CheckerStatusError {
+	level := strings.ToLower(asItem.Level)
+	// node alerts have no probe and checker, only silences of the whole cluster match
+	if silences := silence.DefaultSilencer.Silenced(asItem.Cluster, "", "", levelStatus(level), time.Now()); len(silences) > 0 {
+		klog.Infof("alert of cluster %s node %s is silenced by %v\n", asItem.Cluster, asItem.Node, silences)
+		return false
+	}
+	// fatal and critical alerts are escalated and acknowledged like checker alerts by routes of node-alert probe,
+	// recovery resolves them
+	if alertManager != nil {
+		status := levelStatus(level)
+		if asItem.Status == dingding.AlertRecover {
+			status = kubeproberv1.CheckerStatusPass
+		}
+		alertManager.Receive(apistructs.NodeAlertResult(asItem.Cluster, asItem.Node, asItem.Type, asItem.Component,
+			status, asItem.Msg, time.Now()))
+	}
+	if level == "fatal" || level == "critical" || level == "warning" ||
+		asItem.Status == dingding.AlertRecover {
 		t := &ticket.Ticket{
-			Kind:   ticket.ErrorTicket,
-			Labels: []string{ps.CheckerName, ps.ProbeName, ps.ClusterName, "巡检"},
+			Labels: []string{asItem.Cluster, asItem.Node, asItem.Type, "告警"},
+		}
+		if asItem.Component != "" {
+			t.Labels = append(t.Labels, asItem.Component)
 		}
-		t.Title = fmt.Sprintf("(请勿改标题)巡检异常-[集群]: %s,[类别]: %s,[检查项]：%s",
-			ps.ClusterName, ps.ProbeName, ps.CheckerName)
-		t.Content = fmt.Sprintf("[集群]: %s\n[类别]: %s\n[检查项]：%s\n[检查状态]：%s\n[错误信息]：\n%s",
-			ps.ClusterName, ps.ProbeName, ps.CheckerName, ps.Status, ps.Message)
+		if asItem.Status == dingding.AlertRecover {
+			t.Kind = ticket.PassTicket
+		} else { // asItem.Status == dingding.AlertRecover
+			t.Kind = ticket.ErrorTicket
+		}
+		t.Fingerprint = apistructs.Fingerprint("node", asItem.Cluster, asItem.Node, asItem.Type, asItem.Component)
+		// node alerts are sent to the dingding receiver, so are their tickets rendered with its templates
+		renderTicket(t, ticketTemplate(dingding.DINGDING_ALERT_NAME), &message.TicketData{
+			Cluster:   asItem.Cluster,
+			Node:      asItem.Node,
+			Type:      asItem.Type,
+			Component: asItem.Component,
+			Level:     asItem.Level,
+			Message:   asItem.Msg,
+		})
 		t.Type = erda_api.IssueTypeTicket
-		t.Priority = erda_api.IssuePriorityHigh
+		if level == "fatal" {
+			t.Priority = erda_api.IssuePriorityUrgent
+		} else if level == "critical" {
+			t.Priority = erda_api.IssuePriorityHigh
+		} else if level == "warning" {
+			t.Priority = erda_api.IssuePriorityNormal
+		}
 
-		//ticket.SendTicket(t)
+		ticket.SendTicket(t)
+	}
+	return true
+}
 
-		if err = dingding.SendAlert(&ps); err != nil {
-			errMsg := fmt.Sprintf("send dingding alert err: %+v\n", err)
-			klog.Errorf(errMsg)
+// sendCheckerTicket sends tickets of error results to the ticket backends of matched alert routes,
+// and closes them in the same backends when checkers pass
+func sendCheckerTicket(r *apistructs.CheckerResult) {
+	var t *ticket.Ticket
+	switch r.Status {
+	case kubeproberv1.CheckerStatusError:
+		if silences := silence.DefaultSilencer.Silenced(r.Cluster, r.Probe, r.Checker, r.Status, r.Time); len(silences) > 0 {
+			klog.V(2).Infof("ticket of %s is silenced by %v\n", r.Key(), silences)
+			return
 		}
-	} else if ps.Status == kubeproberv1.CheckerStatusPass {
-		t := &ticket.Ticket{Kind: ticket.PassTicket}
-		t.Title = fmt.Sprintf("(请勿改标题)巡检异常-[集群]: %s,[类别]: %s,[检查项]：%s",
-			ps.ClusterName, ps.ProbeName, ps.CheckerName)
-		t.Content = fmt.Sprintf("[集群]: %s\n[类别]: %s\n[检查项]：%s\n[检查状态]: %s\n[错误信息]：\n%s",
-			ps.ClusterName, ps.ProbeName, ps.CheckerName, ps.Status, ps.Message)
-		t.Type = erda_api.IssueTypeTicket
-		t.Priority = erda_api.IssuePriorityLow
+		t = &ticket.Ticket{
+			Kind:     ticket.ErrorTicket,
+			Labels:   []string{r.Checker, r.Probe, r.Cluster, "巡检"},
+			Priority: erda_api.IssuePriorityHigh,
+		}
+	case kubeproberv1.CheckerStatusPass:
+		t = &ticket.Ticket{Kind: ticket.PassTicket, Priority: erda_api.IssuePriorityLow}
+	default:
+		return
+	}
+	t.Fingerprint = r.Fingerprint()
+	t.Type = erda_api.IssueTypeTicket
+
+	// pass results are routed as errors, so that tickets are closed in the backends they were created
+	matched := *r
+	matched.Status = kubeproberv1.CheckerStatusError
+	d := route.DefaultRouter.Match(&matched)
+	renderTicket(t, ticketTemplate(d.Receivers...), checkerTicketData(r))
+	for _, backend := range d.Tickets {
+		ticket.SendTicketTo(backend, t)
+	}
+}
+
+func checkerTicketData(r *apistructs.CheckerResult) *message.TicketData {
+	return &message.TicketData{
+		Cluster: r.Cluster,
+		Probe:   r.Probe,
+		Checker: r.Checker,
+		Status:  r.Status,
+		Message: r.Message,
+	}
+}
+
+// ticketTemplate returns the templates of the first registered receiver, built-in templates
+// in the language of probe-master if there is none
+func ticketTemplate(receivers ...string) *message.Template {
+	for _, name := range receivers {
+		if n := notifier.DefaultRegistry.Get(name); n != nil {
+			return n.Template()
+		}
+	}
+	return message.Default()
+}
+
+// renderTicket renders title and content of ticket with tpl
+func renderTicket(t *ticket.Ticket, tpl *message.Template, data *message.TicketData) {
+	var err error
+	if t.Title, t.Content, err = tpl.Ticket(data); err != nil {
+		klog.Errorf("failed to render ticket of %s: %+v\n", t.Fingerprint, err)
+	}
+}
+
+// levelStatus maps the level of node alerts to checker status
+func levelStatus(level string) kubeproberv1.CheckerStatus {
+	switch level {
+	case "fatal", "critical":
+		return kubeproberv1.CheckerStatusError
+	case "warning":
+		return kubeproberv1.CheckerStatusWARN
+	}
+	return kubeproberv1.CheckerStatusInfo
+}
+
+func collectProbeStatus(rw http.ResponseWriter, req *http.Request, resultStore history.Store, alertManager *manager.Manager,
+	alertSink *alertmanager.Sink) {
+	ps := apistructs.CollectProbeStatusReq{}
+	var err error
+	if err = json.NewDecoder(req.Body).Decode(&ps); err != nil {
+		errMsg := fmt.Sprintf("receive probe status err: %+v\n", err)
+		klog.Errorf(errMsg)
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(errMsg))
+		return
+	}
+	r := &apistructs.CheckerResult{
+		Cluster: ps.ClusterName,
+		Probe:   ps.ProbeName,
+		Checker: ps.CheckerName,
+		Status:  ps.Status,
+		Message: ps.Message,
+		Time:    time.Now(),
+	}
+	if resultStore != nil {
+		if err = resultStore.WriteResult(r); err != nil {
+			klog.Errorf("failed to write checker result of %s: %+v\n", r.Key(), err)
+		}
+	}
 
-		//ticket.SendTicket(t)
+	// pass results resolve firing alerts
+	alertManager.Receive(r)
+	if alertSink != nil {
+		alertSink.Receive(r)
 	}
 
+	sendCheckerTicket(r)
+
 	rw.WriteHeader(http.StatusOK)
 	return
 }
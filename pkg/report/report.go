@@ -0,0 +1,331 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+
+	// statuses of the trend of a checker
+	trendSize  = 5
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// extra status of cluster shown in report
+var infoExtraStatus = []struct{ key, name string }{
+	{"diceVersion", "Dice Version"},
+	{"k8sVendor", "K8s Vendor"},
+	{"osImages", "OS Images"},
+	{"kernelVersions", "Kernel Versions"},
+	{"masterNode", "Master Nodes"},
+	{"lbNode", "LB Nodes"},
+	{"podNum", "Pods"},
+	{"storageType", "Storage Type"},
+	{"mysqlHost", "MySQL Host"},
+	{"nacosAddr", "Nacos Address"},
+}
+
+// Report is a diagnostics report of clusters
+type Report struct {
+	Title       string          `json:"title"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Start       time.Time       `json:"start"`
+	End         time.Time       `json:"end"`
+	Clusters    []ClusterReport `json:"clusters"`
+	// times are displayed in it, local timezone default
+	Location *time.Location `json:"-"`
+}
+
+// ClusterReport is the cluster info, checker results and score of a cluster
+type ClusterReport struct {
+	Name      string          `json:"name"`
+	Info      []Item          `json:"info"`
+	Heartbeat time.Time       `json:"heartbeat,omitempty"`
+	Checkers  []CheckerReport `json:"checkers"`
+	// 0-100, -1 when there is no checker result
+	Score int `json:"score"`
+	// checker results can't be read, e.g. the cluster is unreachable
+	Error string `json:"error,omitempty"`
+}
+
+// Item is a name and value of cluster info
+type Item struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CheckerReport is the latest result and trend of a checker
+type CheckerReport struct {
+	Probe   string    `json:"probe"`
+	Checker string    `json:"checker"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+	Trend   *Trend    `json:"trend,omitempty"`
+}
+
+// Trend is how the status of a checker changed in the history window
+type Trend struct {
+	Total   int       `json:"total"`
+	Pass    int       `json:"pass"`
+	Rate    float64   `json:"rate"`
+	Changes int       `json:"changes"`
+	Since   time.Time `json:"since"`
+	// the last statuses of checker, oldest first
+	Statuses []string `json:"statuses"`
+}
+
+// Summary counts checkers of all clusters by status
+type Summary struct {
+	Clusters    int
+	Unreachable int
+	Checkers    int
+	Pass        int
+	Warn        int
+	Error       int
+	Unknown     int
+	Score       int
+}
+
+// New returns a report of clusters, trends are from history between start and end,
+// both are zero if the report has no trend
+func New(title string, start, end time.Time, clusters []ClusterReport) *Report {
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return &Report{
+		Title:       title,
+		GeneratedAt: time.Now(),
+		Start:       start,
+		End:         end,
+		Clusters:    clusters,
+		Location:    time.Local,
+	}
+}
+
+// NewClusterReport builds the report of a cluster from its latest checker results and
+// the timeline of checkers, results and timeline of other clusters are ignored
+func NewClusterReport(cluster *kubeproberv1.Cluster, results []apistructs.CheckerResult, timeline []apistructs.StatusChange) ClusterReport {
+	r := ClusterReport{Name: cluster.Name, Info: clusterInfo(cluster)}
+	if cluster.Status.HeartBeatTime != nil {
+		r.Heartbeat = cluster.Status.HeartBeatTime.Time
+	}
+
+	changes := make(map[string][]apistructs.StatusChange)
+	for _, c := range timeline {
+		if c.Cluster == cluster.Name {
+			key := c.Probe + "/" + c.Checker
+			changes[key] = append(changes[key], c)
+		}
+	}
+	for _, res := range results {
+		if res.Cluster != "" && res.Cluster != cluster.Name {
+			continue
+		}
+		r.Checkers = append(r.Checkers, CheckerReport{
+			Probe:   res.Probe,
+			Checker: res.Checker,
+			Status:  string(res.Status),
+			Message: strings.TrimSpace(res.Message),
+			Time:    res.Time,
+			Trend:   newTrend(changes[res.Probe+"/"+res.Checker]),
+		})
+	}
+	// failed checkers first
+	sort.SliceStable(r.Checkers, func(i, j int) bool {
+		a, b := r.Checkers[i], r.Checkers[j]
+		if statusScore(a.Status) != statusScore(b.Status) {
+			return statusScore(a.Status) < statusScore(b.Status)
+		}
+		if a.Probe != b.Probe {
+			return a.Probe < b.Probe
+		}
+		return a.Checker < b.Checker
+	})
+	r.Score = clusterScore(r.Checkers)
+	return r
+}
+
+func clusterInfo(c *kubeproberv1.Cluster) []Item {
+	info := []Item{
+		{"K8s Version", c.Spec.K8sVersion},
+		{"Nodes", fmt.Sprintf("%d", c.Status.NodeCount)},
+		{"Agent Version", c.Status.AgentVersion},
+		{"Probe Namespace", c.Spec.ClusterConfig.ProbeNamespaces},
+		{"Attached Probes", strings.Join(c.Status.AttachedProbes, ", ")},
+		{"Checkers (Total/Error)", c.Status.Checkers},
+	}
+	for _, e := range infoExtraStatus {
+		if v := c.Status.ExtraStatus[e.key]; v != "" {
+			info = append(info, Item{e.name, v})
+		}
+	}
+	return info
+}
+
+// newTrend summarizes the status changes of a checker, nil without history
+func newTrend(changes []apistructs.StatusChange) *Trend {
+	if len(changes) == 0 {
+		return nil
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Since.Before(changes[j].Since) })
+	t := &Trend{Changes: len(changes) - 1, Since: changes[len(changes)-1].Since}
+	for i, c := range changes {
+		t.Total += c.Count
+		if statusScore(string(c.Status)) == 100 {
+			t.Pass += c.Count
+		}
+		if i >= len(changes)-trendSize {
+			t.Statuses = append(t.Statuses, string(c.Status))
+		}
+	}
+	if t.Total > 0 {
+		t.Rate = float64(t.Pass) / float64(t.Total)
+	}
+	return t
+}
+
+// statusScore is 100 for healthy checkers and 0 for failed checkers
+func statusScore(status string) int {
+	switch kubeproberv1.CheckerStatus(status) {
+	case kubeproberv1.CheckerStatusPass, kubeproberv1.CheckerStatusInfo:
+		return 100
+	case kubeproberv1.CheckerStatusWARN, kubeproberv1.CheckerStatusUNKNOWN:
+		return 50
+	}
+	return 0
+}
+
+func clusterScore(checkers []CheckerReport) int {
+	if len(checkers) == 0 {
+		return -1
+	}
+	sum := 0
+	for _, c := range checkers {
+		sum += statusScore(c.Status)
+	}
+	return sum / len(checkers)
+}
+
+// Count returns the number of checkers in the status
+func (c *ClusterReport) Count(status string) int {
+	n := 0
+	for _, ch := range c.Checkers {
+		if ch.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// FormatTime displays t in the location of report
+func (r *Report) FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	loc := r.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format(timeLayout)
+}
+
+// Summary counts checkers of all clusters, the score is the average of scored clusters
+// and unreachable clusters score 0
+func (r *Report) Summary() Summary {
+	s := Summary{Clusters: len(r.Clusters), Score: -1}
+	scored, sum := 0, 0
+	for _, c := range r.Clusters {
+		if c.Error != "" {
+			s.Unreachable++
+			scored++
+			continue
+		}
+		if c.Score >= 0 {
+			scored++
+			sum += c.Score
+		}
+		for _, ch := range c.Checkers {
+			s.Checkers++
+			switch kubeproberv1.CheckerStatus(ch.Status) {
+			case kubeproberv1.CheckerStatusPass, kubeproberv1.CheckerStatusInfo:
+				s.Pass++
+			case kubeproberv1.CheckerStatusWARN:
+				s.Warn++
+			case kubeproberv1.CheckerStatusError:
+				s.Error++
+			default:
+				s.Unknown++
+			}
+		}
+	}
+	if scored > 0 {
+		s.Score = sum / scored
+	}
+	return s
+}
+
+// LatestResults keeps the last result of every checker of every cluster, results are sorted by time
+func LatestResults(results []apistructs.CheckerResult) []apistructs.CheckerResult {
+	latest := make(map[string]int)
+	var keys []string
+	for i, r := range results {
+		if _, ok := latest[r.Key()]; !ok {
+			keys = append(keys, r.Key())
+		}
+		latest[r.Key()] = i
+	}
+	out := make([]apistructs.CheckerResult, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, results[latest[k]])
+	}
+	return out
+}
+
+// ValidateFormat checks the format of report
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatMarkdown, FormatHTML:
+		return nil
+	}
+	return fmt.Errorf("unsupported report format %q, [%s, %s] is expected", format, FormatMarkdown, FormatHTML)
+}
+
+// ContentType is the http content type of the format
+func ContentType(format string) string {
+	if format == FormatHTML {
+		return "text/html; charset=utf-8"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Render writes the report in the format into w
+func Render(w io.Writer, r *Report, format string) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+	if format == FormatHTML {
+		return htmlTemplate.Execute(w, r)
+	}
+	return markdownTemplate.Execute(w, r)
+}
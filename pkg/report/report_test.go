@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeproberv1 "github.com/erda-project/kubeprober/apis/v1"
+	"github.com/erda-project/kubeprober/apistructs"
+)
+
+var t0 = time.Date(2021, 10, 8, 12, 0, 0, 0, time.UTC)
+
+func testReport() *Report {
+	prod := &kubeproberv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+		Spec:       kubeproberv1.ClusterSpec{K8sVersion: "v1.21.0"},
+		Status: kubeproberv1.ClusterStatus{
+			NodeCount:     3,
+			HeartBeatTime: &metav1.Time{Time: t0},
+			ExtraStatus:   map[string]string{"kernelVersions": "5.4", "mysqlHost": ""},
+		},
+	}
+	results := []apistructs.CheckerResult{
+		{Cluster: "prod", Probe: "k8s", Checker: "node", Status: kubeproberv1.CheckerStatusPass, Time: t0},
+		{Cluster: "prod", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusError, Message: "lookup | timeout\nretry", Time: t0},
+		{Cluster: "prod", Probe: "k8s", Checker: "etcd", Status: kubeproberv1.CheckerStatusWARN, Time: t0},
+		{Cluster: "test", Probe: "k8s", Checker: "node", Status: kubeproberv1.CheckerStatusError, Time: t0},
+	}
+	timeline := []apistructs.StatusChange{
+		{Cluster: "prod", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusError, Since: t0.Add(-time.Hour), Count: 1},
+		{Cluster: "prod", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusPass, Since: t0.Add(-2 * time.Hour), Count: 3},
+		{Cluster: "test", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusError, Since: t0, Count: 5},
+	}
+	r := New("Diagnostics", t0.Add(-24*time.Hour), t0, []ClusterReport{
+		{Name: "lost", Error: "tunnel is not connected", Score: -1},
+		NewClusterReport(prod, results, timeline),
+	})
+	r.Location = time.UTC
+	return r
+}
+
+func TestNewClusterReport(t *testing.T) {
+	r := testReport()
+	prod := r.Clusters[1]
+	assert.Equal(t, "prod", prod.Name)
+	assert.Equal(t, 50, prod.Score)
+	assert.Equal(t, []string{"dns", "etcd", "node"}, []string{prod.Checkers[0].Checker, prod.Checkers[1].Checker, prod.Checkers[2].Checker})
+	assert.Equal(t, &Trend{Total: 4, Pass: 3, Rate: 0.75, Changes: 1, Since: t0.Add(-time.Hour), Statuses: []string{"PASS", "ERROR"}},
+		prod.Checkers[0].Trend)
+	assert.Nil(t, prod.Checkers[1].Trend)
+	assert.Contains(t, prod.Info, Item{"Kernel Versions", "5.4"})
+	assert.NotContains(t, prod.Info, Item{"MySQL Host", ""})
+
+	assert.Equal(t, Summary{Clusters: 2, Unreachable: 1, Checkers: 3, Pass: 1, Warn: 1, Error: 1, Score: 25}, r.Summary())
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Render(&b, testReport(), FormatMarkdown))
+	out := b.String()
+	assert.Contains(t, out, "# Diagnostics\n")
+	assert.Contains(t, out, "| 25 | 2 | 1 | 3 | 1 | 1 | 1 | 0 |")
+	assert.Contains(t, out, "| lost | unreachable | 0 | 0 | 0 | - |")
+	assert.Contains(t, out, "| prod | 50 | 3 | 1 | 1 | 2021-10-08 12:00:00 |")
+	assert.Contains(t, out, "| **ERROR** | k8s | dns | lookup \\| timeout<br>retry | 2021-10-08 12:00:00 | 75.00% | 1 | PASS → ERROR |")
+	assert.Contains(t, out, "> **Error:** tunnel is not connected")
+}
+
+func TestRenderHTML(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Render(&b, testReport(), FormatHTML))
+	out := b.String()
+	assert.Contains(t, out, "<title>Diagnostics</title>")
+	assert.Contains(t, out, `<td class="status error">ERROR</td>`)
+	assert.Contains(t, out, `<a href="#cluster-prod">prod</a>`)
+	assert.Contains(t, out, "lookup | timeout\nretry")
+
+	assert.Error(t, Render(&b, testReport(), "pdf"))
+}
+
+func TestLatestResults(t *testing.T) {
+	results := LatestResults([]apistructs.CheckerResult{
+		{Cluster: "prod", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusError, Time: t0},
+		{Cluster: "prod", Probe: "k8s", Checker: "node", Status: kubeproberv1.CheckerStatusPass, Time: t0},
+		{Cluster: "prod", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusPass, Time: t0.Add(time.Minute)},
+	})
+	assert.Equal(t, []apistructs.CheckerResult{
+		{Cluster: "prod", Probe: "k8s", Checker: "dns", Status: kubeproberv1.CheckerStatusPass, Time: t0.Add(time.Minute)},
+		{Cluster: "prod", Probe: "k8s", Checker: "node", Status: kubeproberv1.CheckerStatusPass, Time: t0},
+	}, results)
+}
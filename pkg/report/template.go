@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+var funcs = map[string]interface{}{
+	"score": func(score int) string {
+		if score < 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%d", score)
+	},
+	"rate": func(t *Trend) string {
+		if t == nil || t.Total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f%%", t.Rate*100)
+	},
+	"trend": func(t *Trend) string {
+		if t == nil {
+			return "-"
+		}
+		return strings.Join(t.Statuses, " → ")
+	},
+	"changes": func(t *Trend) string {
+		if t == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%d", t.Changes)
+	},
+	// cell escapes text in a markdown table cell
+	"cell": func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		s = strings.ReplaceAll(s, "|", `\|`)
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+		return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+	},
+	"lower": strings.ToLower,
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(funcs).Parse(`# {{.Title}}
+
+Generated at {{.FormatTime .GeneratedAt}}{{if not .End.IsZero}}, trends from {{.FormatTime .Start}} to {{.FormatTime .End}}{{end}}.
+
+## Summary
+{{with .Summary}}
+| Score | Clusters | Unreachable | Checkers | Pass | Warn | Error | Unknown |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+| {{score .Score}} | {{.Clusters}} | {{.Unreachable}} | {{.Checkers}} | {{.Pass}} | {{.Warn}} | {{.Error}} | {{.Unknown}} |
+{{end}}
+| Cluster | Score | Checkers | Error | Warn | Heartbeat |
+| --- | --- | --- | --- | --- | --- |
+{{range .Clusters}}| {{.Name}} | {{if .Error}}unreachable{{else}}{{score .Score}}{{end}} | {{len .Checkers}} | {{.Count "ERROR"}} | {{.Count "WARN"}} | {{$.FormatTime .Heartbeat}} |
+{{end}}{{range .Clusters}}
+## {{.Name}}
+
+| Cluster Info | |
+| --- | --- |
+{{range .Info}}| {{.Name}} | {{cell .Value}} |
+{{end}}| Heartbeat | {{$.FormatTime .Heartbeat}} |
+{{if .Error}}
+> **Error:** {{cell .Error}}
+{{else if .Checkers}}
+| Status | Probe | Checker | Message | Time | Pass Rate | Changes | Trend |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+{{range .Checkers}}| {{if eq .Status "ERROR" "WARN"}}**{{.Status}}**{{else}}{{.Status}}{{end}} | {{.Probe}} | {{.Checker}} | {{cell .Message}} | {{$.FormatTime .Time}} | {{rate .Trend}} | {{changes .Trend}} | {{trend .Trend}} |
+{{end}}{{else}}
+No checker results.
+{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td.message { white-space: pre-wrap; max-width: 40em; }
+.status { font-weight: bold; }
+.pass, .info { color: #1a7f37; }
+.warn, .unknown { color: #9a6700; }
+.error { color: #cf222e; }
+.score { font-size: 1.5em; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated at {{.FormatTime .GeneratedAt}}{{if not .End.IsZero}}, trends from {{.FormatTime .Start}} to {{.FormatTime .End}}{{end}}.</p>
+
+<h2>Summary</h2>
+{{with .Summary}}<p>Score: <span class="score">{{score .Score}}</span></p>
+<table>
+<tr><th>Clusters</th><th>Unreachable</th><th>Checkers</th><th>Pass</th><th>Warn</th><th>Error</th><th>Unknown</th></tr>
+<tr><td>{{.Clusters}}</td><td>{{.Unreachable}}</td><td>{{.Checkers}}</td><td>{{.Pass}}</td><td>{{.Warn}}</td><td>{{.Error}}</td><td>{{.Unknown}}</td></tr>
+</table>{{end}}
+<table>
+<tr><th>Cluster</th><th>Score</th><th>Checkers</th><th>Error</th><th>Warn</th><th>Heartbeat</th></tr>
+{{range .Clusters}}<tr><td><a href="#cluster-{{.Name}}">{{.Name}}</a></td><td>{{if .Error}}<span class="error">unreachable</span>{{else}}{{score .Score}}{{end}}</td><td>{{len .Checkers}}</td><td>{{.Count "ERROR"}}</td><td>{{.Count "WARN"}}</td><td>{{$.FormatTime .Heartbeat}}</td></tr>
+{{end}}</table>
+{{range .Clusters}}
+<h2 id="cluster-{{.Name}}">{{.Name}}</h2>
+<table>
+{{range .Info}}<tr><th>{{.Name}}</th><td>{{if .Value}}{{.Value}}{{else}}-{{end}}</td></tr>
+{{end}}<tr><th>Heartbeat</th><td>{{$.FormatTime .Heartbeat}}</td></tr>
+</table>
+{{if .Error}}<p class="error">Error: {{.Error}}</p>
+{{else if .Checkers}}<table>
+<tr><th>Status</th><th>Probe</th><th>Checker</th><th>Message</th><th>Time</th><th>Pass Rate</th><th>Changes</th><th>Trend</th></tr>
+{{range .Checkers}}<tr><td class="status {{lower .Status}}">{{.Status}}</td><td>{{.Probe}}</td><td>{{.Checker}}</td><td class="message">{{.Message}}</td><td>{{$.FormatTime .Time}}</td><td>{{rate .Trend}}</td><td>{{changes .Trend}}</td><td>{{trend .Trend}}</td></tr>
+{{end}}</table>
+{{else}}<p>No checker results.</p>
+{{end}}{{end}}</body>
+</html>
+`))